@@ -0,0 +1,189 @@
+package booking
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/jbowles/hotel_supply_platform/hspservice"
+)
+
+var (
+	itinerariesBucket  = []byte("itineraries")   // idempotency key -> record
+	itineraryIdsBucket = []byte("itinerary_ids") // itinerary id -> idempotency key
+	attemptsBucket     = []byte("attempts")      // idempotency key, 0, attempt number -> Attempt
+)
+
+// Bolt is a Repository backed by an embedded BoltDB file.
+type Bolt struct {
+	db *bolt.DB
+}
+
+// OpenBolt opens (creating if needed) the database at path and migrates it to
+// the current schema.
+func OpenBolt(path string) (*Bolt, error) {
+	db, err := bolt.Open(path, os.FileMode(0600), &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Bolt{db}, nil
+}
+
+func (b *Bolt) Reserve(it hspservice.Itinerary) (stored hspservice.Itinerary, fresh bool, err error) {
+	err = b.db.Update(func(tx *bolt.Tx) error {
+		bk := tx.Bucket(itinerariesBucket)
+		if v := bk.Get([]byte(it.IdempotencyKey)); v != nil {
+			var r record
+			if err := json.Unmarshal(v, &r); err != nil {
+				return err
+			}
+			stored = r.Itinerary
+			return nil
+		}
+		stored, fresh = it, true
+		return putRecord(bk, &record{Itinerary: it})
+	})
+	return stored, fresh, err
+}
+
+func (b *Bolt) Release(key string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bk := tx.Bucket(itinerariesBucket)
+		r, err := getRecord(bk, key)
+		if err == ErrNotFound {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if r.Itinerary.ItineraryId != "" {
+			if err := tx.Bucket(itineraryIdsBucket).Delete([]byte(r.Itinerary.ItineraryId)); err != nil {
+				return err
+			}
+		}
+		return bk.Delete([]byte(key))
+	})
+}
+
+func (b *Bolt) Update(it hspservice.Itinerary) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bk := tx.Bucket(itinerariesBucket)
+		r, err := getRecord(bk, it.IdempotencyKey)
+		if err != nil {
+			return err
+		}
+		if err := r.update(it); err != nil {
+			return err
+		}
+		if it.ItineraryId != "" {
+			if err := tx.Bucket(itineraryIdsBucket).Put([]byte(it.ItineraryId), []byte(it.IdempotencyKey)); err != nil {
+				return err
+			}
+		}
+		return putRecord(bk, r)
+	})
+}
+
+func (b *Bolt) ByKey(key string) (it hspservice.Itinerary, err error) {
+	err = b.db.View(func(tx *bolt.Tx) error {
+		r, err := getRecord(tx.Bucket(itinerariesBucket), key)
+		if err == nil {
+			it = r.Itinerary
+		}
+		return err
+	})
+	return it, err
+}
+
+func (b *Bolt) ByItineraryId(id string) (it hspservice.Itinerary, err error) {
+	err = b.db.View(func(tx *bolt.Tx) error {
+		key := tx.Bucket(itineraryIdsBucket).Get([]byte(id))
+		if key == nil {
+			return ErrNotFound
+		}
+		r, err := getRecord(tx.Bucket(itinerariesBucket), string(key))
+		if err == nil {
+			it = r.Itinerary
+		}
+		return err
+	})
+	return it, err
+}
+
+func (b *Bolt) Transitions(key string) (ts []Transition, err error) {
+	err = b.db.View(func(tx *bolt.Tx) error {
+		r, err := getRecord(tx.Bucket(itinerariesBucket), key)
+		if err == nil {
+			ts = r.Transitions
+		}
+		return err
+	})
+	return ts, err
+}
+
+func (b *Bolt) RecordAttempt(a Attempt) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bk := tx.Bucket(attemptsBucket)
+		c := bk.Cursor()
+		prefix := attemptPrefix(a.IdempotencyKey)
+		a.Number = 1
+		for k, _ := c.Seek(prefix); k != nil && hasPrefix(k, prefix); k, _ = c.Next() {
+			a.Number++
+		}
+		v, err := json.Marshal(a)
+		if err != nil {
+			return err
+		}
+		return bk.Put(append(prefix, fmt.Sprintf("%08d", a.Number)...), v)
+	})
+}
+
+func (b *Bolt) Attempts(key string) (as []Attempt, err error) {
+	err = b.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(attemptsBucket).Cursor()
+		prefix := attemptPrefix(key)
+		for k, v := c.Seek(prefix); k != nil && hasPrefix(k, prefix); k, v = c.Next() {
+			var a Attempt
+			if err := json.Unmarshal(v, &a); err != nil {
+				return err
+			}
+			as = append(as, a)
+		}
+		return nil
+	})
+	return as, err
+}
+
+func (b *Bolt) Close() error { return b.db.Close() }
+
+func getRecord(bk *bolt.Bucket, key string) (*record, error) {
+	v := bk.Get([]byte(key))
+	if v == nil {
+		return nil, ErrNotFound
+	}
+	var r record
+	return &r, json.Unmarshal(v, &r)
+}
+
+func putRecord(bk *bolt.Bucket, r *record) error {
+	v, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return bk.Put([]byte(r.Itinerary.IdempotencyKey), v)
+}
+
+// attemptPrefix is the key prefix of a key's attempts. The NUL separator keeps
+// key "a" from matching the attempts of key "ab".
+func attemptPrefix(key string) []byte {
+	return append([]byte(key), 0)
+}
+
+func hasPrefix(k, prefix []byte) bool {
+	return len(k) >= len(prefix) && string(k[:len(prefix)]) == string(prefix)
+}
@@ -0,0 +1,127 @@
+package booking
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jbowles/hotel_supply_platform/hspservice"
+)
+
+// Memory is a Repository that keeps everything in process. It is meant for
+// tests and local development; nothing survives a restart.
+type Memory struct {
+	mu       sync.Mutex
+	records  map[string]*record
+	byId     map[string]string // itinerary id -> idempotency key
+	attempts map[string][]Attempt
+}
+
+// record is an itinerary with its status history, as stored by both repositories.
+type record struct {
+	Itinerary   hspservice.Itinerary `json:"itinerary"`
+	Transitions []Transition         `json:"transitions"`
+}
+
+// NewMemory returns an empty in-memory Repository.
+func NewMemory() *Memory {
+	return &Memory{
+		records:  map[string]*record{},
+		byId:     map[string]string{},
+		attempts: map[string][]Attempt{},
+	}
+}
+
+func (m *Memory) Reserve(it hspservice.Itinerary) (hspservice.Itinerary, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if r, ok := m.records[it.IdempotencyKey]; ok {
+		return r.Itinerary, false, nil
+	}
+	m.records[it.IdempotencyKey] = &record{Itinerary: it}
+	return it, true, nil
+}
+
+func (m *Memory) Release(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if r, ok := m.records[key]; ok && r.Itinerary.ItineraryId != "" {
+		delete(m.byId, r.Itinerary.ItineraryId)
+	}
+	delete(m.records, key)
+	return nil
+}
+
+func (m *Memory) Update(it hspservice.Itinerary) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r, ok := m.records[it.IdempotencyKey]
+	if !ok {
+		return ErrNotFound
+	}
+	if err := r.update(it); err != nil {
+		return err
+	}
+	if it.ItineraryId != "" {
+		m.byId[it.ItineraryId] = it.IdempotencyKey
+	}
+	return nil
+}
+
+// update moves the record to it, recording the status transition if any.
+func (r *record) update(it hspservice.Itinerary) error {
+	from := r.Itinerary.Status
+	if err := checkTransition(from, it.Status); err != nil {
+		return err
+	}
+	if from != it.Status {
+		r.Transitions = append(r.Transitions, Transition{From: from, To: it.Status, At: time.Now().UTC()})
+	}
+	r.Itinerary = it
+	return nil
+}
+
+func (m *Memory) ByKey(key string) (hspservice.Itinerary, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r, ok := m.records[key]
+	if !ok {
+		return hspservice.Itinerary{}, ErrNotFound
+	}
+	return r.Itinerary, nil
+}
+
+func (m *Memory) ByItineraryId(id string) (hspservice.Itinerary, error) {
+	m.mu.Lock()
+	key, ok := m.byId[id]
+	m.mu.Unlock()
+	if !ok {
+		return hspservice.Itinerary{}, ErrNotFound
+	}
+	return m.ByKey(key)
+}
+
+func (m *Memory) Transitions(key string) ([]Transition, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r, ok := m.records[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return append([]Transition(nil), r.Transitions...), nil
+}
+
+func (m *Memory) RecordAttempt(a Attempt) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	a.Number = len(m.attempts[a.IdempotencyKey]) + 1
+	m.attempts[a.IdempotencyKey] = append(m.attempts[a.IdempotencyKey], a)
+	return nil
+}
+
+func (m *Memory) Attempts(key string) ([]Attempt, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Attempt(nil), m.attempts[key]...), nil
+}
+
+func (m *Memory) Close() error { return nil }
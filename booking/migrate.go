@@ -0,0 +1,78 @@
+package booking
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/boltdb/bolt"
+)
+
+var (
+	metaBucket       = []byte("meta")
+	schemaVersionKey = []byte("schema_version")
+)
+
+// migration moves a Bolt database from version-1 to version. Migrations run in
+// order, each in its own transaction, and are never edited once released: a
+// schema change is a new migration appended to the list.
+type migration struct {
+	version     uint64
+	description string
+	apply       func(tx *bolt.Tx) error
+}
+
+var migrations = []migration{
+	{1, "create itinerary and attempt buckets", func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{itinerariesBucket, attemptsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	}},
+	{2, "index itineraries by supplier itinerary id", func(tx *bolt.Tx) error {
+		idx, err := tx.CreateBucketIfNotExists(itineraryIdsBucket)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(itinerariesBucket).ForEach(func(k, v []byte) error {
+			r, err := getRecord(tx.Bucket(itinerariesBucket), string(k))
+			if err != nil || r.Itinerary.ItineraryId == "" {
+				return err
+			}
+			return idx.Put([]byte(r.Itinerary.ItineraryId), k)
+		})
+	}},
+}
+
+// SchemaVersion is the version a database is at after OpenBolt.
+var SchemaVersion = migrations[len(migrations)-1].version
+
+// migrate applies every migration newer than the database's schema version.
+func migrate(db *bolt.DB) error {
+	for _, m := range migrations {
+		err := db.Update(func(tx *bolt.Tx) error {
+			meta, err := tx.CreateBucketIfNotExists(metaBucket)
+			if err != nil {
+				return err
+			}
+			var current uint64
+			if v := meta.Get(schemaVersionKey); v != nil {
+				current = binary.BigEndian.Uint64(v)
+			}
+			if current >= m.version {
+				return nil
+			}
+			if err := m.apply(tx); err != nil {
+				return fmt.Errorf("booking: migration %d (%s): %v", m.version, m.description, err)
+			}
+			v := make([]byte, 8)
+			binary.BigEndian.PutUint64(v, m.version)
+			return meta.Put(schemaVersionKey, v)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
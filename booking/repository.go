@@ -0,0 +1,87 @@
+// Package booking stores itineraries and the booking attempts made for them,
+// so Book calls are idempotent across retries and restarts.
+package booking
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jbowles/hotel_supply_platform/hspservice"
+)
+
+var (
+	// ErrNotFound is returned when no itinerary matches the lookup.
+	ErrNotFound = errors.New("booking: itinerary not found")
+	// ErrInvalidTransition is returned by Update for a status change that is not allowed.
+	ErrInvalidTransition = errors.New("booking: invalid status transition")
+)
+
+// Repository stores itineraries keyed by idempotency key, with their status
+// history and booking attempts.
+//
+// Reserve is the idempotency check: it records a new pending itinerary, or
+// returns the one already stored under the key with fresh set to false.
+// Release removes a reservation whose booking never reached the supplier,
+// with its itinerary id if it has one.
+// Update stores the new state of an itinerary, recording a Transition when
+// its status changes.
+type Repository interface {
+	Reserve(it hspservice.Itinerary) (stored hspservice.Itinerary, fresh bool, err error)
+	Release(key string) error
+	Update(it hspservice.Itinerary) error
+	ByKey(key string) (hspservice.Itinerary, error)
+	ByItineraryId(id string) (hspservice.Itinerary, error)
+	Transitions(key string) ([]Transition, error)
+	RecordAttempt(a Attempt) error
+	Attempts(key string) ([]Attempt, error)
+	Close() error
+}
+
+// Transition is a status change of an itinerary.
+type Transition struct {
+	From string    `json:"from"`
+	To   string    `json:"to"`
+	At   time.Time `json:"at"`
+}
+
+// Attempt is one call to a supplier to book an itinerary. Request is stored
+// redacted; see Redact.
+type Attempt struct {
+	IdempotencyKey      string                 `json:"idempotency_key"`
+	Number              int                    `json:"number"` // assigned by RecordAttempt, from 1
+	At                  time.Time              `json:"at"`
+	Request             hspservice.BookRequest `json:"request"`
+	Status              string                 `json:"status"`
+	ConfirmationNumbers []string               `json:"confirmation_numbers,omitempty"`
+	Error               string                 `json:"error,omitempty"`
+}
+
+// transitions lists the statuses each status may move to.
+var transitions = map[string][]string{
+	hspservice.StatusPending:   {hspservice.StatusConfirmed, hspservice.StatusFailed, hspservice.StatusCancelled},
+	hspservice.StatusConfirmed: {hspservice.StatusCancelled},
+}
+
+// checkTransition reports whether from may move to to. Staying put is always allowed.
+func checkTransition(from, to string) error {
+	if from == to {
+		return nil
+	}
+	for _, s := range transitions[from] {
+		if s == to {
+			return nil
+		}
+	}
+	return fmt.Errorf("%v: %s to %s", ErrInvalidTransition, from, to)
+}
+
+// Redact returns r with the card number cut to its last four digits and the
+// card identifier (CVV) removed, for storing and logging.
+func Redact(r hspservice.BookRequest) hspservice.BookRequest {
+	if n := len(r.Payment.CardNumber); n > 4 {
+		r.Payment.CardNumber = "****" + r.Payment.CardNumber[n-4:]
+	}
+	r.Payment.CardIdentifier = ""
+	return r
+}
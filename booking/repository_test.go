@@ -0,0 +1,324 @@
+package booking
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/jbowles/hotel_supply_platform/hspservice"
+)
+
+// forEachRepository calls f with an empty Memory and an empty Bolt in a
+// temporary directory, each named for error messages.
+func forEachRepository(t *testing.T, f func(name string, r Repository)) {
+	f("memory", NewMemory())
+
+	dir, err := ioutil.TempDir("", "booking")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	b, err := OpenBolt(filepath.Join(dir, "booking.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	f("bolt", b)
+}
+
+func pending(key string) hspservice.Itinerary {
+	return hspservice.Itinerary{IdempotencyKey: key, Supplier: "ean", Status: hspservice.StatusPending, HotelId: "225697"}
+}
+
+func TestReserve(t *testing.T) {
+	forEachRepository(t, func(name string, r Repository) {
+		stored, fresh, err := r.Reserve(pending("key-1"))
+		if err != nil || !fresh || stored.IdempotencyKey != "key-1" {
+			t.Fatalf("%s: first reserve %+v, %v, %v; want it fresh", name, stored, fresh, err)
+		}
+		it := pending("key-1")
+		it.Status, it.ItineraryId = hspservice.StatusConfirmed, "1001"
+		if err := r.Update(it); err != nil {
+			t.Fatal(err)
+		}
+
+		// The retry gets the stored itinerary, not its own.
+		retry := pending("key-1")
+		retry.HotelId = "116908"
+		stored, fresh, err = r.Reserve(retry)
+		if err != nil || fresh {
+			t.Fatalf("%s: second reserve fresh %v, %v; want the stored one", name, fresh, err)
+		}
+		if stored.Status != hspservice.StatusConfirmed || stored.ItineraryId != "1001" || stored.HotelId != "225697" {
+			t.Errorf("%s: stored %+v, want the confirmed itinerary", name, stored)
+		}
+
+		if _, fresh, _ := r.Reserve(pending("key-2")); !fresh {
+			t.Errorf("%s: another key is not fresh", name)
+		}
+	})
+}
+
+func TestRelease(t *testing.T) {
+	forEachRepository(t, func(name string, r Repository) {
+		r.Reserve(pending("key-1"))
+		it := pending("key-1")
+		it.ItineraryId = "1001"
+		if err := r.Update(it); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.Release("key-1"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := r.ByKey("key-1"); err != ErrNotFound {
+			t.Errorf("%s: by key after release: %v, want %v", name, err, ErrNotFound)
+		}
+		if _, fresh, _ := r.Reserve(pending("key-1")); !fresh {
+			t.Errorf("%s: a released key is not fresh", name)
+		}
+		// The new reservation under the key is not the released itinerary.
+		if got, err := r.ByItineraryId("1001"); err != ErrNotFound {
+			t.Errorf("%s: by itinerary id after release: %+v, %v; want %v", name, got, err, ErrNotFound)
+		}
+		if err := r.Release("key-none"); err != nil {
+			t.Errorf("%s: releasing an unknown key: %v", name, err)
+		}
+	})
+}
+
+func TestUpdateTransitions(t *testing.T) {
+	forEachRepository(t, func(name string, r Repository) {
+		if err := r.Update(pending("key-none")); err != ErrNotFound {
+			t.Errorf("%s: update of an unknown key: %v, want %v", name, err, ErrNotFound)
+		}
+
+		r.Reserve(pending("key-1"))
+		for _, tc := range []struct {
+			status string
+			ok     bool
+		}{
+			{hspservice.StatusPending, true}, // staying put
+			{hspservice.StatusConfirmed, true},
+			{hspservice.StatusPending, false},
+			{hspservice.StatusFailed, false},
+			{hspservice.StatusConfirmed, true},
+			{hspservice.StatusCancelled, true},
+			{hspservice.StatusConfirmed, false},
+		} {
+			it := pending("key-1")
+			it.Status = tc.status
+			err := r.Update(it)
+			if tc.ok && err != nil {
+				t.Errorf("%s: update to %s: %v", name, tc.status, err)
+			}
+			if !tc.ok && (err == nil || !strings.Contains(err.Error(), ErrInvalidTransition.Error())) {
+				t.Errorf("%s: update to %s: %v, want %v", name, tc.status, err, ErrInvalidTransition)
+			}
+		}
+		if it, _ := r.ByKey("key-1"); it.Status != hspservice.StatusCancelled {
+			t.Errorf("%s: status %s, want cancelled", name, it.Status)
+		}
+
+		ts, err := r.Transitions("key-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []Transition{
+			{From: hspservice.StatusPending, To: hspservice.StatusConfirmed},
+			{From: hspservice.StatusConfirmed, To: hspservice.StatusCancelled},
+		}
+		if len(ts) != len(want) {
+			t.Fatalf("%s: transitions %+v, want %+v", name, ts, want)
+		}
+		for i, tr := range ts {
+			if tr.From != want[i].From || tr.To != want[i].To || tr.At.IsZero() {
+				t.Errorf("%s: transition %d %+v, want %+v with a time", name, i, tr, want[i])
+			}
+		}
+		if _, err := r.Transitions("key-none"); err != ErrNotFound {
+			t.Errorf("%s: transitions of an unknown key: %v, want %v", name, err, ErrNotFound)
+		}
+	})
+}
+
+func TestByItineraryId(t *testing.T) {
+	forEachRepository(t, func(name string, r Repository) {
+		r.Reserve(pending("key-1"))
+		if _, err := r.ByItineraryId("1001"); err != ErrNotFound {
+			t.Errorf("%s: before the supplier's id: %v, want %v", name, err, ErrNotFound)
+		}
+		it := pending("key-1")
+		it.Status, it.ItineraryId, it.Email = hspservice.StatusConfirmed, "1001", "guest@example.com"
+		if err := r.Update(it); err != nil {
+			t.Fatal(err)
+		}
+		got, err := r.ByItineraryId("1001")
+		if err != nil || got.IdempotencyKey != "key-1" || got.Email != "guest@example.com" {
+			t.Errorf("%s: by itinerary id %+v, %v; want key-1", name, got, err)
+		}
+	})
+}
+
+func TestRecordAttempt(t *testing.T) {
+	forEachRepository(t, func(name string, r Repository) {
+		// The attempts of "ab" sort right after those of "a"; without the NUL
+		// separator "a" would count them as its own.
+		for _, a := range []Attempt{
+			{IdempotencyKey: "a", Status: hspservice.StatusPending, Error: "timeout"},
+			{IdempotencyKey: "ab", Status: hspservice.StatusFailed},
+			{IdempotencyKey: "a", Status: hspservice.StatusConfirmed, ConfirmationNumbers: []string{"C1"}},
+			{IdempotencyKey: "ab", Status: hspservice.StatusFailed},
+			{IdempotencyKey: "ab", Status: hspservice.StatusConfirmed},
+		} {
+			a.At = time.Now().UTC()
+			if err := r.RecordAttempt(a); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for key, want := range map[string][]string{
+			"a":  {hspservice.StatusPending, hspservice.StatusConfirmed},
+			"ab": {hspservice.StatusFailed, hspservice.StatusFailed, hspservice.StatusConfirmed},
+			"b":  nil,
+		} {
+			as, err := r.Attempts(key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(as) != len(want) {
+				t.Errorf("%s: %d attempts of %q, want %d", name, len(as), key, len(want))
+				continue
+			}
+			for i, a := range as {
+				if a.Number != i+1 || a.Status != want[i] || a.IdempotencyKey != key {
+					t.Errorf("%s: attempt %d of %q: %+v, want number %d %s", name, i, key, a, i+1, want[i])
+				}
+			}
+		}
+		if as, _ := r.Attempts("a"); len(as) == 2 && (as[0].Error != "timeout" || len(as[1].ConfirmationNumbers) != 1) {
+			t.Errorf("%s: attempts %+v, want them as recorded", name, as)
+		}
+	})
+}
+
+func TestBoltPersists(t *testing.T) {
+	dir, err := ioutil.TempDir("", "booking")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "booking.db")
+
+	b, err := OpenBolt(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Reserve(pending("key-1"))
+	it := pending("key-1")
+	it.Status, it.ItineraryId = hspservice.StatusConfirmed, "1001"
+	b.Update(it)
+	b.RecordAttempt(Attempt{IdempotencyKey: "key-1", Status: hspservice.StatusConfirmed})
+	b.Close()
+
+	if b, err = OpenBolt(path); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	if _, fresh, _ := b.Reserve(pending("key-1")); fresh {
+		t.Error("reservation lost on reopening")
+	}
+	if got, err := b.ByItineraryId("1001"); err != nil || got.Status != hspservice.StatusConfirmed {
+		t.Errorf("by itinerary id %+v, %v; want the confirmed itinerary", got, err)
+	}
+	if err := b.RecordAttempt(Attempt{IdempotencyKey: "key-1"}); err != nil {
+		t.Fatal(err)
+	}
+	if as, _ := b.Attempts("key-1"); len(as) != 2 || as[1].Number != 2 {
+		t.Errorf("attempts %+v, want the numbering to carry on", as)
+	}
+}
+
+// schemaVersion returns the schema version of the database at path.
+func schemaVersion(t *testing.T, path string) uint64 {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var version uint64
+	db.View(func(tx *bolt.Tx) error {
+		if meta := tx.Bucket(metaBucket); meta != nil {
+			version = binary.BigEndian.Uint64(meta.Get(schemaVersionKey))
+		}
+		return nil
+	})
+	return version
+}
+
+func TestMigrate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "booking")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "booking.db")
+
+	// A database at version 1, from before itineraries were indexed by id.
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		if err := migrations[0].apply(tx); err != nil {
+			return err
+		}
+		meta, err := tx.CreateBucket(metaBucket)
+		if err != nil {
+			return err
+		}
+		v := make([]byte, 8)
+		binary.BigEndian.PutUint64(v, 1)
+		if err := meta.Put(schemaVersionKey, v); err != nil {
+			return err
+		}
+		bk := tx.Bucket(itinerariesBucket)
+		confirmed := pending("key-1")
+		confirmed.Status, confirmed.ItineraryId = hspservice.StatusConfirmed, "1001"
+		if err := putRecord(bk, &record{Itinerary: confirmed}); err != nil {
+			return err
+		}
+		return putRecord(bk, &record{Itinerary: pending("key-2")})
+	})
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := OpenBolt(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := b.ByItineraryId("1001"); err != nil || got.IdempotencyKey != "key-1" {
+		t.Errorf("by itinerary id after migrating: %+v, %v; want key-1", got, err)
+	}
+	if got, err := b.ByKey("key-2"); err != nil || got.Status != hspservice.StatusPending {
+		t.Errorf("by key after migrating: %+v, %v; want key-2 pending", got, err)
+	}
+	b.Close()
+	if v := schemaVersion(t, path); v != SchemaVersion {
+		t.Errorf("schema version %d, want %d", v, SchemaVersion)
+	}
+
+	// Opening a current database migrates nothing.
+	if b, err = OpenBolt(path); err != nil {
+		t.Fatal(err)
+	}
+	b.Close()
+	if v := schemaVersion(t, path); v != SchemaVersion {
+		t.Errorf("schema version %d after reopening, want %d", v, SchemaVersion)
+	}
+}
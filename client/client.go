@@ -0,0 +1,251 @@
+// Package client is a Go client for the hsp HTTP API. New returns an
+// hspservice.Hsp, so a remote hsp is used just like a local one.
+//
+// Calls are load balanced round robin over the hsp instances and retried on
+// transport errors and 5xx statuses, within the call's deadline; a 4xx status
+// or a response that cannot be decoded is returned at once. Book is safe to
+// retry as hsp deduplicates bookings by idempotency key, so it requires one.
+// Errors the service returns are
+// decoded into the response Error: the hspservice sentinel errors (such as
+// ErrNotSupported) when the service sent a known code, otherwise an
+// *hspservice.Error. Transport failures are returned there too, as a
+// *StatusError when an instance answered with a non 2xx status. Calls carry
+// the trace of their context in a W3C traceparent header, and its request id
+// (see hspservice.NewRequestIDContext) in an X-Request-Id header.
+package client
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/loadbalancer"
+	"github.com/go-kit/kit/loadbalancer/static"
+	"github.com/go-kit/kit/log"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/jbowles/hotel_supply_platform/hspservice"
+	"github.com/jbowles/hotel_supply_platform/trace"
+)
+
+// ErrNoInstances is returned by New when it is given no hsp instances.
+var ErrNoInstances = errors.New("client: no hsp instances")
+
+// StatusError is returned when an hsp instance answers with a non 2xx status,
+// which it does for requests it cannot decode.
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("client: hsp returned %d: %s", e.StatusCode, e.Body)
+}
+
+// finalError marks the error of an attempt that another attempt would only
+// repeat, so it is returned without retrying.
+type finalError struct{ err error }
+
+func (e finalError) Error() string { return e.err.Error() }
+
+// Client is an hspservice.Hsp backed by one or more hsp instances.
+type Client struct {
+	ctx         context.Context
+	timeout     time.Duration
+	maxAttempts int
+	httpClient  *http.Client
+	logger      log.Logger
+
+	rateBreakdown, book, itinerary, cancel endpoint.Endpoint
+}
+
+// Option sets an optional parameter of a Client.
+type Option func(*Client)
+
+// Timeout bounds each call, retries included. The default is 5 seconds.
+func Timeout(d time.Duration) Option { return func(c *Client) { c.timeout = d } }
+
+// MaxAttempts sets how many times a call is tried. The default is 3.
+func MaxAttempts(n int) Option { return func(c *Client) { c.maxAttempts = n } }
+
+// HTTPClient sets the http.Client used for requests. The default is
+// http.DefaultClient.
+func HTTPClient(hc *http.Client) Option { return func(c *Client) { c.httpClient = hc } }
+
+// Logger sets the logger for instance errors. The default discards them.
+func Logger(logger log.Logger) Option { return func(c *Client) { c.logger = logger } }
+
+// New returns a Client for the hsp instances, given as base URLs such as
+// "http://hsp-1:8022" or just "hsp-1:8022"; a path in the URL is kept as a
+// prefix of the API paths.
+func New(instances []string, options ...Option) (*Client, error) {
+	if len(instances) == 0 {
+		return nil, ErrNoInstances
+	}
+	c := &Client{
+		ctx:         context.Background(),
+		timeout:     5 * time.Second,
+		maxAttempts: 3,
+		httpClient:  http.DefaultClient,
+		logger:      log.NewNopLogger(),
+	}
+	for _, option := range options {
+		option(c)
+	}
+	for _, instance := range instances {
+		if _, err := baseURL(instance); err != nil {
+			return nil, err
+		}
+	}
+
+	c.rateBreakdown = c.balance(instances, "/rate_breakdown", hspservice.EncodeRateBreakdownRequest, hspservice.DecodeRateBreakdownResponse)
+	c.book = c.balance(instances, "/book", hspservice.EncodeBookRequest, hspservice.DecodeBookResponse)
+	c.itinerary = c.balance(instances, "/itinerary", hspservice.EncodeItineraryRequest, hspservice.DecodeItineraryResponse)
+	c.cancel = c.balance(instances, "/cancel", hspservice.EncodeCancelRequest, hspservice.DecodeCancelResponse)
+	return c, nil
+}
+
+// WithContext returns a copy of c whose calls are made with ctx, so they end
+// at its deadline or cancellation, or at c's Timeout if that comes first.
+func (c *Client) WithContext(ctx context.Context) hspservice.Hsp {
+	c2 := *c
+	c2.ctx = ctx
+	return &c2
+}
+
+// balance returns an endpoint for the API path that round robins over the
+// instances, retrying failed attempts.
+func (c *Client) balance(instances []string, path string, enc httptransport.EncodeRequestFunc, dec httptransport.DecodeResponseFunc) endpoint.Endpoint {
+	factory := func(instance string) (endpoint.Endpoint, io.Closer, error) {
+		u, err := baseURL(instance)
+		if err != nil {
+			return nil, nil, err
+		}
+		u.Path = strings.TrimRight(u.Path, "/") + path
+		return httptransport.NewClient(
+			"POST",
+			u,
+			final(enc),
+			checkStatus(dec),
+			httptransport.SetClient(c.httpClient),
+			httptransport.ClientBefore(trace.ContextToHTTP, hspservice.RequestIDToHTTP),
+		).Endpoint(), nil, nil
+	}
+	publisher := static.NewPublisher(instances, factory, c.logger)
+	return c.retry(loadbalancer.NewRoundRobin(publisher))
+}
+
+// retry returns an endpoint trying the endpoints of lb in turn, up to
+// maxAttempts times within timeout, until one succeeds or fails with a
+// finalError. It returns the error of the last attempt as is, so callers can
+// tell a *StatusError from a transport error.
+func (c *Client) retry(lb loadbalancer.LoadBalancer) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		ctx, cancel := context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+		var err error
+		for attempt := 1; attempt <= c.maxAttempts; attempt++ {
+			e, lbErr := lb.Endpoint()
+			if lbErr != nil {
+				return nil, lbErr
+			}
+			var response interface{}
+			if response, err = e(ctx, request); err == nil {
+				return response, nil
+			}
+			if f, ok := err.(finalError); ok {
+				return nil, f.err
+			}
+			c.logger.Log("attempt", attempt, "err", err)
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		return nil, err
+	}
+}
+
+func baseURL(instance string) (*url.URL, error) {
+	if !strings.HasPrefix(instance, "http") {
+		instance = "http://" + instance
+	}
+	return url.Parse(instance)
+}
+
+// final wraps enc so its errors are not retried.
+func final(enc httptransport.EncodeRequestFunc) httptransport.EncodeRequestFunc {
+	return func(r *http.Request, request interface{}) error {
+		if err := enc(r, request); err != nil {
+			return finalError{err}
+		}
+		return nil
+	}
+}
+
+// checkStatus wraps dec so non 2xx responses are returned as a *StatusError.
+// Only 5xx statuses are retried; 4xx statuses and decode errors are final.
+func checkStatus(dec httptransport.DecodeResponseFunc) httptransport.DecodeResponseFunc {
+	return func(resp *http.Response) (interface{}, error) {
+		if resp.StatusCode/100 != 2 {
+			body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<10))
+			err := &StatusError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(body))}
+			if resp.StatusCode/100 == 5 {
+				return nil, err
+			}
+			return nil, finalError{err}
+		}
+		response, err := dec(resp)
+		if err != nil {
+			return nil, finalError{err}
+		}
+		return response, nil
+	}
+}
+
+// RateBreakdown implements hspservice.Hsp.
+func (c *Client) RateBreakdown(req hspservice.RateBreakdownRequest) hspservice.RateBreakdownResponse {
+	resp, err := c.rateBreakdown(c.ctx, req)
+	if err != nil {
+		return hspservice.RateBreakdownResponse{Request: req, Error: err}
+	}
+	return resp.(hspservice.RateBreakdownResponse)
+}
+
+// Book implements hspservice.Hsp. It returns hspservice.ErrNoIdempotencyKey
+// without calling hsp when req has no IdempotencyKey, as it could not be
+// retried safely.
+func (c *Client) Book(req hspservice.BookRequest) hspservice.BookResponse {
+	if req.IdempotencyKey == "" {
+		return hspservice.BookResponse{Error: hspservice.ErrNoIdempotencyKey}
+	}
+	resp, err := c.book(c.ctx, req)
+	if err != nil {
+		return hspservice.BookResponse{Error: err}
+	}
+	return resp.(hspservice.BookResponse)
+}
+
+// GetItinerary implements hspservice.Hsp.
+func (c *Client) GetItinerary(req hspservice.ItineraryRequest) hspservice.ItineraryResponse {
+	resp, err := c.itinerary(c.ctx, req)
+	if err != nil {
+		return hspservice.ItineraryResponse{Error: err}
+	}
+	return resp.(hspservice.ItineraryResponse)
+}
+
+// Cancel implements hspservice.Hsp.
+func (c *Client) Cancel(req hspservice.CancelRequest) hspservice.CancelResponse {
+	resp, err := c.cancel(c.ctx, req)
+	if err != nil {
+		return hspservice.CancelResponse{Error: err}
+	}
+	return resp.(hspservice.CancelResponse)
+}
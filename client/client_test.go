@@ -0,0 +1,137 @@
+package client
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/jbowles/hotel_supply_platform/hspservice"
+)
+
+// instance is an hsp instance answering its calls with the statuses in turn,
+// then with a rate breakdown. It counts the calls it is sent.
+type instance struct {
+	statuses []int
+	body     string
+
+	mtx   sync.Mutex
+	calls int
+}
+
+func (in *instance) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	in.mtx.Lock()
+	n := in.calls
+	in.calls++
+	in.mtx.Unlock()
+	if n < len(in.statuses) {
+		http.Error(w, http.StatusText(in.statuses[n]), in.statuses[n])
+		return
+	}
+	body := in.body
+	if body == "" {
+		body = `{"request":{},"rates":[{"supplier":"ean","hotel_id":"225697"}]}`
+	}
+	w.Header().Set("Content-Type", "application/json")
+	io.WriteString(w, body)
+}
+
+func (in *instance) count() int {
+	in.mtx.Lock()
+	defer in.mtx.Unlock()
+	return in.calls
+}
+
+func newClient(t *testing.T, instances ...string) *Client {
+	c, err := New(instances)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestRetryServerError(t *testing.T) {
+	in := &instance{statuses: []int{http.StatusServiceUnavailable}}
+	srv := httptest.NewServer(in)
+	defer srv.Close()
+
+	res := newClient(t, srv.URL).RateBreakdown(hspservice.RateBreakdownRequest{})
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	if len(res.Rates) != 1 {
+		t.Errorf("rates %+v, want the second attempt's", res.Rates)
+	}
+	if n := in.count(); n != 2 {
+		t.Errorf("%d calls, want 2", n)
+	}
+}
+
+func TestRetryTransportError(t *testing.T) {
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	in := &instance{}
+	srv := httptest.NewServer(in)
+	defer srv.Close()
+
+	res := newClient(t, down.URL, srv.URL).RateBreakdown(hspservice.RateBreakdownRequest{})
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	if n := in.count(); n != 1 {
+		t.Errorf("%d calls to the instance up, want 1", n)
+	}
+}
+
+func TestRetryExhausted(t *testing.T) {
+	in := &instance{statuses: []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway}}
+	srv := httptest.NewServer(in)
+	defer srv.Close()
+
+	res := newClient(t, srv.URL).RateBreakdown(hspservice.RateBreakdownRequest{})
+	if e, ok := res.Error.(*StatusError); !ok || e.StatusCode != http.StatusBadGateway {
+		t.Errorf("error %#v, want a *StatusError 502", res.Error)
+	}
+	if n := in.count(); n != 3 {
+		t.Errorf("%d calls, want 3", n)
+	}
+}
+
+func TestNoRetry(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		in     *instance
+		status int
+	}{
+		{"bad request", &instance{statuses: []int{http.StatusBadRequest}}, http.StatusBadRequest},
+		{"undecodable", &instance{body: "{"}, 0},
+	} {
+		srv := httptest.NewServer(tc.in)
+		res := newClient(t, srv.URL).RateBreakdown(hspservice.RateBreakdownRequest{})
+		srv.Close()
+		if res.Error == nil {
+			t.Errorf("%s: no error", tc.name)
+		}
+		if e, ok := res.Error.(*StatusError); tc.status != 0 && (!ok || e.StatusCode != tc.status) {
+			t.Errorf("%s: error %#v, want a *StatusError %d", tc.name, res.Error, tc.status)
+		}
+		if n := tc.in.count(); n != 1 {
+			t.Errorf("%s: %d calls, want 1", tc.name, n)
+		}
+	}
+}
+
+func TestBookRequiresIdempotencyKey(t *testing.T) {
+	in := &instance{}
+	srv := httptest.NewServer(in)
+	defer srv.Close()
+
+	res := newClient(t, srv.URL).Book(hspservice.BookRequest{HotelId: "225697"})
+	if res.Error != hspservice.ErrNoIdempotencyKey {
+		t.Errorf("error %v, want ErrNoIdempotencyKey", res.Error)
+	}
+	if n := in.count(); n != 0 {
+		t.Errorf("%d calls, want none", n)
+	}
+}
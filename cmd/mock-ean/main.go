@@ -0,0 +1,79 @@
+// Command mock-ean serves a mock of the EAN hotel API (see package eanmock)
+// for local development: run it and start hsp in the mock EAN environment,
+// or with -ean.url pointing at it when it runs elsewhere.
+//
+//	mock-ean -addr :9000 -latency 200ms -fail res=SOLD_OUT
+//	hsp -ean.env mock
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/go-kit/kit/log"
+	"github.com/jbowles/hotel_supply_platform/eanmock"
+)
+
+func main() {
+	var (
+		addr     = flag.String("addr", ":9000", "Address to serve the mock EAN API on")
+		fixtures = flag.String("fixtures", "", "Fixtures file (JSON); empty uses the built in hotels")
+		latency  = flag.Duration("latency", 0, "Latency added to every response")
+		jitter   = flag.Duration("jitter", 0, "Random latency added on top of -latency, up to this much")
+		qps      = flag.Int("qps", 0, "Requests a second served before answering 403 Developer Over Qps; 0 is unlimited")
+		pageSize = flag.Int("page-size", 20, "Hotels per hotel list page")
+		fail     = flag.String("fail", "", "Comma separated endpoint=CATEGORY errors to force, e.g. res=SOLD_OUT,list=PRICE_MISMATCH; endpoints are list, avail, res, itin and cancel")
+	)
+	flag.Parse()
+
+	logger := log.NewLogfmtLogger(os.Stderr)
+	logger = log.NewContext(logger).With("ts", log.DefaultTimestampUTC)
+
+	f := eanmock.DefaultFixtures()
+	if *fixtures != "" {
+		var err error
+		if f, err = eanmock.OpenFixtures(*fixtures); err != nil {
+			logger.Log("fatal", err)
+			os.Exit(1)
+		}
+	}
+	failures, err := parseFail(*fail)
+	if err != nil {
+		logger.Log("fatal", err)
+		os.Exit(1)
+	}
+
+	mock := eanmock.New(f, eanmock.Config{
+		Latency:  *latency,
+		Jitter:   *jitter,
+		QPS:      *qps,
+		PageSize: *pageSize,
+		Fail:     failures,
+	})
+	logger.Log("addr", *addr, "hotels", len(f.Hotels))
+	logger.Log("exit", http.ListenAndServe(*addr, mock))
+}
+
+// parseFail parses the -fail flag.
+func parseFail(s string) (map[string]string, error) {
+	failures := map[string]string{}
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+			return nil, fmt.Errorf("invalid -fail %q, want endpoint=CATEGORY", part)
+		}
+		switch kv[0] {
+		case eanmock.HotelList, eanmock.RoomAvailability, eanmock.Reservation, eanmock.Itinerary, eanmock.Cancellation:
+		default:
+			return nil, fmt.Errorf("invalid -fail endpoint %q", kv[0])
+		}
+		failures[kv[0]] = kv[1]
+	}
+	return failures, nil
+}
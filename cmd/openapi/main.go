@@ -0,0 +1,44 @@
+// Command openapi writes the OpenAPI spec of the hsp HTTP API, as generated by
+// hspservice.OpenAPI. With -check it writes nothing and exits 1 if the file is
+// not up to date, for CI.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/jbowles/hotel_supply_platform/hspservice"
+)
+
+func main() {
+	var (
+		out   = flag.String("o", "openapi.json", "Spec file")
+		check = flag.Bool("check", false, "Check the spec file is up to date instead of writing it")
+	)
+	flag.Parse()
+
+	spec, err := hspservice.OpenAPI()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if *check {
+		current, err := ioutil.ReadFile(*out)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if !bytes.Equal(current, spec) {
+			fmt.Fprintf(os.Stderr, "%s is out of date; run go generate ./hspservice\n", *out)
+			os.Exit(1)
+		}
+		return
+	}
+	if err := ioutil.WriteFile(*out, spec, 0644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
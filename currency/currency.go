@@ -0,0 +1,69 @@
+// Package currency provides exact decimal money amounts and conversion between
+// currencies. Amounts are held exactly and all arithmetic goes through
+// math/big, never floats; they are rounded per currency only for display.
+package currency
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// ErrUnknownCurrency is returned for codes that cannot be ISO 4217 codes,
+// which are three letters.
+var ErrUnknownCurrency = errors.New("currency: unknown currency code")
+
+// Rule is the rounding rule for a currency. Exponent is the number of minor
+// unit digits (2 for USD, 0 for JPY). Increment is the smallest amount, in
+// minor units, prices are rounded to; 0 and 1 both mean the minor unit.
+type Rule struct {
+	Exponent  int
+	Increment int64
+}
+
+var rules = map[string]Rule{
+	"AUD": {2, 1}, "BRL": {2, 1}, "CAD": {2, 1}, "CNY": {2, 1}, "DKK": {2, 1},
+	"EUR": {2, 1}, "GBP": {2, 1}, "HKD": {2, 1}, "INR": {2, 1}, "MXN": {2, 1},
+	"NOK": {2, 1}, "NZD": {2, 1}, "SEK": {2, 1}, "SGD": {2, 1}, "THB": {2, 1},
+	"USD": {2, 1}, "ZAR": {2, 1},
+	"CHF": {2, 5}, // rounded to 5 Rappen
+	"CLP": {0, 1}, "ISK": {0, 1}, "JPY": {0, 1}, "KRW": {0, 1}, "VND": {0, 1},
+	"BHD": {3, 1}, "JOD": {3, 1}, "KWD": {3, 1}, "OMR": {3, 1}, "TND": {3, 1},
+}
+
+// defaultRule is the rule of the codes missing from rules: most currencies
+// have two minor unit digits, and a new or rare one should not make its rates
+// unusable.
+var defaultRule = Rule{2, 1}
+
+// RuleFor returns the rounding rule for an ISO 4217 currency code, the
+// default two digits for a code it has no rule for.
+func RuleFor(code string) (Rule, error) {
+	code = strings.ToUpper(code)
+	if r, ok := rules[code]; ok {
+		return r, nil
+	}
+	if len(code) != 3 || strings.Trim(code, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+		return Rule{}, fmt.Errorf("%v: %q", ErrUnknownCurrency, code)
+	}
+	return defaultRule, nil
+}
+
+// round rounds an exact amount in major units to the rule's increment,
+// half away from zero.
+func (r Rule) round(amount *big.Rat) *big.Rat {
+	inc := r.Increment
+	if inc < 1 {
+		inc = 1
+	}
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(r.Exponent)), nil)
+	steps := new(big.Rat).Mul(amount, new(big.Rat).SetFrac(scale, big.NewInt(inc)))
+
+	num, den := steps.Num(), steps.Denom()
+	q, m := new(big.Int).QuoRem(num, den, new(big.Int))
+	if new(big.Int).Mul(new(big.Int).Abs(m), big.NewInt(2)).Cmp(den) >= 0 {
+		q.Add(q, big.NewInt(int64(num.Sign())))
+	}
+	return new(big.Rat).SetFrac(q.Mul(q, big.NewInt(inc)), scale)
+}
@@ -0,0 +1,104 @@
+package currency
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Money is an exact amount of a currency. Amounts are kept as given or
+// computed; they are rounded by the currency's Rule only when formatted.
+// The zero Money is zero of no currency.
+type Money struct {
+	amount   *big.Rat // in major units; nil is zero
+	Currency string
+}
+
+// Parse reads a decimal amount such as "123.45" in the given currency, exactly.
+func Parse(amount, code string) (Money, error) {
+	if _, err := RuleFor(code); err != nil {
+		return Money{}, err
+	}
+	a, ok := new(big.Rat).SetString(strings.TrimSpace(amount))
+	if !ok {
+		return Money{}, fmt.Errorf("currency: invalid amount %q", amount)
+	}
+	return Money{amount: a, Currency: strings.ToUpper(code)}, nil
+}
+
+// Rat returns the exact amount in major units.
+func (m Money) Rat() *big.Rat {
+	if m.amount == nil {
+		return new(big.Rat)
+	}
+	return new(big.Rat).Set(m.amount)
+}
+
+// String formats the amount in major units without the currency code, e.g.
+// "123.45", rounded by the currency's Rule.
+func (m Money) String() string {
+	r, _ := RuleFor(m.Currency)
+	return r.round(m.Rat()).FloatString(r.Exponent)
+}
+
+type money struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// MarshalJSON implements json.Marshaler. The amount is a decimal string, as
+// formatted by String, so clients do not read it back as a float.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(money{m.String(), m.Currency})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (m *Money) UnmarshalJSON(b []byte) error {
+	var v money
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	p, err := Parse(v.Amount, v.Currency)
+	if err != nil {
+		return err
+	}
+	*m = p
+	return nil
+}
+
+type moneyXML struct {
+	Amount   string `xml:",chardata"`
+	Currency string `xml:"currency,attr"`
+}
+
+// MarshalXML implements xml.Marshaler, as the amount with a currency
+// attribute: <total currency="USD">123.45</total>.
+func (m Money) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(moneyXML{m.String(), m.Currency}, start)
+}
+
+// UnmarshalXML implements xml.Unmarshaler.
+func (m *Money) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var v moneyXML
+	if err := d.DecodeElement(&v, &start); err != nil {
+		return err
+	}
+	p, err := Parse(v.Amount, v.Currency)
+	if err != nil {
+		return err
+	}
+	*m = p
+	return nil
+}
+
+// Mul returns m multiplied by r, exactly.
+func (m Money) Mul(r *big.Rat) Money {
+	return Money{amount: new(big.Rat).Mul(m.Rat(), r), Currency: m.Currency}
+}
+
+// Cmp compares two amounts of the same currency, returning -1, 0 or +1.
+func (m Money) Cmp(o Money) int {
+	return m.Rat().Cmp(o.Rat())
+}
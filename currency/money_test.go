@@ -0,0 +1,99 @@
+package currency
+
+import (
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestParseIsExact(t *testing.T) {
+	for _, tc := range []struct {
+		amount, code, exact, display string
+	}{
+		{"289.52", "CHF", "289.52", "289.50"},
+		{"289.53", "CHF", "289.53", "289.55"},
+		{"10.005", "USD", "10.005", "10.01"},
+		{"-10.005", "USD", "-10.005", "-10.01"},
+		{"1234.5", "JPY", "1234.5", "1235"},
+		{"1.0005", "KWD", "1.0005", "1.001"},
+		{"10.005", "pln", "10.005", "10.01"}, // no rule of its own
+	} {
+		m, err := Parse(tc.amount, tc.code)
+		if err != nil {
+			t.Fatalf("%s %s: %v", tc.amount, tc.code, err)
+		}
+		exact, _ := new(big.Rat).SetString(tc.exact)
+		if m.Rat().Cmp(exact) != 0 {
+			t.Errorf("%s %s: amount %s, want %s", tc.amount, tc.code, m.Rat().FloatString(4), tc.exact)
+		}
+		if s := m.String(); s != tc.display {
+			t.Errorf("%s %s: displayed %s, want %s", tc.amount, tc.code, s, tc.display)
+		}
+	}
+}
+
+func TestParseUnknownCurrency(t *testing.T) {
+	for _, code := range []string{"", "US", "USDX", "U$D", "123"} {
+		if _, err := Parse("1.00", code); err == nil || !strings.Contains(err.Error(), ErrUnknownCurrency.Error()) {
+			t.Errorf("%q: error %v, want %v", code, err, ErrUnknownCurrency)
+		}
+	}
+}
+
+func TestUnmarshalJSONIsExact(t *testing.T) {
+	var m Money
+	if err := json.Unmarshal([]byte(`{"amount": "289.52", "currency": "chf"}`), &m); err != nil {
+		t.Fatal(err)
+	}
+	if want := big.NewRat(28952, 100); m.Rat().Cmp(want) != 0 || m.Currency != "CHF" {
+		t.Errorf("got %s %s, want 289.52 CHF", m.Rat().FloatString(2), m.Currency)
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"amount":"289.50","currency":"CHF"}`; string(b) != want {
+		t.Errorf("marshaled %s, want %s", b, want)
+	}
+}
+
+func TestArithmeticIsExact(t *testing.T) {
+	m, _ := Parse("100.00", "USD")
+	third := m.Mul(big.NewRat(1, 3))
+	if back := third.Mul(big.NewRat(3, 1)); back.Cmp(m) != 0 {
+		t.Errorf("a third of %s times 3 is %s", m, back.Rat().FloatString(10))
+	}
+	if third.String() != "33.33" {
+		t.Errorf("a third of %s displayed %s, want 33.33", m, third)
+	}
+}
+
+func TestLargeAmounts(t *testing.T) {
+	// Far beyond an int64 of minor units.
+	m, err := Parse("123456789012345678901234567890.125", "USD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := m.String(); s != "123456789012345678901234567890.13" {
+		t.Errorf("displayed %s", s)
+	}
+	if s := m.Mul(big.NewRat(1000, 1)).String(); s != "123456789012345678901234567890125.00" {
+		t.Errorf("times 1000 displayed %s", s)
+	}
+}
+
+func TestConvertIsExact(t *testing.T) {
+	table := &Table{Base: "USD", Rates: map[string]*big.Rat{"CHF": big.NewRat(9, 10)}}
+	m, _ := Parse("100.03", "USD")
+	chf, err := Converter{table}.Convert(m, "chf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := big.NewRat(90027, 1000); chf.Rat().Cmp(want) != 0 || chf.Currency != "CHF" {
+		t.Errorf("converted to %s %s, want 90.027 CHF", chf.Rat().FloatString(3), chf.Currency)
+	}
+	if s := chf.String(); s != "90.05" {
+		t.Errorf("displayed %s, want 90.05", s)
+	}
+}
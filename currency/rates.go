@@ -0,0 +1,110 @@
+package currency
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+)
+
+// Provider supplies exchange rates: one unit of from is worth Rate units of to.
+type Provider interface {
+	Rate(from, to string) (*big.Rat, error)
+}
+
+// Table is a Provider backed by a static set of rates against a base currency.
+type Table struct {
+	Base  string
+	AsOf  time.Time
+	Rates map[string]*big.Rat // units of the currency per one unit of Base
+}
+
+type tableFile struct {
+	Base  string                 `json:"base"`
+	AsOf  time.Time              `json:"as_of"`
+	Rates map[string]json.Number `json:"rates"`
+}
+
+// LoadTable reads a rates table such as
+//
+//	{"base": "USD", "as_of": "2016-03-01T00:00:00Z", "rates": {"EUR": "0.9187", "JPY": 113.6}}
+//
+// Rates may be JSON strings or numbers; either way they are parsed exactly.
+func LoadTable(r io.Reader) (*Table, error) {
+	var f tableFile
+	d := json.NewDecoder(r)
+	d.UseNumber()
+	if err := d.Decode(&f); err != nil {
+		return nil, err
+	}
+	t := &Table{Base: strings.ToUpper(f.Base), AsOf: f.AsOf, Rates: map[string]*big.Rat{}}
+	if _, err := RuleFor(t.Base); err != nil {
+		return nil, err
+	}
+	for code, n := range f.Rates {
+		rate, ok := new(big.Rat).SetString(n.String())
+		if !ok || rate.Sign() <= 0 {
+			return nil, fmt.Errorf("currency: invalid rate %q for %s", n, code)
+		}
+		t.Rates[strings.ToUpper(code)] = rate
+	}
+	return t, nil
+}
+
+// LoadFile reads a rates table from a file; see LoadTable.
+func LoadFile(path string) (*Table, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadTable(f)
+}
+
+// Rate implements Provider, crossing through the base currency when needed.
+func (t *Table) Rate(from, to string) (*big.Rat, error) {
+	from, to = strings.ToUpper(from), strings.ToUpper(to)
+	if from == to {
+		return big.NewRat(1, 1), nil
+	}
+	f, err := t.perBase(from)
+	if err != nil {
+		return nil, err
+	}
+	g, err := t.perBase(to)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Rat).Quo(g, f), nil
+}
+
+func (t *Table) perBase(code string) (*big.Rat, error) {
+	if code == t.Base {
+		return big.NewRat(1, 1), nil
+	}
+	r, ok := t.Rates[code]
+	if !ok {
+		return nil, fmt.Errorf("currency: no %s rate for %s", t.Base, code)
+	}
+	return r, nil
+}
+
+// Converter converts Money between currencies using a Provider.
+type Converter struct {
+	Provider
+}
+
+// Convert returns m in the currency to, exactly.
+func (c Converter) Convert(m Money, to string) (Money, error) {
+	if _, err := RuleFor(to); err != nil {
+		return Money{}, err
+	}
+	rate, err := c.Rate(m.Currency, to)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{amount: new(big.Rat).Mul(m.Rat(), rate), Currency: strings.ToUpper(to)}, nil
+}
@@ -0,0 +1,504 @@
+package main
+
+// EAN booking: the res, itin and cancel APIs. Requests are sent as the xml
+// param, like HotelAvail; responses are decoded in the service's Format.
+// Book passes the idempotency key to EAN as affiliateConfirmationId, which EAN
+// refuses to book twice, so a retry can never double book even when our own
+// record of the first attempt was lost.
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jbowles/hotel_supply_platform/booking"
+	"github.com/jbowles/hotel_supply_platform/hspservice"
+	"github.com/jbowles/hotel_supply_platform/trace"
+)
+
+var (
+	// ErrNoEanClient is returned by calls that need EAN when the service has no Client.
+	ErrNoEanClient = errors.New("ean: no http client configured")
+	// ErrNoItineraryStore is returned by Book when the service has no Itineraries.
+	ErrNoItineraryStore = errors.New("ean: no itinerary store configured")
+)
+
+// HotelRoomReservationRequest is the res API request.
+type HotelRoomReservationRequest struct {
+	XMLName                 xml.Name          `xml:"HotelRoomReservationRequest"`
+	HotelId                 string            `xml:"hotelId"`
+	ArrivalDate             string            `xml:"arrivalDate"`
+	DepartureDate           string            `xml:"departureDate"`
+	SupplierType            string            `xml:"supplierType"`
+	RateKey                 string            `xml:"rateKey"`
+	RoomTypeCode            string            `xml:"roomTypeCode"`
+	RateCode                string            `xml:"rateCode"`
+	ChargeableRate          string            `xml:"chargeableRate"`
+	AffiliateConfirmationId string            `xml:"affiliateConfirmationId"`
+	Rooms                   []ReservationRoom `xml:"RoomGroup>Room"`
+	ReservationInfo         ReservationInfo   `xml:"ReservationInfo"`
+	AddressInfo             AddressInfo       `xml:"AddressInfo"`
+}
+
+type ReservationRoom struct {
+	NumberOfAdults    int    `xml:"numberOfAdults"`
+	NumberOfChildren  int    `xml:"numberOfChildren,omitempty"`
+	ChildAges         string `xml:"childAges,omitempty"`
+	FirstName         string `xml:"firstName"`
+	LastName          string `xml:"lastName"`
+	BedTypeId         string `xml:"bedTypeId,omitempty"`
+	SmokingPreference string `xml:"smokingPreference,omitempty"`
+}
+
+type ReservationInfo struct {
+	Email                     string `xml:"email"`
+	FirstName                 string `xml:"firstName"`
+	LastName                  string `xml:"lastName"`
+	HomePhone                 string `xml:"homePhone,omitempty"`
+	CreditCardType            string `xml:"creditCardType"`
+	CreditCardNumber          string `xml:"creditCardNumber"`
+	CreditCardIdentifier      string `xml:"creditCardIdentifier"`
+	CreditCardExpirationMonth string `xml:"creditCardExpirationMonth"`
+	CreditCardExpirationYear  string `xml:"creditCardExpirationYear"`
+}
+
+type AddressInfo struct {
+	Address1          string `xml:"address1"`
+	City              string `xml:"city"`
+	StateProvinceCode string `xml:"stateProvinceCode,omitempty"`
+	CountryCode       string `xml:"countryCode"`
+	PostalCode        string `xml:"postalCode"`
+}
+
+// HotelRoomReservationResponse is the res API response.
+type HotelRoomReservationResponse struct {
+	XMLName                   xml.Name      `xml:"HotelRoomReservationResponse" json:"-"`
+	ItineraryId               json.Number   `xml:"itineraryId" json:"itineraryId"`
+	ConfirmationNumbers       []json.Number `xml:"confirmationNumbers" json:"confirmationNumbers"`
+	ProcessedWithConfirmation bool          `xml:"processedWithConfirmation" json:"processedWithConfirmation"`
+	ReservationStatusCode     string        `xml:"reservationStatusCode" json:"reservationStatusCode"`
+	NonRefundable             bool          `xml:"nonRefundable" json:"nonRefundable"`
+	EanWsError                *EanWsError   `xml:"EanWsError" json:"EanWsError"`
+}
+
+// HotelItineraryRequest is the itin API request. It looks an itinerary up by
+// ItineraryId or by the AffiliateConfirmationId it was booked with.
+type HotelItineraryRequest struct {
+	XMLName                 xml.Name `xml:"HotelItineraryRequest"`
+	ItineraryId             string   `xml:"itineraryId,omitempty"`
+	AffiliateConfirmationId string   `xml:"affiliateConfirmationId,omitempty"`
+	Email                   string   `xml:"email"`
+}
+
+// HotelItineraryResponse is the itin API response.
+type HotelItineraryResponse struct {
+	XMLName     xml.Name        `xml:"HotelItineraryResponse" json:"-"`
+	Itineraries []ItineraryInfo `xml:"Itinerary" json:"Itinerary"`
+	EanWsError  *EanWsError     `xml:"EanWsError" json:"EanWsError"`
+}
+
+// ItineraryInfo is an itinerary of the itin API response, with a
+// HotelConfirmation per room.
+type ItineraryInfo struct {
+	ItineraryId   json.Number         `xml:"itineraryId" json:"itineraryId"`
+	Confirmations []HotelConfirmation `xml:"HotelConfirmation" json:"HotelConfirmation"`
+}
+
+type HotelConfirmation struct {
+	ConfirmationNumber json.Number `xml:"confirmationNumber" json:"confirmationNumber"`
+	Status             string      `xml:"status" json:"status"`
+	HotelId            json.Number `xml:"hotelId" json:"hotelId"`
+	ArrivalDate        string      `xml:"arrivalDate" json:"arrivalDate"`
+	DepartureDate      string      `xml:"departureDate" json:"departureDate"`
+}
+
+// rooms returns the rooms of the itinerary as EAN reports them, keeping the
+// cancellation numbers of the known rooms.
+func (ii ItineraryInfo) rooms(known []hspservice.ItineraryRoom) []hspservice.ItineraryRoom {
+	var rooms []hspservice.ItineraryRoom
+	for _, c := range ii.Confirmations {
+		rm := hspservice.ItineraryRoom{ConfirmationNumber: c.ConfirmationNumber.String(), Status: eanStatus(c.Status)}
+		for _, k := range known {
+			if k.ConfirmationNumber == rm.ConfirmationNumber {
+				rm.CancellationNumber = k.CancellationNumber
+			}
+		}
+		rooms = append(rooms, rm)
+	}
+	return rooms
+}
+
+// HotelRoomCancellationRequest is the cancel API request.
+type HotelRoomCancellationRequest struct {
+	XMLName            xml.Name `xml:"HotelRoomCancellationRequest"`
+	ItineraryId        string   `xml:"itineraryId"`
+	Email              string   `xml:"email"`
+	ConfirmationNumber string   `xml:"confirmationNumber"`
+	Reason             string   `xml:"reason,omitempty"`
+}
+
+// HotelRoomCancellationResponse is the cancel API response.
+type HotelRoomCancellationResponse struct {
+	XMLName            xml.Name    `xml:"HotelRoomCancellationResponse" json:"-"`
+	CancellationNumber string      `xml:"cancellationNumber" json:"cancellationNumber"`
+	EanWsError         *EanWsError `xml:"EanWsError" json:"EanWsError"`
+}
+
+func (r *HotelRoomReservationResponse) wsError() *EanWsError  { return r.EanWsError }
+func (r *HotelItineraryResponse) wsError() *EanWsError        { return r.EanWsError }
+func (r *HotelRoomCancellationResponse) wsError() *EanWsError { return r.EanWsError }
+
+// eanStatus maps EAN reservation status codes onto itinerary statuses.
+func eanStatus(code string) string {
+	switch code {
+	case "CF":
+		return hspservice.StatusConfirmed
+	case "CX":
+		return hspservice.StatusCancelled
+	case "ER", "DT":
+		return hspservice.StatusFailed
+	default: // PS, UC
+		return hspservice.StatusPending
+	}
+}
+
+func newReservationRequest(r hspservice.BookRequest) (rq HotelRoomReservationRequest, err error) {
+	if rq.ArrivalDate, err = eanDate(r.Arrival); err != nil {
+		return rq, err
+	}
+	if rq.DepartureDate, err = eanDate(r.Departure); err != nil {
+		return rq, err
+	}
+	rq.HotelId = r.HotelId
+	rq.SupplierType = MakeEanSpecs(EanCredentials{}).supplierType
+	rq.RateKey, rq.RoomTypeCode, rq.RateCode = r.RateKey, r.RoomTypeCode, r.RateCode
+	rq.ChargeableRate = r.ChargeableRate
+	rq.AffiliateConfirmationId = r.IdempotencyKey
+	for _, rm := range r.Rooms {
+		ages := make([]string, len(rm.ChildAges))
+		for i, a := range rm.ChildAges {
+			ages[i] = strconv.Itoa(a)
+		}
+		rq.Rooms = append(rq.Rooms, ReservationRoom{
+			NumberOfAdults:    rm.Adults,
+			NumberOfChildren:  len(rm.ChildAges),
+			ChildAges:         strings.Join(ages, ","),
+			FirstName:         rm.FirstName,
+			LastName:          rm.LastName,
+			BedTypeId:         rm.BedTypeId,
+			SmokingPreference: rm.SmokingPreference,
+		})
+	}
+	p := r.Payment
+	rq.ReservationInfo = ReservationInfo{
+		Email:                     r.Email,
+		FirstName:                 p.FirstName,
+		LastName:                  p.LastName,
+		HomePhone:                 r.Phone,
+		CreditCardType:            p.CardType,
+		CreditCardNumber:          p.CardNumber,
+		CreditCardIdentifier:      p.CardIdentifier,
+		CreditCardExpirationMonth: p.ExpirationMonth,
+		CreditCardExpirationYear:  p.ExpirationYear,
+	}
+	rq.AddressInfo = AddressInfo{
+		Address1:          p.Address1,
+		City:              p.City,
+		StateProvinceCode: p.StateProvinceCode,
+		CountryCode:       p.CountryCode,
+		PostalCode:        p.PostalCode,
+	}
+	return rq, nil
+}
+
+// call sends an EAN request document as the xml param, with the common and
+// customer params, and decodes the response named root into out. POST sends
+// the params as a form body (EAN requires it for res).
+func (s EanHspService) call(method, path string, doc interface{}, root string, out eanResponse) error {
+	if s.Client == nil {
+		return ErrNoEanClient
+	}
+	enc, err := xml.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	u, err := url.Parse(path)
+	if err != nil {
+		return err
+	}
+	e := MakeEanSpecs(s.Credentials)
+	if s.currencyCode != "" {
+		e.currencyCode = s.currencyCode
+	}
+	v := u.Query()
+	e.commonParams(v)
+	v.Add("customerSessionId", s.sessionID())
+	v.Add("customerIpAddress", e.customerIpAddress)
+	v.Add("customerUserAgent", e.customerUserAgent)
+	v.Add("xml", string(enc))
+
+	var req *http.Request
+	if method == "POST" {
+		u.RawQuery = ""
+		req, err = http.NewRequest("POST", u.String(), strings.NewReader(v.Encode()))
+		if err == nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	} else {
+		u.RawQuery = v.Encode()
+		req, err = http.NewRequest("GET", u.String(), nil)
+	}
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(s.context())
+	f := s.format()
+	req.Header.Set("Accept", "application/"+f)
+	countAttempt(s.context())
+	span, _ := trace.StartSpan(s.context(), "supplier.ean")
+	span.SetAttribute("http.url", hspservice.RedactURL(u))
+	defer span.Finish()
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		err = hspservice.RedactError(err)
+		span.SetError(err)
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("ean: unexpected status %s", resp.Status)
+	} else {
+		err = decodeEan(f, resp.Body, root, out)
+	}
+	span.SetError(err)
+	return err
+}
+
+func (s EanHspService) format() string {
+	if s.Format == "" {
+		return "xml"
+	}
+	return s.Format
+}
+
+// Book implements hspservice.Hsp. The first call for an idempotency key books;
+// later calls with the same key return the recorded itinerary. A call that
+// fails before the request is sent releases the key so the client can retry
+// it. When the outcome is unknown, as on a timeout, EAN may have booked, so
+// the itinerary stays pending; the next call for the key looks it up at EAN
+// by affiliateConfirmationId and books again only when EAN has none.
+func (s EanHspService) Book(r hspservice.BookRequest) (res hspservice.BookResponse) {
+	if r.IdempotencyKey == "" {
+		res.Error = hspservice.ErrNoIdempotencyKey
+		return
+	}
+	if s.Itineraries == nil {
+		res.Error = ErrNoItineraryStore
+		return
+	}
+	now := time.Now().UTC()
+	span, _ := trace.StartSpan(s.context(), "cache.idempotency")
+	it, fresh, err := s.Itineraries.Reserve(hspservice.Itinerary{
+		IdempotencyKey: r.IdempotencyKey,
+		Supplier:       eanSupplierName,
+		Status:         hspservice.StatusPending,
+		HotelId:        r.HotelId,
+		Arrival:        r.Arrival,
+		Departure:      r.Departure,
+		Total:          r.ChargeableRate,
+		Currency:       r.Currency,
+		Email:          r.Email,
+		Created:        now,
+		Updated:        now,
+	})
+	span.SetAttribute("hit", strconv.FormatBool(!fresh))
+	span.SetError(err)
+	span.Finish()
+	res.Itinerary, res.Error = it, err
+	if err != nil || (!fresh && it.Status != hspservice.StatusPending) {
+		return
+	}
+	if !fresh {
+		found, err := s.lookup(&it)
+		if found {
+			// Store errors leave the itinerary pending, to be looked up again.
+			s.Itineraries.Update(it)
+		}
+		if err != nil || found {
+			res.Itinerary, res.Error = it, err
+			return
+		}
+	}
+
+	rq, err := newReservationRequest(r)
+	if err != nil {
+		if fresh {
+			s.Itineraries.Release(r.IdempotencyKey)
+		}
+		res.Error = err
+		return
+	}
+	var rs HotelRoomReservationResponse
+	s.currencyCode = r.Currency
+	err = s.call("POST", s.endpoints().Reservation, rq, "HotelRoomReservationResponse", &rs)
+	_, definitive := err.(*EanWsError)
+
+	it.Updated = time.Now().UTC()
+	switch {
+	case err == nil:
+		it.ItineraryId = rs.ItineraryId.String()
+		it.Status = eanStatus(rs.ReservationStatusCode)
+		var rooms []hspservice.ItineraryRoom
+		for _, c := range rs.ConfirmationNumbers {
+			rooms = append(rooms, hspservice.ItineraryRoom{ConfirmationNumber: c.String(), Status: it.Status})
+		}
+		it.SetRooms(rooms)
+	case definitive && !fresh:
+		// EAN refuses an affiliateConfirmationId it has booked, which it
+		// may have for the attempt this call retries.
+		if found, lerr := s.lookup(&it); lerr == nil && found {
+			err = nil
+		} else {
+			it.Status = hspservice.StatusFailed
+		}
+	case definitive:
+		it.Status = hspservice.StatusFailed
+	}
+	// Store errors never fail the booking: EAN has the outcome, and an
+	// itinerary left pending is looked up by the next call for its key.
+	s.recordAttempt(r, it, err)
+	s.Itineraries.Update(it)
+	res.Itinerary, res.Error = it, err
+	return
+}
+
+// lookup asks EAN for the itinerary booked with the idempotency key of it as
+// affiliateConfirmationId. When there is one it reports found and sets the
+// itinerary id and rooms of it from EAN's.
+func (s EanHspService) lookup(it *hspservice.Itinerary) (found bool, err error) {
+	var rs HotelItineraryResponse
+	rq := HotelItineraryRequest{AffiliateConfirmationId: it.IdempotencyKey, Email: it.Email}
+	if err := s.call("GET", s.endpoints().Itinerary, rq, "HotelItineraryResponse", &rs); err != nil {
+		return false, err
+	}
+	if len(rs.Itineraries) == 0 {
+		return false, nil
+	}
+	it.ItineraryId = rs.Itineraries[0].ItineraryId.String()
+	it.SetRooms(rs.Itineraries[0].rooms(it.Rooms))
+	it.Updated = time.Now().UTC()
+	return true, nil
+}
+
+// recordAttempt records the outcome err of a booking attempt of it for r.
+// The attempt log is for audit only, so failing to record is not an error of
+// the booking.
+func (s EanHspService) recordAttempt(r hspservice.BookRequest, it hspservice.Itinerary, err error) {
+	a := booking.Attempt{
+		IdempotencyKey:      r.IdempotencyKey,
+		At:                  it.Updated,
+		Request:             booking.Redact(r),
+		Status:              it.Status,
+		ConfirmationNumbers: it.ConfirmationNumbers,
+	}
+	if err != nil {
+		a.Error = err.Error()
+	}
+	s.Itineraries.RecordAttempt(a)
+}
+
+// GetItinerary implements hspservice.Hsp. It asks EAN for the current state of
+// the itinerary and folds it into our record when we have one.
+func (s EanHspService) GetItinerary(r hspservice.ItineraryRequest) (res hspservice.ItineraryResponse) {
+	var rs HotelItineraryResponse
+	err := s.call("GET", s.endpoints().Itinerary, HotelItineraryRequest{ItineraryId: r.ItineraryId, Email: r.Email}, "HotelItineraryResponse", &rs)
+	if err != nil {
+		res.Error = err
+		return
+	}
+	if len(rs.Itineraries) == 0 {
+		res.Error = hspservice.ErrItineraryNotFound
+		return
+	}
+
+	it := hspservice.Itinerary{Supplier: eanSupplierName, ItineraryId: r.ItineraryId, Email: r.Email}
+	if s.Itineraries != nil {
+		if stored, err := s.Itineraries.ByItineraryId(r.ItineraryId); err == nil {
+			it = stored
+		}
+	}
+	info := rs.Itineraries[0]
+	it.SetRooms(info.rooms(it.RoomList()))
+	if len(info.Confirmations) > 0 {
+		it.HotelId = info.Confirmations[0].HotelId.String()
+	}
+	it.Updated = time.Now().UTC()
+	if s.Itineraries != nil && it.IdempotencyKey != "" {
+		res.Error = s.Itineraries.Update(it)
+	}
+	res.Itinerary = it
+	return
+}
+
+// Cancel implements hspservice.Hsp. ConfirmationNumber may be left empty for
+// itineraries we booked with a single room; for those with several it is
+// required, as EAN cancels one room per call.
+func (s EanHspService) Cancel(r hspservice.CancelRequest) (res hspservice.CancelResponse) {
+	it := hspservice.Itinerary{Supplier: eanSupplierName, ItineraryId: r.ItineraryId, Email: r.Email}
+	if s.Itineraries != nil {
+		if stored, err := s.Itineraries.ByItineraryId(r.ItineraryId); err == nil {
+			it = stored
+		}
+	}
+	rooms := it.RoomList()
+	if r.ConfirmationNumber == "" {
+		switch {
+		case len(rooms) == 1:
+			r.ConfirmationNumber = rooms[0].ConfirmationNumber
+		case len(rooms) > 1:
+			res.Itinerary, res.Error = it, hspservice.ErrNoConfirmationNumber
+			return
+		}
+	}
+
+	var rs HotelRoomCancellationResponse
+	rq := HotelRoomCancellationRequest{
+		ItineraryId:        r.ItineraryId,
+		Email:              r.Email,
+		ConfirmationNumber: r.ConfirmationNumber,
+		Reason:             r.Reason,
+	}
+	if err := s.call("GET", s.endpoints().Cancel, rq, "HotelRoomCancellationResponse", &rs); err != nil {
+		res.Itinerary, res.Error = it, err
+		return
+	}
+	it.CancellationNumber = rs.CancellationNumber
+	switch {
+	case len(rooms) > 0:
+		for i := range rooms {
+			if rooms[i].ConfirmationNumber == r.ConfirmationNumber {
+				rooms[i].Status, rooms[i].CancellationNumber = hspservice.StatusCancelled, rs.CancellationNumber
+			}
+		}
+		it.SetRooms(rooms)
+	case r.ConfirmationNumber == "":
+		it.Status = hspservice.StatusCancelled
+	default:
+		// Of an itinerary we do not know the rooms of, only the room
+		// cancelled is known; its status is not.
+		it.Rooms = []hspservice.ItineraryRoom{{ConfirmationNumber: r.ConfirmationNumber, Status: hspservice.StatusCancelled, CancellationNumber: rs.CancellationNumber}}
+		it.ConfirmationNumbers = []string{r.ConfirmationNumber}
+	}
+	it.Updated = time.Now().UTC()
+	if s.Itineraries != nil && it.IdempotencyKey != "" {
+		res.Error = s.Itineraries.Update(it)
+	}
+	res.Itinerary = it
+	return
+}
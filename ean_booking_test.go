@@ -0,0 +1,303 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jbowles/hotel_supply_platform/booking"
+	"github.com/jbowles/hotel_supply_platform/eanmock"
+	"github.com/jbowles/hotel_supply_platform/hspservice"
+)
+
+// eanBooking is a mock EAN server for booking tests. It counts the
+// reservation requests it is sent and, while stall is set, books them
+// without ever answering, as when a call times out after EAN booked.
+type eanBooking struct {
+	next http.Handler
+
+	mtx          sync.Mutex
+	reservations int
+	stall        bool
+}
+
+func (b *eanBooking) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasSuffix(r.URL.Path, "/"+eanmock.Reservation) {
+		b.mtx.Lock()
+		b.reservations++
+		stall := b.stall
+		b.mtx.Unlock()
+		if stall {
+			b.next.ServeHTTP(httptest.NewRecorder(), r)
+			<-r.Context().Done()
+			return
+		}
+	}
+	b.next.ServeHTTP(w, r)
+}
+
+func (b *eanBooking) count() int {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return b.reservations
+}
+
+// newEanBooking returns an EanHspService booking at a mock EAN server, with
+// an empty itinerary store, and a func that shuts the server down.
+func newEanBooking(t *testing.T) (EanHspService, *eanBooking, func()) {
+	b := &eanBooking{next: eanmock.New(eanmock.DefaultFixtures(), eanmock.Config{})}
+	srv := httptest.NewServer(b)
+	cfg := eanEnvironments["mock"]
+	cfg.BaseURL = srv.URL
+	endpoints, err := cfg.Endpoints()
+	if err != nil {
+		srv.Close()
+		t.Fatal(err)
+	}
+	svc := EanHspService{Client: &http.Client{}, Endpoints: endpoints, Itineraries: booking.NewMemory()}
+	return svc, b, srv.Close
+}
+
+// bookRequest books the fixture room roomTypeCode/rateCode of the Mock Harbour
+// Hotel at rate, for the given number of rooms.
+func bookRequest(key string, roomTypeCode, rateCode, rate string, rooms int) hspservice.BookRequest {
+	r := hspservice.BookRequest{
+		IdempotencyKey: key,
+		Supplier:       eanSupplierName,
+		HotelId:        "225697",
+		Arrival:        "2026-11-02",
+		Departure:      "2026-11-05",
+		RateKey:        "mock-225697-" + roomTypeCode + "-" + rateCode,
+		RoomTypeCode:   roomTypeCode,
+		RateCode:       rateCode,
+		ChargeableRate: rate,
+		Currency:       "USD",
+		Email:          "guest@example.com",
+		Payment:        hspservice.BookPayment{CardType: "CA", CardNumber: "5401999999999999", CardIdentifier: "123", ExpirationMonth: "11", ExpirationYear: "2029"},
+	}
+	for i := 0; i < rooms; i++ {
+		r.Rooms = append(r.Rooms, hspservice.BookRoom{Adults: 2, FirstName: "Ada", LastName: "Guest"})
+	}
+	return r
+}
+
+func TestBookConfirmed(t *testing.T) {
+	svc, mock, done := newEanBooking(t)
+	defer done()
+
+	res := svc.Book(bookRequest("key-ok", "200", "2001", "289.50", 1))
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	it := res.Itinerary
+	if it.Status != hspservice.StatusConfirmed || it.ItineraryId == "" || len(it.ConfirmationNumbers) != 1 {
+		t.Fatalf("itinerary %+v, want confirmed with one confirmation", it)
+	}
+
+	again := svc.Book(bookRequest("key-ok", "200", "2001", "289.50", 1))
+	if again.Error != nil || again.Itinerary.ItineraryId != it.ItineraryId {
+		t.Errorf("retry got %+v, %v; want itinerary %s", again.Itinerary, again.Error, it.ItineraryId)
+	}
+	if n := mock.count(); n != 1 {
+		t.Errorf("%d reservations sent, want 1", n)
+	}
+}
+
+func TestBookRefused(t *testing.T) {
+	for _, tc := range []struct {
+		name, roomTypeCode, rateCode, rate, category string
+	}{
+		{"sold out", "202", "2021", "640.00", eanmock.SoldOut},
+		{"price mismatch", "200", "2001", "199.00", eanmock.PriceMismatch},
+	} {
+		svc, mock, done := newEanBooking(t)
+		key := "key-" + tc.rateCode
+		res := svc.Book(bookRequest(key, tc.roomTypeCode, tc.rateCode, tc.rate, 1))
+		if e, ok := res.Error.(*EanWsError); !ok || e.Category != tc.category {
+			t.Errorf("%s: got error %v, want %s", tc.name, res.Error, tc.category)
+		}
+		if res.Itinerary.Status != hspservice.StatusFailed {
+			t.Errorf("%s: status %s, want failed", tc.name, res.Itinerary.Status)
+		}
+		// The refusal is definitive: a retry returns it without booking.
+		again := svc.Book(bookRequest(key, tc.roomTypeCode, tc.rateCode, tc.rate, 1))
+		if again.Itinerary.Status != hspservice.StatusFailed {
+			t.Errorf("%s: retry status %s, want failed", tc.name, again.Itinerary.Status)
+		}
+		if n := mock.count(); n != 1 {
+			t.Errorf("%s: %d reservations sent, want 1", tc.name, n)
+		}
+		done()
+	}
+}
+
+func TestBookTimeoutThenRetry(t *testing.T) {
+	svc, mock, done := newEanBooking(t)
+	defer done()
+
+	mock.stall = true
+	timeout := svc
+	timeout.Client = &http.Client{Timeout: 100 * time.Millisecond}
+	res := timeout.Book(bookRequest("key-timeout", "200", "2001", "289.50", 1))
+	if res.Error == nil {
+		t.Fatal("no error for a timed out reservation")
+	}
+	if res.Itinerary.Status != hspservice.StatusPending {
+		t.Errorf("status %s after a timeout, want pending", res.Itinerary.Status)
+	}
+	if stored, err := svc.Itineraries.ByKey("key-timeout"); err != nil || stored.Status != hspservice.StatusPending {
+		t.Errorf("stored %+v, %v; want pending", stored, err)
+	}
+
+	mock.mtx.Lock()
+	mock.stall = false
+	mock.mtx.Unlock()
+	res = svc.Book(bookRequest("key-timeout", "200", "2001", "289.50", 1))
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	if it := res.Itinerary; it.Status != hspservice.StatusConfirmed || it.ItineraryId == "" || len(it.ConfirmationNumbers) != 1 {
+		t.Errorf("retry itinerary %+v, want the booking EAN made", it)
+	}
+	if stored, _ := svc.Itineraries.ByKey("key-timeout"); stored.Status != hspservice.StatusConfirmed {
+		t.Errorf("stored status %s, want confirmed", stored.Status)
+	}
+	if n := mock.count(); n != 1 {
+		t.Errorf("%d reservations sent, want 1", n)
+	}
+}
+
+// failingStore fails to record attempts and updates.
+type failingStore struct {
+	booking.Repository
+}
+
+var errStore = errors.New("store unavailable")
+
+func (failingStore) RecordAttempt(booking.Attempt) error  { return errStore }
+func (failingStore) Update(it hspservice.Itinerary) error { return errStore }
+func (failingStore) Release(key string) error             { panic("released " + key) }
+
+func TestBookStoreFailsAfterSuccess(t *testing.T) {
+	svc, mock, done := newEanBooking(t)
+	defer done()
+	mem := svc.Itineraries
+	svc.Itineraries = failingStore{mem}
+
+	res := svc.Book(bookRequest("key-store", "200", "2001", "289.50", 1))
+	if res.Error != nil || res.Itinerary.Status != hspservice.StatusConfirmed {
+		t.Fatalf("got %+v, %v; want the confirmed booking", res.Itinerary, res.Error)
+	}
+
+	// The record is left pending; a retry finds the booking at EAN.
+	svc.Itineraries = mem
+	again := svc.Book(bookRequest("key-store", "200", "2001", "289.50", 1))
+	if again.Error != nil || again.Itinerary.ItineraryId != res.Itinerary.ItineraryId {
+		t.Errorf("retry got %+v, %v; want itinerary %s", again.Itinerary, again.Error, res.Itinerary.ItineraryId)
+	}
+	if n := mock.count(); n != 1 {
+		t.Errorf("%d reservations sent, want 1", n)
+	}
+}
+
+func TestCancelOneRoom(t *testing.T) {
+	svc, _, done := newEanBooking(t)
+	defer done()
+
+	booked := svc.Book(bookRequest("key-rooms", "200", "2001", "289.50", 2))
+	if booked.Error != nil {
+		t.Fatal(booked.Error)
+	}
+	it := booked.Itinerary
+	if len(it.Rooms) != 2 {
+		t.Fatalf("rooms %+v, want 2", it.Rooms)
+	}
+
+	res := svc.Cancel(hspservice.CancelRequest{ItineraryId: it.ItineraryId, ConfirmationNumber: it.Rooms[0].ConfirmationNumber, Email: it.Email})
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	if res.Itinerary.Status != hspservice.StatusConfirmed {
+		t.Errorf("status %s after cancelling one room of two, want confirmed", res.Itinerary.Status)
+	}
+	if r := res.Itinerary.Rooms; r[0].Status != hspservice.StatusCancelled || r[0].CancellationNumber == "" || r[1].Status != hspservice.StatusConfirmed {
+		t.Errorf("rooms %+v, want the first cancelled", r)
+	}
+
+	got := svc.GetItinerary(hspservice.ItineraryRequest{ItineraryId: it.ItineraryId, Email: it.Email})
+	if got.Error != nil || got.Itinerary.Status != hspservice.StatusConfirmed || got.Itinerary.Rooms[0].CancellationNumber == "" {
+		t.Errorf("itinerary %+v, %v; want confirmed, the first room cancelled", got.Itinerary, got.Error)
+	}
+
+	res = svc.Cancel(hspservice.CancelRequest{ItineraryId: it.ItineraryId, ConfirmationNumber: it.Rooms[1].ConfirmationNumber, Email: it.Email})
+	if res.Error != nil || res.Itinerary.Status != hspservice.StatusCancelled {
+		t.Errorf("got %+v, %v; want cancelled once every room is", res.Itinerary, res.Error)
+	}
+}
+
+func TestCancelNeedsRoom(t *testing.T) {
+	svc, _, done := newEanBooking(t)
+	defer done()
+
+	booked := svc.Book(bookRequest("key-rooms", "200", "2001", "289.50", 2))
+	if booked.Error != nil {
+		t.Fatal(booked.Error)
+	}
+	it := booked.Itinerary
+	res := svc.Cancel(hspservice.CancelRequest{ItineraryId: it.ItineraryId, Email: it.Email})
+	if res.Error != hspservice.ErrNoConfirmationNumber {
+		t.Fatalf("cancel without a room: %v, want %v", res.Error, hspservice.ErrNoConfirmationNumber)
+	}
+	got := svc.GetItinerary(hspservice.ItineraryRequest{ItineraryId: it.ItineraryId, Email: it.Email})
+	for _, r := range got.Itinerary.Rooms {
+		if r.Status != hspservice.StatusConfirmed {
+			t.Errorf("room %+v, want every room still confirmed", r)
+		}
+	}
+
+	// A single room needs no confirmation number.
+	booked = svc.Book(bookRequest("key-room", "200", "2001", "289.50", 1))
+	if booked.Error != nil {
+		t.Fatal(booked.Error)
+	}
+	it = booked.Itinerary
+	res = svc.Cancel(hspservice.CancelRequest{ItineraryId: it.ItineraryId, Email: it.Email})
+	if res.Error != nil || res.Itinerary.Status != hspservice.StatusCancelled {
+		t.Errorf("got %+v, %v; want the room cancelled", res.Itinerary, res.Error)
+	}
+}
+
+func TestCallStatus(t *testing.T) {
+	// The body is a well formed answer; the status says not to trust it.
+	rec := &recorder{status: http.StatusBadGateway, body: "<HotelItineraryResponse><Itinerary><itineraryId>1001</itineraryId></Itinerary></HotelItineraryResponse>"}
+	svc := EanHspService{Client: &http.Client{Transport: rec}}
+	res := svc.GetItinerary(hspservice.ItineraryRequest{ItineraryId: "1001", Email: "guest@example.com"})
+	if res.Error == nil || !strings.Contains(res.Error.Error(), "unexpected status 502") {
+		t.Errorf("error %v, want the status", res.Error)
+	}
+
+	// A booking answered with an error status has an unknown outcome.
+	rec = &recorder{status: http.StatusServiceUnavailable, body: "<HotelRoomReservationResponse><itineraryId>1001</itineraryId><reservationStatusCode>CF</reservationStatusCode></HotelRoomReservationResponse>"}
+	svc = EanHspService{Client: &http.Client{Transport: rec}, Itineraries: booking.NewMemory()}
+	booked := svc.Book(bookRequest("key-status", "200", "2001", "289.50", 1))
+	if booked.Error == nil || booked.Itinerary.Status != hspservice.StatusPending || booked.Itinerary.ItineraryId != "" {
+		t.Errorf("got %+v, %v; want the itinerary left pending", booked.Itinerary, booked.Error)
+	}
+	rec.only(t)
+}
+
+func TestGetItineraryWithoutConfirmations(t *testing.T) {
+	rec := &recorder{body: "<HotelItineraryResponse><Itinerary><itineraryId>1001</itineraryId></Itinerary></HotelItineraryResponse>"}
+	svc := EanHspService{Client: &http.Client{Transport: rec}}
+	res := svc.GetItinerary(hspservice.ItineraryRequest{ItineraryId: "1001", Email: "guest@example.com"})
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	if res.Itinerary.Status == hspservice.StatusCancelled {
+		t.Error("an itinerary without confirmations is reported cancelled")
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/jbowles/hotel_supply_platform/currency"
+	"github.com/jbowles/hotel_supply_platform/format"
+	"github.com/jbowles/hotel_supply_platform/hspservice"
+)
+
+// CancelPolicyInfo is one penalty window of a rate. The penalty starts
+// StartWindowHours before CancelTime on the arrival day, hotel local time, and
+// is NightCount nights, Amount in CurrencyCode or Percent of the total.
+type CancelPolicyInfo struct {
+	CancelTime          string      `xml:"cancelTime" json:"cancelTime"`
+	StartWindowHours    int         `xml:"startWindowHours" json:"startWindowHours"`
+	NightCount          int         `xml:"nightCount" json:"nightCount"`
+	Amount              json.Number `xml:"amount" json:"amount"`
+	Percent             json.Number `xml:"percent" json:"percent"`
+	CurrencyCode        string      `xml:"currencyCode" json:"currencyCode"`
+	TimeZoneDescription string      `xml:"timeZoneDescription" json:"timeZoneDescription"`
+}
+
+// eanTimeZone matches the offset of descriptions like
+// "(GMT-06:00) Central Time (US & Canada)" and "(GMT) Greenwich Mean Time".
+var eanTimeZone = regexp.MustCompile(`^\(GMT(?:([+-])(\d{2}):(\d{2}))?\)`)
+
+// eanZone returns the hotel's time zone from its description. EAN only gives
+// the standard offset, so the zone is fixed and ignores daylight saving.
+func eanZone(desc string) (*time.Location, error) {
+	m := eanTimeZone.FindStringSubmatch(desc)
+	if m == nil {
+		return nil, fmt.Errorf("ean: unknown time zone %q", desc)
+	}
+	if m[1] == "" {
+		return time.FixedZone("GMT", 0), nil
+	}
+	h, _ := strconv.Atoi(m[2])
+	min, _ := strconv.Atoi(m[3])
+	offset := (h*60 + min) * 60
+	if m[1] == "-" {
+		offset = -offset
+	}
+	return time.FixedZone("GMT"+m[1]+m[2]+":"+m[3], offset), nil
+}
+
+// tier converts the window into a penalty tier for a stay arriving on arrival
+// (MM/DD/YYYY).
+func (c CancelPolicyInfo) tier(arrival string) (t hspservice.PenaltyTier, err error) {
+	loc, err := eanZone(c.TimeZoneDescription)
+	if err != nil {
+		return t, err
+	}
+	day, err := time.ParseInLocation(format.EanDateLayout, arrival, loc)
+	if err != nil {
+		return t, err
+	}
+	at, err := time.Parse("15:04:05", c.CancelTime)
+	if err != nil {
+		return t, fmt.Errorf("ean: invalid cancel time %q", c.CancelTime)
+	}
+	t.Start = day.Add(time.Duration(at.Hour())*time.Hour +
+		time.Duration(at.Minute())*time.Minute +
+		time.Duration(at.Second())*time.Second -
+		time.Duration(c.StartWindowHours)*time.Hour)
+
+	switch {
+	case c.NightCount > 0:
+		t.Nights = c.NightCount
+	case c.Amount != "":
+		m, err := currency.Parse(c.Amount.String(), c.CurrencyCode)
+		if err != nil {
+			return t, err
+		}
+		t.Amount = &m
+	case c.Percent != "":
+		t.Percent = c.Percent.String()
+	}
+	return t, nil
+}
+
+// Policy normalizes the rate's cancellation policy for a stay arriving on
+// arrival (MM/DD/YYYY). When a window cannot be parsed it returns the error
+// with a policy that has no tiers, whose penalty is unknown, and the text.
+func (ri RateInfo) Policy(arrival string) (hspservice.CancellationPolicy, error) {
+	var tiers []hspservice.PenaltyTier
+	for _, c := range ri.CancelPolicyInfoList.List {
+		t, err := c.tier(arrival)
+		if err != nil {
+			return hspservice.NewCancellationPolicy(!ri.NonRefundable, nil, ri.CancellationPolicy), err
+		}
+		tiers = append(tiers, t)
+	}
+	return hspservice.NewCancellationPolicy(!ri.NonRefundable, tiers, ri.CancellationPolicy), nil
+}
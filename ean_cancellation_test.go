@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/jbowles/hotel_supply_platform/currency"
+	"github.com/jbowles/hotel_supply_platform/eanmock"
+	"github.com/jbowles/hotel_supply_platform/hspservice"
+)
+
+func TestRateBreakdownCancellationPolicy(t *testing.T) {
+	for _, format := range []string{"xml", "json"} {
+		svc, done := newMockEan(t, format, eanmock.Config{})
+		rates := allRates(t, svc, hspservice.RateBreakdownRequest{Arrival: "2026-11-02", Departure: "2026-11-05", Currency: "USD"})
+		done()
+
+		policies := make(map[string]*hspservice.CancellationPolicy)
+		for _, r := range rates {
+			policies[r.RoomTypeCode] = r.CancellationPolicy
+		}
+
+		pacific := time.FixedZone("GMT-08:00", -8*60*60)
+		p := policies["200"]
+		if p == nil || len(p.Tiers) != 2 {
+			t.Fatalf("%s: room 200 policy %+v, want two tiers", format, p)
+		}
+		if want := time.Date(2026, 11, 1, 18, 0, 0, 0, pacific); !p.FreeCancelUntil.Equal(want) {
+			t.Errorf("%s: free cancel until %v, want %v", format, p.FreeCancelUntil, want)
+		}
+		if p.Tiers[0].Nights != 1 || p.Tiers[1].Percent != "100" {
+			t.Errorf("%s: room 200 tiers %+v", format, p.Tiers)
+		}
+
+		if p := policies["300"]; p == nil || len(p.Tiers) != 1 || p.Tiers[0].Amount == nil || p.Tiers[0].Amount.String() != "50.00" {
+			t.Errorf("%s: room 300 policy %+v, want a 50.00 tier", format, p)
+		}
+
+		if p := policies["201"]; p == nil || p.Refundable {
+			t.Errorf("%s: room 201 policy %+v, want non refundable", format, p)
+		}
+	}
+}
+
+// policyHotelList is a hotel list page with a tiered, a non refundable and an
+// unparsable policy.
+const policyHotelList = `<HotelListResponse>
+	<HotelList size="1">
+		<HotelSummary><hotelId>225697</hotelId><name>Harbour Hotel</name><countryCode>US</countryCode><lowRate>129.0</lowRate><rateCurrencyCode>USD</rateCurrencyCode>
+			<RoomRateDetailsList>
+				<RoomRateDetails><roomTypeCode>200</roomTypeCode><rateCode>2001</rateCode>
+					<RateInfos><RateInfo>
+						<cancellationPolicy>One night is charged from 18:00 two days before arrival, the full stay from arrival.</cancellationPolicy>
+						<CancelPolicyInfoList>
+							<CancelPolicyInfo><cancelTime>18:00:00</cancelTime><startWindowHours>24</startWindowHours><nightCount>1</nightCount><currencyCode>USD</currencyCode><timeZoneDescription>(GMT-08:00) Pacific Time (US &amp; Canada); Tijuana</timeZoneDescription></CancelPolicyInfo>
+							<CancelPolicyInfo><cancelTime>18:00:00</cancelTime><startWindowHours>0</startWindowHours><percent>100</percent><currencyCode>USD</currencyCode><timeZoneDescription>(GMT-08:00) Pacific Time (US &amp; Canada); Tijuana</timeZoneDescription></CancelPolicyInfo>
+						</CancelPolicyInfoList>
+						<ChargeableRateInfo total="387.00" currencyCode="USD"/>
+					</RateInfo></RateInfos>
+				</RoomRateDetails>
+				<RoomRateDetails><roomTypeCode>201</roomTypeCode><rateCode>2011</rateCode>
+					<RateInfos><RateInfo>
+						<nonRefundable>true</nonRefundable>
+						<cancellationPolicy>This rate is non refundable.</cancellationPolicy>
+						<ChargeableRateInfo total="349.00" currencyCode="USD"/>
+					</RateInfo></RateInfos>
+				</RoomRateDetails>
+				<RoomRateDetails><roomTypeCode>202</roomTypeCode><rateCode>2021</rateCode>
+					<RateInfos><RateInfo>
+						<cancellationPolicy>Cancellations are charged 50% of the stay.</cancellationPolicy>
+						<CancelPolicyInfoList>
+							<CancelPolicyInfo><cancelTime>18:00:00</cancelTime><startWindowHours>24</startWindowHours><percent>50</percent><currencyCode>USD</currencyCode><timeZoneDescription>Pacific Time</timeZoneDescription></CancelPolicyInfo>
+						</CancelPolicyInfoList>
+						<ChargeableRateInfo total="412.00" currencyCode="USD"/>
+					</RateInfo></RateInfos>
+				</RoomRateDetails>
+			</RoomRateDetailsList>
+		</HotelSummary>
+	</HotelList>
+</HotelListResponse>`
+
+func TestHotelListRatesCancellationPolicy(t *testing.T) {
+	list, err := decodeHotelList("xml", strings.NewReader(policyHotelList))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rates, err := list.Rates("11/02/2026")
+	if err == nil {
+		t.Error("no error for a policy with an unknown time zone")
+	}
+	if len(rates) != 3 {
+		t.Fatalf("rates %+v, want all three kept", rates)
+	}
+	policies := make(map[string]*hspservice.CancellationPolicy)
+	for _, r := range rates {
+		policies[r.RoomTypeCode] = r.CancellationPolicy
+	}
+
+	pacific := time.FixedZone("GMT-08:00", -8*60*60)
+	p := policies["200"]
+	if p == nil || len(p.Tiers) != 2 {
+		t.Fatalf("room 200 policy %+v, want two tiers", p)
+	}
+	if want := time.Date(2026, 11, 1, 18, 0, 0, 0, pacific); !p.FreeCancelUntil.Equal(want) {
+		t.Errorf("free cancel until %v, want %v", p.FreeCancelUntil, want)
+	}
+	if p.Tiers[0].Nights != 1 || p.Tiers[1].Percent != "100" {
+		t.Errorf("room 200 tiers %+v", p.Tiers)
+	}
+
+	if p := policies["201"]; p == nil || p.Refundable {
+		t.Errorf("room 201 policy %+v, want non refundable", p)
+	}
+
+	p = policies["202"]
+	if p == nil || !p.Refundable || len(p.Tiers) != 0 || p.Text == "" {
+		t.Fatalf("room 202 policy %+v, want refundable with text and no tiers", p)
+	}
+	total, err := currency.Parse("412.00", "USD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Penalty(time.Now(), total, 3); err != hspservice.ErrUnknownPenalty {
+		t.Errorf("penalty error %v, want ErrUnknownPenalty", err)
+	}
+}
+
+func TestRateBreakdownBadCancellationPolicy(t *testing.T) {
+	fixtures := eanmock.DefaultFixtures()
+	fixtures.Hotels[0].Rooms[0].CancelPolicies[0].TimeZone = "Pacific Time"
+	svc, done := newMockEanFixtures(t, "xml", fixtures, eanmock.Config{})
+	defer done()
+	var logged []interface{}
+	svc.Logger = log.LoggerFunc(func(keyvals ...interface{}) error {
+		logged = append(logged, keyvals...)
+		return nil
+	})
+	res := svc.RateBreakdown(hspservice.RateBreakdownRequest{Arrival: "2026-11-02", Departure: "2026-11-05"})
+	if res.Error != nil {
+		t.Fatalf("error %v, want the rates with no error", res.Error)
+	}
+	if !strings.Contains(fmt.Sprint(logged...), "Pacific Time") {
+		t.Errorf("logged %v, want the policy error", logged)
+	}
+	var found bool
+	for _, r := range res.Rates {
+		if r.RoomTypeCode != "200" {
+			continue
+		}
+		found = true
+		p := r.CancellationPolicy
+		if p == nil || !p.Refundable || len(p.Tiers) != 0 || p.Text == "" {
+			t.Fatalf("room 200 policy %+v, want refundable with text and no tiers", p)
+		}
+		total, err := currency.Parse(r.Total, r.Currency)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := p.Penalty(time.Now(), total, 3); err != hspservice.ErrUnknownPenalty {
+			t.Errorf("penalty error %v, want ErrUnknownPenalty", err)
+		}
+	}
+	if !found {
+		t.Errorf("room 200 left out of %+v", res.Rates)
+	}
+}
+
+func TestRateInfoPolicy(t *testing.T) {
+	var ri RateInfo
+	ri.CancellationPolicy = "Cancellations from 12:00 the day before arrival are charged 50% of the stay."
+	ri.CancelPolicyInfoList.List = []CancelPolicyInfo{
+		{CancelTime: "12:00:00", StartWindowHours: 24, Percent: "50", CurrencyCode: "EUR", TimeZoneDescription: "(GMT+01:00) Amsterdam, Berlin, Bern, Rome, Stockholm, Vienna"},
+	}
+	p, err := ri.Policy("11/02/2026")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := time.Date(2026, 11, 1, 12, 0, 0, 0, time.FixedZone("GMT+01:00", 60*60))
+	if !p.Refundable || len(p.Tiers) != 1 || p.Tiers[0].Percent != "50" || !p.Tiers[0].Start.Equal(want) {
+		t.Errorf("policy %+v, want 50%% from %v", p, want)
+	}
+
+	ri.CancelPolicyInfoList.List[0].CancelTime = "noon"
+	p, err = ri.Policy("11/02/2026")
+	if err == nil {
+		t.Error("no error for an invalid cancel time")
+	}
+	if !p.Refundable || len(p.Tiers) != 0 || p.Text != ri.CancellationPolicy {
+		t.Errorf("policy %+v, want refundable with its text and no tiers", p)
+	}
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// EanConfig is where the EAN API is and how to connect to it. Each
+// environment (production, sandbox, mock) has one; a JSON file can override
+// any of its fields, the fields it leaves out keeping the environment's value.
+// BookingURL is the base URL of reservations, which EAN serves from its own
+// host; empty uses BaseURL.
+type EanConfig struct {
+	BaseURL     string         `json:"base_url"`
+	BookingURL  string         `json:"booking_url,omitempty"`
+	Paths       EanPaths       `json:"paths"`
+	Credentials EanCredentials `json:"credentials"`
+	TLS         EanTLS         `json:"tls"`
+	Proxy       string         `json:"proxy,omitempty"`
+	HTTP        EanHTTP        `json:"http"`
+}
+
+// EanCredentials identify hsp to EAN: the cid and apiKey every call carries.
+// The EAN_CID and EAN_API_KEY environment variables override the file's, so
+// the key need not be written to it.
+type EanCredentials struct {
+	CID    string `json:"cid,omitempty"`
+	APIKey string `json:"api_key,omitempty"`
+}
+
+// ErrNoEanCredentials is returned by loadEanConfig for an environment other
+// than mock without a cid and an API key; EAN rejects every call without them.
+var ErrNoEanCredentials = errors.New("ean: no credentials; set EAN_CID and EAN_API_KEY, or credentials in the configuration file")
+
+// EanPaths are the paths of the EAN operations, below the base URLs.
+type EanPaths struct {
+	HotelList        string `json:"hotel_list"`
+	RoomAvailability string `json:"room_availability"`
+	Reservation      string `json:"reservation"`
+	Itinerary        string `json:"itinerary"`
+	Cancel           string `json:"cancel"`
+}
+
+// EanTLS configures TLS to EAN. CAFile holds PEM certificates trusted besides
+// the system ones, for sandboxes and proxies with a private CA; CertFile and
+// KeyFile are a client certificate, for endpoints that require one.
+type EanTLS struct {
+	CAFile             string `json:"ca_file,omitempty"`
+	CertFile           string `json:"cert_file,omitempty"`
+	KeyFile            string `json:"key_file,omitempty"`
+	ServerName         string `json:"server_name,omitempty"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty"` // never in production
+}
+
+// EanHTTP tunes the HTTP client. Timeout bounds a whole call, response body
+// included; zero durations and sizes mean no limit.
+type EanHTTP struct {
+	MaxConnsPerHost       int      `json:"max_conns_per_host"`
+	MaxIdleConns          int      `json:"max_idle_conns"`
+	MaxIdleConnsPerHost   int      `json:"max_idle_conns_per_host"`
+	IdleConnTimeout       duration `json:"idle_conn_timeout"`
+	DialTimeout           duration `json:"dial_timeout"`
+	KeepAlive             duration `json:"keep_alive"`
+	TLSHandshakeTimeout   duration `json:"tls_handshake_timeout"`
+	ResponseHeaderTimeout duration `json:"response_header_timeout"`
+	Timeout               duration `json:"timeout"`
+}
+
+// duration is a time.Duration written in JSON as a string such as "30s".
+type duration time.Duration
+
+// MarshalJSON implements json.Marshaler.
+func (d duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = duration(v)
+	return nil
+}
+
+var (
+	eanPaths = EanPaths{
+		HotelList:        "/ean-services/rs/hotel/v3/list",
+		RoomAvailability: "/ean-services/rs/hotel/v3/avail",
+		Reservation:      "/ean-services/rs/hotel/v3/res",
+		Itinerary:        "/ean-services/rs/hotel/v3/itin",
+		Cancel:           "/ean-services/rs/hotel/v3/cancel",
+	}
+	eanHTTP = EanHTTP{
+		MaxConnsPerHost:       64,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   16,
+		IdleConnTimeout:       duration(90 * time.Second),
+		DialTimeout:           duration(5 * time.Second),
+		KeepAlive:             duration(30 * time.Second),
+		TLSHandshakeTimeout:   duration(5 * time.Second),
+		ResponseHeaderTimeout: duration(20 * time.Second),
+		Timeout:               duration(30 * time.Second),
+	}
+)
+
+// eanEnvironments are the EAN environments -ean.env selects from. mock is a
+// mock-ean server on its default address.
+var eanEnvironments = map[string]EanConfig{
+	"production": {BaseURL: "https://api.ean.com", BookingURL: "https://book.api.ean.com", Paths: eanPaths, HTTP: eanHTTP},
+	"sandbox":    {BaseURL: "https://dev.api.ean.com", Paths: eanPaths, HTTP: eanHTTP},
+	"mock":       {BaseURL: "http://localhost:9000", Paths: eanPaths, HTTP: eanHTTP},
+}
+
+// loadEanConfig returns the configuration of the environment env, overridden
+// by the JSON file path when it is not empty, and its credentials by the
+// environment variables.
+func loadEanConfig(env, path string) (EanConfig, error) {
+	c, ok := eanEnvironments[env]
+	if !ok {
+		known := make([]string, 0, len(eanEnvironments))
+		for k := range eanEnvironments {
+			known = append(known, k)
+		}
+		sort.Strings(known)
+		return c, fmt.Errorf("unknown EAN environment %q; known: %s", env, strings.Join(known, ", "))
+	}
+	if path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return c, err
+		}
+		err = json.NewDecoder(f).Decode(&c)
+		f.Close()
+		if err != nil {
+			return c, fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	if v := os.Getenv("EAN_CID"); v != "" {
+		c.Credentials.CID = v
+	}
+	if v := os.Getenv("EAN_API_KEY"); v != "" {
+		c.Credentials.APIKey = v
+	}
+	if env != "mock" && (c.Credentials.CID == "" || c.Credentials.APIKey == "") {
+		return c, ErrNoEanCredentials
+	}
+	return c, nil
+}
+
+// EanEndpoints are the full URLs of the EAN operations.
+type EanEndpoints struct {
+	HotelList        string
+	RoomAvailability string
+	Reservation      string
+	Itinerary        string
+	Cancel           string
+}
+
+// defaultEanEndpoints are the endpoints of an EanHspService with none set:
+// EAN production.
+var defaultEanEndpoints = mustEanEndpoints(eanEnvironments["production"])
+
+func mustEanEndpoints(c EanConfig) EanEndpoints {
+	e, err := c.Endpoints()
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+// Endpoints returns the URLs of the operations, the paths joined to the base
+// URLs.
+func (c EanConfig) Endpoints() (EanEndpoints, error) {
+	base, err := parseBaseURL(c.BaseURL)
+	if err != nil {
+		return EanEndpoints{}, err
+	}
+	booking := base
+	if c.BookingURL != "" {
+		if booking, err = parseBaseURL(c.BookingURL); err != nil {
+			return EanEndpoints{}, err
+		}
+	}
+	join := func(b *url.URL, path string) string {
+		u := *b
+		u.Path = strings.TrimSuffix(b.Path, "/") + "/" + strings.TrimPrefix(path, "/")
+		return u.String()
+	}
+	return EanEndpoints{
+		HotelList:        join(base, c.Paths.HotelList),
+		RoomAvailability: join(base, c.Paths.RoomAvailability),
+		Reservation:      join(booking, c.Paths.Reservation),
+		Itinerary:        join(base, c.Paths.Itinerary),
+		Cancel:           join(base, c.Paths.Cancel),
+	}, nil
+}
+
+func parseBaseURL(s string) (*url.URL, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("ean: base URL %q needs an http or https scheme and a host", s)
+	}
+	return u, nil
+}
+
+// Transport returns the HTTP transport to EAN: its connection pool, TLS and
+// proxy. An empty Proxy uses the environment's (HTTPS_PROXY and friends);
+// "direct" uses none.
+func (c EanConfig) Transport() (*http.Transport, error) {
+	tlsConfig, err := c.TLS.config()
+	if err != nil {
+		return nil, err
+	}
+	proxy := http.ProxyFromEnvironment
+	switch c.Proxy {
+	case "":
+	case "direct":
+		proxy = nil
+	default:
+		u, err := url.Parse(c.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("ean: proxy: %v", err)
+		}
+		proxy = http.ProxyURL(u)
+	}
+	dialer := &net.Dialer{
+		Timeout:   time.Duration(c.HTTP.DialTimeout),
+		KeepAlive: time.Duration(c.HTTP.KeepAlive),
+	}
+	return &http.Transport{
+		Proxy:                 proxy,
+		Dial:                  dialer.Dial,
+		TLSClientConfig:       tlsConfig,
+		TLSHandshakeTimeout:   time.Duration(c.HTTP.TLSHandshakeTimeout),
+		ResponseHeaderTimeout: time.Duration(c.HTTP.ResponseHeaderTimeout),
+		MaxIdleConns:          c.HTTP.MaxIdleConns,
+		MaxIdleConnsPerHost:   c.HTTP.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       c.HTTP.MaxConnsPerHost,
+		IdleConnTimeout:       time.Duration(c.HTTP.IdleConnTimeout),
+	}, nil
+}
+
+// Client returns an http.Client making calls through rt, bounded by
+// HTTP.Timeout.
+func (c EanConfig) Client(rt http.RoundTripper) *http.Client {
+	return &http.Client{Transport: rt, Timeout: time.Duration(c.HTTP.Timeout)}
+}
+
+func (t EanTLS) config() (*tls.Config, error) {
+	c := &tls.Config{ServerName: t.ServerName, InsecureSkipVerify: t.InsecureSkipVerify}
+	if t.CAFile != "" {
+		pem, err := ioutil.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("ean: no certificates in %s", t.CAFile)
+		}
+		c.RootCAs = pool
+	}
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		return nil, errors.New("ean: a client certificate needs both cert_file and key_file")
+	}
+	if t.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		c.Certificates = []tls.Certificate{cert}
+	}
+	return c, nil
+}
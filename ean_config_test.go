@@ -0,0 +1,87 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jbowles/hotel_supply_platform/hspservice"
+)
+
+// setenv sets the environment variables of env, "" unsetting them, and
+// returns a func restoring them.
+func setenv(env map[string]string) func() {
+	old := map[string]string{}
+	for k, v := range env {
+		old[k] = os.Getenv(k)
+		if v == "" {
+			os.Unsetenv(k)
+		} else {
+			os.Setenv(k, v)
+		}
+	}
+	return func() {
+		for k, v := range old {
+			if v == "" {
+				os.Unsetenv(k)
+			} else {
+				os.Setenv(k, v)
+			}
+		}
+	}
+}
+
+func TestLoadEanConfigCredentials(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ean-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "ean.json")
+	if err := ioutil.WriteFile(path, []byte(`{"base_url": "https://ean.example.com", "credentials": {"cid": "55505", "api_key": "file-key"}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name      string
+		env, path string
+		vars      map[string]string
+		want      EanCredentials
+		wantErr   error
+	}{
+		{"file", "production", path, nil, EanCredentials{CID: "55505", APIKey: "file-key"}, nil},
+		{"env overrides file", "production", path, map[string]string{"EAN_API_KEY": "env-key"}, EanCredentials{CID: "55505", APIKey: "env-key"}, nil},
+		{"env only", "sandbox", "", map[string]string{"EAN_CID": "1", "EAN_API_KEY": "env-key"}, EanCredentials{CID: "1", APIKey: "env-key"}, nil},
+		{"none", "production", "", nil, EanCredentials{}, ErrNoEanCredentials},
+		{"no api key", "sandbox", "", map[string]string{"EAN_CID": "1"}, EanCredentials{CID: "1"}, ErrNoEanCredentials},
+		{"mock needs none", "mock", "", nil, EanCredentials{}, nil},
+	} {
+		vars := map[string]string{"EAN_CID": "", "EAN_API_KEY": ""}
+		for k, v := range tc.vars {
+			vars[k] = v
+		}
+		restore := setenv(vars)
+		c, err := loadEanConfig(tc.env, tc.path)
+		restore()
+		if err != tc.wantErr {
+			t.Errorf("%s: error %v, want %v", tc.name, err, tc.wantErr)
+		}
+		if c.Credentials != tc.want {
+			t.Errorf("%s: credentials %+v, want %+v", tc.name, c.Credentials, tc.want)
+		}
+	}
+}
+
+func TestRateBreakdownCredentials(t *testing.T) {
+	rec := &recorder{body: "<HotelListResponse><HotelList size=\"0\"></HotelList></HotelListResponse>"}
+	svc := EanHspService{Client: &http.Client{Transport: rec}, Credentials: testCredentials}
+	if res := svc.RateBreakdown(hspservice.RateBreakdownRequest{}); res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	req, _ := rec.only(t)
+	if q := req.URL.Query(); q.Get("cid") != testCredentials.CID || q.Get("apiKey") != testCredentials.APIKey {
+		t.Errorf("cid %q apiKey %q, want the service's credentials", q.Get("cid"), q.Get("apiKey"))
+	}
+}
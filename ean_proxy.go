@@ -3,11 +3,14 @@ package main
 import (
 	"fmt"
 	"io"
+	"net/http"
 	"net/url"
 	"strings"
 	"time"
 
 	"github.com/jbowles/hotel_supply_platform/hspservice"
+	"github.com/jbowles/hotel_supply_platform/outbound"
+	"github.com/jbowles/hotel_supply_platform/trace"
 	jujuratelimit "github.com/juju/ratelimit"
 	"github.com/sony/gobreaker"
 	"golang.org/x/net/context"
@@ -21,16 +24,27 @@ import (
 	httptransport "github.com/go-kit/kit/transport/http"
 )
 
-// proxymw implements StringService, forwarding Uppercase requests to the
-// provided endpoint, and serving all other (i.e. Count) requests via the
-// embedded StringService.
+// proxymw implements Hsp, forwarding every call to the EAN stacks balanced by
+// lb. The embedded Hsp is only bound to the request context.
 type proxymw struct {
 	context.Context
-	EanHspService endpoint.Endpoint
+	lb             loadbalancer.LoadBalancer
+	attemptTimeout time.Duration
 	hspservice.Hsp
 }
 
-func eanProxyingMiddleware(proxyList string, ctx context.Context, logger log.Logger) ServiceMiddleware {
+// The attempts the proxy makes at a call. Bookings are deduplicated by
+// idempotency key, so only cancellations are not retried.
+const (
+	maxProxyAttempts    = 3
+	maxProxyCancels     = 1
+	unboundedProxyRetry = 24 * time.Hour // retry budget with no deadline nor attempt timeout
+)
+
+// eanProxyingMiddleware forwards calls to the EAN stacks of proxyList with
+// client. The attempts of a call share what is left of its deadline or, when
+// it has none, an attemptTimeout each.
+func eanProxyingMiddleware(proxyList string, ctx context.Context, client *http.Client, attemptTimeout time.Duration, health *supplierHealth, m stackMetrics, logger log.Logger) ServiceMiddleware {
 	if proxyList == "" {
 		logger.Log("proxy_to", "none")
 		return func(next hspservice.Hsp) hspservice.Hsp { return next }
@@ -40,56 +54,204 @@ func eanProxyingMiddleware(proxyList string, ctx context.Context, logger log.Log
 
 	return func(next hspservice.Hsp) hspservice.Hsp {
 		var (
-			qps         = 100 // max to each instance
-			publisher   = static.NewPublisher(proxies, factory(ctx, qps), logger)
-			lb          = loadbalancer.NewRoundRobin(publisher)
-			maxAttempts = 3
-			maxTime     = 100 * time.Millisecond
-			endpoint    = loadbalancer.Retry(maxAttempts, maxTime, lb)
+			qps       = 100 // max to each instance
+			publisher = static.NewPublisher(proxies, factory(ctx, client, qps, health, m), logger)
+			lb        = loadbalancer.NewRoundRobin(publisher)
 		)
-		return proxymw{ctx, endpoint, next}
+		return proxymw{ctx, lb, attemptTimeout, next}
+	}
+}
+
+// WithContext binds the proxy to the context of a request; see contextual.
+func (mw proxymw) WithContext(ctx context.Context) hspservice.Hsp {
+	mw.Context = ctx
+	mw.Hsp = withContext(ctx, mw.Hsp)
+	return mw
+}
+
+// retryBudget is the time the attempts of a call made in ctx share: what is
+// left before its deadline, or else attempts attempt timeouts.
+func retryBudget(ctx context.Context, attempts int, attemptTimeout time.Duration) time.Duration {
+	if deadline, ok := ctx.Deadline(); ok {
+		return deadline.Sub(time.Now())
+	}
+	if attemptTimeout <= 0 {
+		return unboundedProxyRetry
+	}
+	return time.Duration(attempts) * attemptTimeout
+}
+
+// call forwards request to the proxied instances, up to attempts times, in a
+// span of its own and each attempt in its own span.
+func (mw proxymw) call(method string, request interface{}, attempts int) (interface{}, error) {
+	span, ctx := trace.StartSpan(mw.Context, "supplier.ean.proxy")
+	defer span.Finish()
+	span.SetAttribute("method", method)
+	budget := retryBudget(ctx, attempts, mw.attemptTimeout)
+	response, err := loadbalancer.Retry(attempts, budget, mw.lb)(ctx, request)
+	if err == nil {
+		err = responseError(response)
+	}
+	span.SetError(err)
+	return response, err
+}
+
+// responseError returns the error a proxied response carries.
+func responseError(response interface{}) error {
+	switch r := response.(type) {
+	case hspservice.RateBreakdownResponse:
+		return r.Error
+	case hspservice.BookResponse:
+		return r.Error
+	case hspservice.ItineraryResponse:
+		return r.Error
+	case hspservice.CancelResponse:
+		return r.Error
+	}
+	return nil
+}
+
+// RateBreakdown implements hspservice.Hsp by forwarding to the proxied
+// instances.
+func (mw proxymw) RateBreakdown(rbreq hspservice.RateBreakdownRequest) hspservice.RateBreakdownResponse {
+	response, err := mw.call("rate_breakdown", rbreq, maxProxyAttempts)
+	if response == nil {
+		return hspservice.RateBreakdownResponse{Request: rbreq, Error: err}
 	}
+	return response.(hspservice.RateBreakdownResponse)
 }
 
-// satisfy interface
-func (proxymw) RateBreakdown(rbreq hspservice.RateBreakdownRequest) (rbres hspservice.RateBreakdownResponse) {
-	h := HotelAvail{Format: "xml"}
-	//
-	h.HotelId.List = []int{225697, 116908}
-	h.RoomGroup.Rm = []Room{{NumberOfAdults: 2, NumberOfChildren: 0, ChildAges: []int{}}}
+// Book implements hspservice.Hsp by forwarding to the proxied instances.
+func (mw proxymw) Book(r hspservice.BookRequest) hspservice.BookResponse {
+	if r.IdempotencyKey == "" {
+		return hspservice.BookResponse{Error: hspservice.ErrNoIdempotencyKey}
+	}
+	response, err := mw.call("book", r, maxProxyAttempts)
+	if response == nil {
+		return hspservice.BookResponse{Error: err}
+	}
+	return response.(hspservice.BookResponse)
+}
 
-	rbreq.RequestUrl = hspservice.Build(&h, 14)
-	rbres.Request = rbreq
-	return
+// GetItinerary implements hspservice.Hsp by forwarding to the proxied
+// instances.
+func (mw proxymw) GetItinerary(r hspservice.ItineraryRequest) hspservice.ItineraryResponse {
+	response, err := mw.call("itinerary", r, maxProxyAttempts)
+	if response == nil {
+		return hspservice.ItineraryResponse{Error: err}
+	}
+	return response.(hspservice.ItineraryResponse)
 }
 
-func factory(ctx context.Context, qps int) loadbalancer.Factory {
+// Cancel implements hspservice.Hsp by forwarding to the proxied instances.
+func (mw proxymw) Cancel(r hspservice.CancelRequest) hspservice.CancelResponse {
+	response, err := mw.call("cancel", r, maxProxyCancels)
+	if response == nil {
+		return hspservice.CancelResponse{Error: err}
+	}
+	return response.(hspservice.CancelResponse)
+}
+
+// traceAttempt runs each call to instance in its own span, so retries and the
+// calls the breaker or the rate limiter reject show in the trace.
+func traceAttempt(instance string) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			countAttempt(ctx)
+			span, ctx := trace.StartSpan(ctx, "supplier.attempt")
+			defer span.Finish()
+			span.SetAttribute("instance", instance)
+			response, err := next(ctx, request)
+			switch err {
+			case gobreaker.ErrOpenState, gobreaker.ErrTooManyRequests:
+				span.SetAttribute("rejected", "breaker")
+			case kitratelimit.ErrLimited:
+				span.SetAttribute("rejected", "rate_limit")
+			}
+			span.SetError(err)
+			return response, err
+		}
+	}
+}
+
+// factory builds the endpoint of each proxied instance, calling it with
+// client, behind a circuit breaker and a rate limiter, and reports them to
+// health and m.
+func factory(ctx context.Context, client *http.Client, qps int, health *supplierHealth, m stackMetrics) loadbalancer.Factory {
 	return func(instance string) (endpoint.Endpoint, io.Closer, error) {
-		var e endpoint.Endpoint
-		e = makeEanProxy(ctx, instance)
-		e = circuitbreaker.Gobreaker(gobreaker.NewCircuitBreaker(gobreaker.Settings{}))(e)
-		e = kitratelimit.NewTokenBucketLimiter(jujuratelimit.NewBucketWithRate(float64(qps), int64(qps)))(e)
+		e, err := makeEanProxy(ctx, client, instance)
+		if err != nil {
+			return nil, nil, err
+		}
+		inst := &supplierInstance{
+			Supplier: eanSupplierName,
+			Instance: instance,
+			Breaker:  gobreaker.NewCircuitBreaker(gobreaker.Settings{Name: instance, OnStateChange: m.breakerStateChange}),
+			Bucket:   jujuratelimit.NewBucketWithRate(float64(qps), int64(qps)),
+		}
+		health.Add(inst)
+		e = inst.Endpoint()(e)
+		e = circuitbreaker.Gobreaker(inst.Breaker)(e)
+		e = kitratelimit.NewTokenBucketLimiter(inst.Bucket)(e)
+		e = m.countRateLimited(instance)(e)
+		e = traceAttempt(instance)(e)
 		return e, nil, nil
 	}
 }
 
-func makeEanProxy(ctx context.Context, instance string) endpoint.Endpoint {
+// newProxyClient returns the client the proxy calls EAN stacks with: the
+// supplier client layer over pool, tuned by o, so proxied calls get its
+// attempt timeouts, gzip, response size cap and hedging.
+func newProxyClient(pool http.RoundTripper, o outbound.Options) *http.Client {
+	return &http.Client{Transport: outbound.NewTransport(pool, o)}
+}
+
+// makeEanProxy returns the endpoint calling the EAN stack instance, which is
+// a base URL such as "http://ean-1:8023" or just "ean-1:8023". The API paths
+// are below its path, "/ean" by default. The endpoint takes any request of
+// the Hsp methods.
+func makeEanProxy(ctx context.Context, client *http.Client, instance string) (endpoint.Endpoint, error) {
 	if !strings.HasPrefix(instance, "http") {
 		instance = "http://" + instance
 	}
-	u, err := url.Parse(instance)
+	base, err := url.Parse(instance)
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+	if base.Path == "" {
+		base.Path = "/ean"
 	}
-	if u.Path == "" {
-		u.Path = "/ean/rate_breakdown"
+	call := func(method, path string, enc httptransport.EncodeRequestFunc, dec httptransport.DecodeResponseFunc) endpoint.Endpoint {
+		u := *base
+		u.Path = strings.TrimRight(u.Path, "/") + path
+		return httptransport.NewClient(
+			method,
+			&u,
+			enc,
+			dec,
+			httptransport.ClientBefore(trace.ContextToHTTP, hspservice.RequestIDToHTTP),
+			httptransport.SetClient(client),
+		).Endpoint()
 	}
-	return httptransport.NewClient(
-		"GET",
-		u,
-		hspservice.EncodeRateBreakdownRequest,
-		hspservice.DecodeRateBreakdownResponse,
-	).Endpoint()
+	var (
+		rateBreakdown = call("GET", "/rate_breakdown", hspservice.EncodeRateBreakdownRequest, hspservice.DecodeRateBreakdownResponse)
+		book          = call("POST", "/book", hspservice.EncodeBookRequest, hspservice.DecodeBookResponse)
+		itinerary     = call("POST", "/itinerary", hspservice.EncodeItineraryRequest, hspservice.DecodeItineraryResponse)
+		cancel        = call("POST", "/cancel", hspservice.EncodeCancelRequest, hspservice.DecodeCancelResponse)
+	)
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		switch request.(type) {
+		case hspservice.RateBreakdownRequest:
+			return rateBreakdown(ctx, request)
+		case hspservice.BookRequest:
+			return book(ctx, request)
+		case hspservice.ItineraryRequest:
+			return itinerary(ctx, request)
+		case hspservice.CancelRequest:
+			return cancel(ctx, request)
+		}
+		return nil, fmt.Errorf("proxy: no EAN stack endpoint for %T", request)
+	}, nil
 }
 
 func split(s string) []string {
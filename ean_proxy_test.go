@@ -0,0 +1,248 @@
+package main
+
+import (
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/log"
+	"github.com/jbowles/hotel_supply_platform/hspservice"
+	"github.com/jbowles/hotel_supply_platform/outbound"
+	"github.com/jbowles/hotel_supply_platform/trace"
+)
+
+// testMetrics are the metrics of the stacks built by tests, registered once.
+var testMetrics = newStackMetrics(nil)
+
+// newEanStack serves fake as an EAN stack's rate breakdown, tracing to
+// exporter, and returns its server.
+func newEanStack(fake hspservice.Hsp, exporter trace.Exporter) *httptest.Server {
+	return httptest.NewServer(eanStackHandler(fake, exporter))
+}
+
+// eanStackHandler is the handler of newEanStack.
+func eanStackHandler(fake hspservice.Hsp, exporter trace.Exporter) http.Handler {
+	mux := http.NewServeMux()
+	tracer := trace.NewTracer(exporter)
+	mux.Handle("/ean/rate_breakdown", newHTTPHandler(
+		context.Background(),
+		"ean.rate_breakdown",
+		makeRateBreakdownEndpoint(fake),
+		hspservice.DecodeRateBreakdownRequest,
+		hspservice.EncodeRateBreakdownResponse,
+		tracer,
+		log.NewNopLogger(),
+	))
+	mux.Handle("/ean/book", newHTTPHandler(context.Background(), "ean.book", makeBookEndpoint(fake),
+		hspservice.DecodeBookRequest, hspservice.EncodeBookResponse, tracer, log.NewNopLogger()))
+	mux.Handle("/ean/itinerary", newHTTPHandler(context.Background(), "ean.itinerary", makeItineraryEndpoint(fake),
+		hspservice.DecodeItineraryRequest, hspservice.EncodeItineraryResponse, tracer, log.NewNopLogger()))
+	mux.Handle("/ean/cancel", newHTTPHandler(context.Background(), "ean.cancel", makeCancelEndpoint(fake),
+		hspservice.DecodeCancelRequest, hspservice.EncodeCancelResponse, tracer, log.NewNopLogger()))
+	return mux
+}
+
+// newProxy returns an Hsp forwarding calls to the instances with client,
+// bound to ctx, with a second for each attempt.
+func newProxy(ctx context.Context, instances string, client *http.Client) hspservice.Hsp {
+	return newProxyTimeout(ctx, instances, client, time.Second)
+}
+
+// newProxyTimeout is newProxy with attemptTimeout for each attempt.
+func newProxyTimeout(ctx context.Context, instances string, client *http.Client, attemptTimeout time.Duration) hspservice.Hsp {
+	health := newSupplierHealth(testMetrics.observeSupplierCall)
+	mw := eanProxyingMiddleware(instances, context.Background(), client, attemptTimeout, health, testMetrics, log.NewNopLogger())
+	return withContext(ctx, mw(HspService{}))
+}
+
+// slow delays every request to next by d.
+func slow(d time.Duration, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(d)
+		next.ServeHTTP(w, r)
+	})
+}
+
+func TestProxySlowBackend(t *testing.T) {
+	fake := grpcFake{ids: new([]string), mtx: new(sync.Mutex)}
+	stack := httptest.NewServer(slow(250*time.Millisecond, eanStackHandler(fake, nil)))
+	defer stack.Close()
+
+	// The budget is the attempt timeouts, well over the backend's delay.
+	res := newProxyTimeout(context.Background(), stack.URL, &http.Client{}, time.Second).RateBreakdown(hspservice.RateBreakdownRequest{})
+	if res.Error != nil {
+		t.Fatalf("attempt timeout budget: %v", res.Error)
+	}
+
+	// The budget is what is left of the request's deadline.
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	res = newProxyTimeout(ctx, stack.URL, &http.Client{}, 0).RateBreakdown(hspservice.RateBreakdownRequest{})
+	if res.Error != nil {
+		t.Fatalf("deadline budget: %v", res.Error)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	begin := time.Now()
+	res = newProxyTimeout(ctx, stack.URL, &http.Client{}, time.Second).RateBreakdown(hspservice.RateBreakdownRequest{})
+	if res.Error == nil {
+		t.Error("no error past the request's deadline")
+	}
+	if took := time.Since(begin); took > 200*time.Millisecond {
+		t.Errorf("gave up after %v, want at the request's deadline", took)
+	}
+}
+
+func TestProxyForwardsAll(t *testing.T) {
+	fake := grpcFake{ids: new([]string), mtx: new(sync.Mutex)}
+	stack := newEanStack(fake, nil)
+	defer stack.Close()
+	svc := newProxy(context.Background(), stack.URL, &http.Client{})
+
+	book := svc.Book(hspservice.BookRequest{IdempotencyKey: "key-proxy", Email: "guest@example.com"})
+	if it := book.Itinerary; book.Error != nil || it.ItineraryId != "1001" || it.IdempotencyKey != "key-proxy" {
+		t.Errorf("book %+v, %v", it, book.Error)
+	}
+	if res := svc.Book(hspservice.BookRequest{}); res.Error != hspservice.ErrNoIdempotencyKey {
+		t.Errorf("book without a key: %v, want %v", res.Error, hspservice.ErrNoIdempotencyKey)
+	}
+	it := svc.GetItinerary(hspservice.ItineraryRequest{ItineraryId: "42"})
+	if it.Error == nil || !strings.Contains(it.Error.Error(), "no itinerary 42") {
+		t.Errorf("itinerary error %v, want the EAN stack's", it.Error)
+	}
+	c := svc.Cancel(hspservice.CancelRequest{ItineraryId: "1001", ConfirmationNumber: "1234"})
+	if c.Error != nil || len(c.Itinerary.Rooms) != 1 || c.Itinerary.Rooms[0].Status != hspservice.StatusCancelled {
+		t.Errorf("cancel %+v, %v", c.Itinerary, c.Error)
+	}
+	if n := len(*fake.ids); n != 3 {
+		t.Errorf("EAN stack served %d calls, want 3", n)
+	}
+}
+
+func TestProxyTrace(t *testing.T) {
+	fake := grpcFake{ids: new([]string), mtx: new(sync.Mutex)}
+	server := trace.NewInMemoryExporter()
+	srv := newEanStack(fake, server)
+	defer srv.Close()
+
+	client := trace.NewInMemoryExporter()
+	ctx := trace.NewContext(context.Background(), trace.NewTracer(client))
+	ctx = hspservice.NewRequestIDContext(ctx, "req-proxy-1")
+	res := newProxy(ctx, srv.URL, &http.Client{}).RateBreakdown(hspservice.RateBreakdownRequest{Currency: "EUR"})
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	if res.Request.Currency != "EUR" || !reflect.DeepEqual(res.Rates, []hspservice.HotelRate{grpcFakeRate}) {
+		t.Errorf("response %+v, want the EAN stack's", res)
+	}
+	if ids := *fake.ids; len(ids) != 1 || ids[0] != "req-proxy-1" {
+		t.Errorf("EAN stack served request ids %q, want the caller's", ids)
+	}
+
+	spans := client.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("proxy exported %d spans, want 2", len(spans))
+	}
+	attempt, proxy := spans[0], spans[1]
+	if proxy.Name != "supplier.ean.proxy" || attempt.Name != "supplier.attempt" || attempt.Parent != proxy.Context.SpanID {
+		t.Errorf("spans %+v, want an attempt in the proxy span", spans)
+	}
+	if attempt.Attributes["instance"] != srv.URL {
+		t.Errorf("attempt instance %q, want %s", attempt.Attributes["instance"], srv.URL)
+	}
+	remote := server.Spans()
+	if len(remote) != 1 || remote[0].Name != "ean.rate_breakdown" || remote[0].Parent != attempt.Context.SpanID ||
+		remote[0].Context.TraceID != attempt.Context.TraceID {
+		t.Errorf("EAN stack spans %+v, want one child of the attempt", remote)
+	}
+}
+
+func TestProxyTraceFailure(t *testing.T) {
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	client := trace.NewInMemoryExporter()
+	ctx := trace.NewContext(context.Background(), trace.NewTracer(client))
+	res := newProxy(ctx, down.URL, &http.Client{Timeout: time.Second}).RateBreakdown(hspservice.RateBreakdownRequest{})
+	if res.Error == nil {
+		t.Fatal("no error from an instance that is down")
+	}
+
+	var proxy, attempts int
+	for _, s := range client.Spans() {
+		switch s.Name {
+		case "supplier.ean.proxy":
+			proxy++
+			if s.Error == "" {
+				t.Error("proxy span not failed")
+			}
+		case "supplier.attempt":
+			attempts++
+			if s.Error == "" {
+				t.Error("attempt span not failed")
+			}
+		}
+	}
+	if proxy != 1 || attempts == 0 {
+		t.Errorf("%d proxy spans and %d attempt spans, want 1 and at least 1", proxy, attempts)
+	}
+}
+
+// gzipped serves next gzip compressed to the requests that accept it, and
+// keeps the Accept-Encoding of the last request.
+type gzipped struct {
+	next           http.Handler
+	acceptEncoding string
+}
+
+func (g *gzipped) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.acceptEncoding = r.Header.Get("Accept-Encoding")
+	if g.acceptEncoding != "gzip" {
+		g.next.ServeHTTP(w, r)
+		return
+	}
+	rec := httptest.NewRecorder()
+	g.next.ServeHTTP(rec, r)
+	for k, v := range rec.Header() {
+		w.Header()[k] = v
+	}
+	w.Header().Set("Content-Encoding", "gzip")
+	w.WriteHeader(rec.Code)
+	zw := gzip.NewWriter(w)
+	zw.Write(rec.Body.Bytes())
+	zw.Close()
+}
+
+func TestProxyOutboundClient(t *testing.T) {
+	fake := grpcFake{ids: new([]string), mtx: new(sync.Mutex)}
+	g := &gzipped{next: eanStackHandler(fake, nil)}
+	stack := httptest.NewServer(g)
+	defer stack.Close()
+
+	pool := outbound.NewPool(4, 4)
+	client := newProxyClient(pool, outbound.Options{AttemptTimeout: time.Second})
+	res := newProxy(context.Background(), stack.URL, client).RateBreakdown(hspservice.RateBreakdownRequest{})
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	if g.acceptEncoding != "gzip" {
+		t.Errorf("Accept-Encoding %q, want gzip", g.acceptEncoding)
+	}
+	if !reflect.DeepEqual(res.Rates, []hspservice.HotelRate{grpcFakeRate}) {
+		t.Errorf("rates %+v, want the EAN stack's, decompressed", res.Rates)
+	}
+
+	client = newProxyClient(pool, outbound.Options{MaxResponseBytes: 16})
+	res = newProxy(context.Background(), stack.URL, client).RateBreakdown(hspservice.RateBreakdownRequest{})
+	if res.Error == nil || !strings.Contains(res.Error.Error(), outbound.ErrResponseTooLarge.Error()) {
+		t.Errorf("error %v, want %v", res.Error, outbound.ErrResponseTooLarge)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// queryEncoder is implemented by request types that do not map onto a single
+// query parameter, e.g. RoomGroup, which EAN flattens to room1, room2, ...
+type queryEncoder interface {
+	encodeQuery(v url.Values)
+}
+
+// queryValues adds every populated field of the struct s to v, keyed by the
+// field's json tag name. Embedded structs are flattened, empty values and
+// fields tagged `json:"-"` are dropped, and int slices are comma separated.
+func queryValues(v url.Values, s interface{}) {
+	rv := reflect.Indirect(reflect.ValueOf(s))
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		f, fv := rt.Field(i), rv.Field(i)
+		if f.PkgPath != "" && !f.Anonymous { // unexported
+			continue
+		}
+		if qe, ok := fv.Interface().(queryEncoder); ok {
+			qe.encodeQuery(v)
+			continue
+		}
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && fv.Kind() == reflect.Struct {
+			queryValues(v, fv.Interface())
+			continue
+		}
+		if str, ok := queryString(fv); ok && name != "" {
+			v.Add(name, str)
+		}
+	}
+}
+
+// queryString formats a single field value; ok is false for empty values and
+// for kinds EAN has no query representation of.
+func queryString(fv reflect.Value) (s string, ok bool) {
+	switch fv.Kind() {
+	case reflect.String:
+		s = fv.String()
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		if fv.Int() != 0 {
+			s = strconv.FormatInt(fv.Int(), 10)
+		}
+	case reflect.Bool:
+		if fv.Bool() {
+			s = "true"
+		}
+	case reflect.Slice:
+		parts := make([]string, 0, fv.Len())
+		for i := 0; i < fv.Len(); i++ {
+			if p, ok := queryString(fv.Index(i)); ok {
+				parts = append(parts, p)
+			}
+		}
+		s = strings.Join(parts, ",")
+	}
+	return s, s != ""
+}
+
+// encodeQuery implements queryEncoder. EAN expects one parameter per room,
+// room1=2,5,7 being two adults travelling with children aged 5 and 7.
+func (g RoomGroup) encodeQuery(v url.Values) {
+	for i, rm := range g.Rm {
+		room := []string{strconv.Itoa(rm.NumberOfAdults)}
+		for _, age := range rm.ChildAges {
+			room = append(room, strconv.Itoa(age))
+		}
+		v.Add("room"+strconv.Itoa(i+1), strings.Join(room, ","))
+	}
+}
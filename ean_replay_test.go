@@ -0,0 +1,105 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/jbowles/hotel_supply_platform/booking"
+	"github.com/jbowles/hotel_supply_platform/eanmock"
+	"github.com/jbowles/hotel_supply_platform/hspservice"
+	"github.com/jbowles/hotel_supply_platform/replay"
+)
+
+// eanRecording is a session with the mock EAN server, recorded with -update.
+const eanRecording = "testdata/ean_replay.jsonl"
+
+// toServer sends every request to the server at srv instead of its host.
+type toServer struct{ srv *url.URL }
+
+func (t toServer) RoundTrip(req *http.Request) (*http.Response, error) {
+	r, u := *req, *req.URL
+	u.Scheme, u.Host = t.srv.Scheme, t.srv.Host
+	r.URL = &u
+	return http.DefaultTransport.RoundTrip(&r)
+}
+
+// replayedEan returns an EanHspService at the mock environment's URLs,
+// answered from the recording, and a func that ends the session. With
+// -update, it records a new session with a mock EAN server instead.
+func replayedEan(t *testing.T) (EanHspService, func()) {
+	endpoints, err := eanEnvironments["mock"].Endpoints()
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := EanHspService{Endpoints: endpoints, Credentials: testCredentials, Itineraries: booking.NewMemory()}
+	if !*update {
+		p, err := replay.Open(eanRecording)
+		if err != nil {
+			t.Fatal(err)
+		}
+		svc.Client = &http.Client{Transport: p}
+		return svc, func() {}
+	}
+
+	srv := httptest.NewServer(eanmock.New(eanmock.DefaultFixtures(), eanmock.Config{}))
+	u, _ := url.Parse(srv.URL)
+	f, err := os.Create(eanRecording)
+	if err != nil {
+		srv.Close()
+		t.Fatal(err)
+	}
+	rec := replay.NewRecorder(f)
+	svc.Client = &http.Client{Transport: rec.Transport(toServer{u})}
+	return svc, func() {
+		srv.Close()
+		if err := rec.Err(); err != nil {
+			t.Error(err)
+		}
+		if err := f.Close(); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestEanReplay(t *testing.T) {
+	svc, done := replayedEan(t)
+	defer done()
+
+	rates := svc.RateBreakdown(hspservice.RateBreakdownRequest{Currency: "USD"})
+	if rates.Error != nil {
+		t.Fatal(rates.Error)
+	}
+	if len(rates.Rates) == 0 {
+		t.Fatal("no rates replayed")
+	}
+	for _, r := range rates.Rates {
+		if r.Supplier != eanSupplierName || r.HotelId == "" || r.Total == "" {
+			t.Errorf("rate %+v, want an EAN one", r)
+		}
+	}
+
+	booked := svc.Book(bookRequest("key-replay", "200", "2001", "289.50", 2))
+	if booked.Error != nil {
+		t.Fatal(booked.Error)
+	}
+	it := booked.Itinerary
+	if it.Status != hspservice.StatusConfirmed || len(it.Rooms) != 2 {
+		t.Fatalf("itinerary %+v, want confirmed with 2 rooms", it)
+	}
+
+	got := svc.GetItinerary(hspservice.ItineraryRequest{ItineraryId: it.ItineraryId, Email: it.Email})
+	if got.Error != nil || got.Itinerary.ItineraryId != it.ItineraryId {
+		t.Errorf("itinerary %+v, %v; want %s", got.Itinerary, got.Error, it.ItineraryId)
+	}
+
+	cancelled := svc.Cancel(hspservice.CancelRequest{ItineraryId: it.ItineraryId, ConfirmationNumber: it.Rooms[0].ConfirmationNumber, Email: it.Email})
+	if cancelled.Error != nil {
+		t.Fatal(cancelled.Error)
+	}
+	if r := cancelled.Itinerary.Rooms; r[0].Status != hspservice.StatusCancelled || r[1].Status != hspservice.StatusConfirmed {
+		t.Errorf("rooms %+v, want the first cancelled", r)
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jbowles/hotel_supply_platform/booking"
+	"github.com/jbowles/hotel_supply_platform/hspservice"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of testdata")
+
+// testCredentials are the EAN credentials of the golden requests.
+var testCredentials = EanCredentials{CID: "55505", APIKey: "test-api-key"}
+
+// golden compares got to the golden file testdata/name, or rewrites the file
+// with -update.
+func golden(t *testing.T, name string, got []byte) {
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := ioutil.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s differs from the golden file\ngot:\n%s\nwant:\n%s", name, got, want)
+	}
+}
+
+// goldenRequest compares the exact request line, and form body if any, of
+// req to testdata/name.url, and its xml param to testdata/name.xml.
+func goldenRequest(t *testing.T, name, method string, u *url.URL, body string) {
+	line := fmt.Sprintf("%s %s\n", method, u)
+	v := u.Query()
+	if body != "" {
+		line += body + "\n"
+		var err error
+		if v, err = url.ParseQuery(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	golden(t, name+".url", []byte(line))
+	if x := v.Get("xml"); x != "" {
+		golden(t, name+".xml", []byte(x+"\n"))
+	}
+}
+
+func TestHotelListParamsGolden(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		format   string
+		currency string
+		cursor   *hspservice.Cursor
+	}{
+		{"hotel_list", "xml", "", nil},
+		{"hotel_list_json", "json", "", nil},
+		{"hotel_list_eur", "xml", "EUR", nil},
+		{"hotel_list_page", "xml", "", &hspservice.Cursor{Supplier: eanSupplierName, CacheKey: "-7f4a1c2b:15e1d3a2b4c:-7ff3", CacheLocation: "10.186.170.126:7300"}},
+	} {
+		h := HotelAvail{Format: tc.format, CurrencyCode: tc.currency, Credentials: testCredentials}
+		if err := h.Stay("2026-11-02", "2026-11-05"); err != nil {
+			t.Fatal(err)
+		}
+		h.HotelId.List = []int{225697, 116908}
+		h.RoomGroup.Rm = []Room{{NumberOfAdults: 2, ChildAges: []int{}}}
+		if tc.cursor != nil {
+			if err := h.Page(*tc.cursor); err != nil {
+				t.Fatal(err)
+			}
+		}
+		u, err := h.Params()
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		goldenRequest(t, tc.name, "GET", u, "")
+	}
+}
+
+// recorder is an http.RoundTripper that keeps the requests it is sent and
+// answers each with body, and status when it is set.
+type recorder struct {
+	body   string
+	status int
+	reqs   []*http.Request
+	forms  []string
+}
+
+func (r *recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var form []byte
+	if req.Body != nil {
+		var err error
+		if form, err = ioutil.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+	}
+	r.reqs = append(r.reqs, req)
+	r.forms = append(r.forms, string(form))
+	status := r.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	return &http.Response{
+		StatusCode: status,
+		Status:     fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		Header:     http.Header{"Content-Type": {"application/xml"}},
+		Body:       ioutil.NopCloser(strings.NewReader(r.body)),
+		Request:    req,
+	}, nil
+}
+
+// only returns the one request r was sent.
+func (r *recorder) only(t *testing.T) (*http.Request, string) {
+	if len(r.reqs) != 1 {
+		t.Fatalf("sent %d requests, want 1", len(r.reqs))
+	}
+	return r.reqs[0], r.forms[0]
+}
+
+func isEanWsError(err error) bool {
+	_, ok := err.(*EanWsError)
+	return ok
+}
+
+func TestBookingRequestsGolden(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		root string
+		call func(EanHspService) error
+	}{
+		{"reservation", "HotelRoomReservationResponse", func(s EanHspService) error {
+			return s.Book(hspservice.BookRequest{
+				IdempotencyKey: "3f9c2d61-golden",
+				Supplier:       eanSupplierName,
+				HotelId:        "225697",
+				Arrival:        "2026-11-02",
+				Departure:      "2026-11-05",
+				RateKey:        "mock-225697-200-2001",
+				RoomTypeCode:   "200",
+				RateCode:       "2001",
+				ChargeableRate: "289.50",
+				Currency:       "USD",
+				Email:          "guest@example.com",
+				Rooms: []hspservice.BookRoom{
+					{Adults: 2, ChildAges: []int{4, 7}, FirstName: "Ada", LastName: "Guest", SmokingPreference: "NS"},
+				},
+				Payment: hspservice.BookPayment{
+					CardType: "CA", CardNumber: "5401999999999999", CardIdentifier: "123",
+					ExpirationMonth: "11", ExpirationYear: "2029",
+					FirstName: "Ada", LastName: "Guest",
+					Address1: "1 Main St", City: "Seattle", StateProvinceCode: "WA", CountryCode: "US", PostalCode: "98101",
+				},
+			}).Error
+		}},
+		{"itinerary", "HotelItineraryResponse", func(s EanHspService) error {
+			return s.GetItinerary(hspservice.ItineraryRequest{ItineraryId: "1001", Email: "guest@example.com"}).Error
+		}},
+		{"cancel", "HotelRoomCancellationResponse", func(s EanHspService) error {
+			return s.Cancel(hspservice.CancelRequest{ItineraryId: "1001", ConfirmationNumber: "1234", Email: "guest@example.com", Reason: "COP"}).Error
+		}},
+	} {
+		// An EanWsError ends every call after its one request.
+		rec := &recorder{body: fmt.Sprintf("<%[1]s><EanWsError><category>DATA_VALIDATION</category></EanWsError></%[1]s>", tc.root)}
+		s := EanHspService{Client: &http.Client{Transport: rec}, Credentials: testCredentials, Itineraries: booking.NewMemory()}
+		if err := tc.call(s); !isEanWsError(err) {
+			t.Fatalf("%s: got error %v, want the recorded EanWsError", tc.name, err)
+		}
+		req, form := rec.only(t)
+		goldenRequest(t, tc.name, req.Method, req.URL, form)
+	}
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/jbowles/hotel_supply_platform/hspservice"
+	"golang.org/x/net/context"
+)
+
+// HotelListResponse is the EAN hotel list response. Like HotelAvail it only
+// models what we use; EAN sends many more fields.
+// MoreResultsAvailable, CacheKey and CacheLocation drive the follow up pages.
+type HotelListResponse struct {
+	XMLName              xml.Name    `xml:"HotelListResponse" json:"-"`
+	CustomerSessionId    string      `xml:"customerSessionId" json:"customerSessionId"`
+	MoreResultsAvailable bool        `xml:"moreResultsAvailable" json:"moreResultsAvailable"`
+	CacheKey             string      `xml:"cacheKey" json:"cacheKey"`
+	CacheLocation        string      `xml:"cacheLocation" json:"cacheLocation"`
+	EanWsError           *EanWsError `xml:"EanWsError" json:"EanWsError"`
+	HotelList            HotelList   `xml:"HotelList" json:"HotelList"`
+}
+
+// HotelList wraps the hotel summaries of a single page.
+type HotelList struct {
+	Size                int            `xml:"size,attr" json:"@size,string"`
+	ActivePropertyCount int            `xml:"activePropertyCount,attr" json:"@activePropertyCount,string"`
+	Hotels              []HotelSummary `xml:"HotelSummary" json:"HotelSummary"`
+}
+
+// HotelSummary is a single hotel in a hotel list page.
+// Rates are kept as decimal text (json.Number decodes from both formats) so no
+// precision is lost to floats.
+type HotelSummary struct {
+	HotelId          int         `xml:"hotelId" json:"hotelId"`
+	Name             string      `xml:"name" json:"name"`
+	City             string      `xml:"city" json:"city"`
+	CountryCode      string      `xml:"countryCode" json:"countryCode"`
+	LowRate          json.Number `xml:"lowRate" json:"lowRate"`
+	HighRate         json.Number `xml:"highRate" json:"highRate"`
+	RateCurrencyCode string      `xml:"rateCurrencyCode" json:"rateCurrencyCode"`
+	RoomRateDetails  struct {
+		List []RoomRateDetails `xml:"RoomRateDetails" json:"RoomRateDetails"`
+	} `xml:"RoomRateDetailsList" json:"RoomRateDetailsList"`
+}
+
+// RoomRateDetails is a bookable room type at a hotel, sent with the
+// ROOM_RATE_DETAILS option. Codes are json.Number as EAN's JSON sends them as numbers.
+type RoomRateDetails struct {
+	RoomTypeCode    json.Number `xml:"roomTypeCode" json:"roomTypeCode"`
+	RateCode        json.Number `xml:"rateCode" json:"rateCode"`
+	RoomDescription string      `xml:"roomDescription" json:"roomDescription"`
+	RateInfos       struct {
+		List []RateInfo `xml:"RateInfo" json:"RateInfo"`
+	} `xml:"RateInfos" json:"RateInfos"`
+}
+
+// RateInfo is the price of a room type, with the rateKey needed to book it.
+// CancellationPolicy is the policy as text; CancelPolicyInfoList has its
+// penalty windows.
+type RateInfo struct {
+	NonRefundable        bool   `xml:"nonRefundable" json:"nonRefundable"`
+	CancellationPolicy   string `xml:"cancellationPolicy" json:"cancellationPolicy"`
+	CancelPolicyInfoList struct {
+		List []CancelPolicyInfo `xml:"CancelPolicyInfo" json:"CancelPolicyInfo"`
+	} `xml:"CancelPolicyInfoList" json:"CancelPolicyInfoList"`
+	RoomGroup struct {
+		Rooms []RateRoom `xml:"Room" json:"Room"`
+	} `xml:"RoomGroup" json:"RoomGroup"`
+	ChargeableRateInfo struct {
+		Total        json.Number `xml:"total,attr" json:"@total"`
+		CurrencyCode string      `xml:"currencyCode,attr" json:"@currencyCode"`
+	} `xml:"ChargeableRateInfo" json:"ChargeableRateInfo"`
+}
+
+// RateRoom is a room of a RateInfo.
+type RateRoom struct {
+	NumberOfAdults int    `xml:"numberOfAdults" json:"numberOfAdults"`
+	RateKey        string `xml:"rateKey" json:"rateKey"`
+}
+
+// EanWsError is the error element EAN returns instead of (or inside) a response.
+type EanWsError struct {
+	ItineraryId          int64  `xml:"itineraryId" json:"itineraryId"`
+	Handling             string `xml:"handling" json:"handling"`
+	Category             string `xml:"category" json:"category"`
+	ExceptionConditionId int    `xml:"exceptionConditionId" json:"exceptionConditionId"`
+	PresentationMessage  string `xml:"presentationMessage" json:"presentationMessage"`
+	VerboseMessage       string `xml:"verboseMessage" json:"verboseMessage"`
+}
+
+func (e *EanWsError) Error() string {
+	return fmt.Sprintf("ean: %s (%s): %s", e.Category, e.Handling, e.PresentationMessage)
+}
+
+// eanJSONLists are the elements EAN's JSON mode sends as a lone value rather
+// than a one element array when there is only one of them.
+var eanJSONLists = map[string]bool{
+	"HotelSummary":        true,
+	"RoomRateDetails":     true,
+	"RateInfo":            true,
+	"Room":                true,
+	"CancelPolicyInfo":    true,
+	"Itinerary":           true,
+	"HotelConfirmation":   true,
+	"confirmationNumbers": true,
+}
+
+// eanResponse is implemented by EAN response types, all of which can carry an
+// EanWsError in place of a result.
+type eanResponse interface {
+	wsError() *EanWsError
+}
+
+func (r *HotelListResponse) wsError() *EanWsError { return r.EanWsError }
+
+// decodeEanJSON decodes an EAN JSON document into v, first wrapping any lone
+// eanJSONLists object into an array so v can always use slices.
+func decodeEanJSON(r io.Reader, v interface{}) error {
+	var doc interface{}
+	d := json.NewDecoder(r)
+	d.UseNumber()
+	if err := d.Decode(&doc); err != nil {
+		return err
+	}
+	b, err := json.Marshal(eanJSONArrays(doc))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+func eanJSONArrays(doc interface{}) interface{} {
+	switch t := doc.(type) {
+	case map[string]interface{}:
+		for k, v := range t {
+			if _, ok := v.([]interface{}); !ok && eanJSONLists[k] {
+				v = []interface{}{v}
+			}
+			t[k] = eanJSONArrays(v)
+		}
+	case []interface{}:
+		for i := range t {
+			t[i] = eanJSONArrays(t[i])
+		}
+	}
+	return doc
+}
+
+// Rates normalizes the page into supplier neutral rates: one per room rate
+// when EAN sent room rate details, otherwise one per hotel at its lowest rate.
+// Room rates carry their cancellation policy for a stay arriving on arrival
+// (MM/DD/YYYY). A policy that cannot be parsed keeps its text but no tiers, so
+// its penalty is unknown rather than guessed, and the first such error is
+// returned with the rates.
+func (r HotelListResponse) Rates(arrival string) ([]hspservice.HotelRate, error) {
+	rates := make([]hspservice.HotelRate, 0, len(r.HotelList.Hotels))
+	var first error
+	for _, h := range r.HotelList.Hotels {
+		var err error
+		if rates, err = h.appendRates(rates, arrival); err != nil && first == nil {
+			first = err
+		}
+	}
+	return rates, first
+}
+
+// appendRates appends the rates of the hotel to rates; see Rates.
+func (h HotelSummary) appendRates(rates []hspservice.HotelRate, arrival string) ([]hspservice.HotelRate, error) {
+	hr := hspservice.HotelRate{
+		Supplier:    eanSupplierName,
+		HotelId:     strconv.Itoa(h.HotelId),
+		HotelName:   h.Name,
+		CountryCode: h.CountryCode,
+		Total:       h.LowRate.String(),
+		Currency:    h.RateCurrencyCode,
+	}
+	if len(h.RoomRateDetails.List) == 0 {
+		return append(rates, hr), nil
+	}
+	var first error
+	for _, d := range h.RoomRateDetails.List {
+		for _, ri := range d.RateInfos.List {
+			rr := hr
+			rr.RoomTypeCode, rr.RateCode = d.RoomTypeCode.String(), d.RateCode.String()
+			rr.Total, rr.Currency = ri.ChargeableRateInfo.Total.String(), ri.ChargeableRateInfo.CurrencyCode
+			if len(ri.RoomGroup.Rooms) > 0 {
+				rr.RateKey = ri.RoomGroup.Rooms[0].RateKey
+			}
+			p, err := ri.Policy(arrival)
+			if err != nil && first == nil {
+				first = fmt.Errorf("ean: hotel %d room type %s: cancellation policy: %v", h.HotelId, rr.RoomTypeCode, err)
+			}
+			rr.CancellationPolicy = &p
+			rates = append(rates, rr)
+		}
+	}
+	return rates, first
+}
+
+// NextCursor returns the cursor for the follow up page, if EAN has one.
+func (r HotelListResponse) NextCursor() (hspservice.Cursor, bool) {
+	if !r.MoreResultsAvailable || r.CacheKey == "" {
+		return hspservice.Cursor{}, false
+	}
+	return hspservice.Cursor{
+		Supplier:      eanSupplierName,
+		CacheKey:      r.CacheKey,
+		CacheLocation: r.CacheLocation,
+	}, true
+}
+
+// decodeEan decodes the EAN response named root, in the given format ("xml" or
+// "json"), into out. A returned EanWsError is surfaced as the error.
+func decodeEan(format string, r io.Reader, root string, out eanResponse) error {
+	var err error
+	switch format {
+	case "json":
+		var envelope map[string]json.RawMessage
+		if err = decodeEanJSON(r, &envelope); err != nil {
+			break
+		}
+		if doc, ok := envelope[root]; ok {
+			err = json.Unmarshal(doc, out)
+		} else {
+			err = fmt.Errorf("ean: response has no %s", root)
+		}
+	default:
+		err = xml.NewDecoder(r).Decode(out)
+	}
+	if err != nil {
+		return err
+	}
+	if e := out.wsError(); e != nil {
+		return e
+	}
+	return nil
+}
+
+// decodeHotelList decodes a hotel list response; see decodeEan.
+func decodeHotelList(format string, r io.Reader) (list HotelListResponse, err error) {
+	err = decodeEan(format, r, "HotelListResponse", &list)
+	return list, err
+}
+
+// fetchHotelList makes the hotel list request in ctx and decodes the
+// response, calling hotel with each hotel as it is decoded; the returned
+// response has no hotels. XML responses are streamed, see streamHotelList;
+// JSON ones are decoded whole first.
+func fetchHotelList(ctx context.Context, client *http.Client, u *url.URL, format string, hotel func(HotelSummary) error) (list HotelListResponse, err error) {
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return list, err
+	}
+	req = req.WithContext(ctx)
+	if format == "json" {
+		req.Header.Set("Accept", "application/json")
+	} else {
+		req.Header.Set("Accept", "application/xml")
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return list, hspservice.RedactError(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return list, fmt.Errorf("ean: unexpected status %s", resp.Status)
+	}
+	if format != "json" {
+		return streamHotelList(resp.Body, hotel)
+	}
+	if list, err = decodeHotelList(format, resp.Body); err != nil {
+		return list, err
+	}
+	hotels := list.HotelList.Hotels
+	list.HotelList.Hotels = nil
+	for _, h := range hotels {
+		if err = hotel(h); err != nil {
+			return list, err
+		}
+	}
+	return list, nil
+}
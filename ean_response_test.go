@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// hotelListBodies are the same hotel list pages as EAN sends them in XML and
+// in JSON mode.
+var hotelListBodies = []struct {
+	name      string
+	xml, json string
+}{
+	{"two hotels",
+		`<HotelListResponse>
+	<customerSessionId>0ABAAA7A-1B2C</customerSessionId>
+	<moreResultsAvailable>true</moreResultsAvailable>
+	<cacheKey>-7f4a1c2b:15e1d3a2b4c:-7ff3</cacheKey>
+	<cacheLocation>10.186.170.126:7300</cacheLocation>
+	<HotelList size="2" activePropertyCount="3">
+		<HotelSummary><hotelId>225697</hotelId><name>Harbour Hotel</name><city>Seattle</city><countryCode>US</countryCode><lowRate>129.0</lowRate><highRate>189.5</highRate><rateCurrencyCode>USD</rateCurrencyCode></HotelSummary>
+		<HotelSummary><hotelId>116908</hotelId><name>Pike Inn</name><city>Seattle</city><countryCode>US</countryCode><lowRate>99.0</lowRate><highRate>99.0</highRate><rateCurrencyCode>USD</rateCurrencyCode></HotelSummary>
+	</HotelList>
+</HotelListResponse>`,
+		`{"HotelListResponse": {
+	"customerSessionId": "0ABAAA7A-1B2C",
+	"moreResultsAvailable": true,
+	"cacheKey": "-7f4a1c2b:15e1d3a2b4c:-7ff3",
+	"cacheLocation": "10.186.170.126:7300",
+	"HotelList": {"@size": "2", "@activePropertyCount": "3", "HotelSummary": [
+		{"hotelId": 225697, "name": "Harbour Hotel", "city": "Seattle", "countryCode": "US", "lowRate": 129.0, "highRate": 189.5, "rateCurrencyCode": "USD"},
+		{"hotelId": 116908, "name": "Pike Inn", "city": "Seattle", "countryCode": "US", "lowRate": 99.0, "highRate": 99.0, "rateCurrencyCode": "USD"}
+	]}
+}}`},
+	{"lone hotel",
+		`<HotelListResponse>
+	<customerSessionId>0ABAAA7A-1B2C</customerSessionId>
+	<HotelList size="1" activePropertyCount="3">
+		<HotelSummary><hotelId>225698</hotelId><name>Union Lofts</name><city>Seattle</city><countryCode>US</countryCode><lowRate>150.25</lowRate><highRate>150.25</highRate><rateCurrencyCode>USD</rateCurrencyCode></HotelSummary>
+	</HotelList>
+</HotelListResponse>`,
+		`{"HotelListResponse": {
+	"customerSessionId": "0ABAAA7A-1B2C",
+	"HotelList": {"@size": "1", "@activePropertyCount": "3", "HotelSummary":
+		{"hotelId": 225698, "name": "Union Lofts", "city": "Seattle", "countryCode": "US", "lowRate": 150.25, "highRate": 150.25, "rateCurrencyCode": "USD"}
+	}
+}}`},
+}
+
+func TestDecodeHotelListFormatsAgree(t *testing.T) {
+	for _, tc := range hotelListBodies {
+		xmlList, err := decodeHotelList("xml", strings.NewReader(tc.xml))
+		if err != nil {
+			t.Fatalf("%s: xml: %v", tc.name, err)
+		}
+		jsonList, err := decodeHotelList("json", strings.NewReader(tc.json))
+		if err != nil {
+			t.Fatalf("%s: json: %v", tc.name, err)
+		}
+		if len(xmlList.HotelList.Hotels) != xmlList.HotelList.Size {
+			t.Errorf("%s: decoded %d hotels, want %d", tc.name, len(xmlList.HotelList.Hotels), xmlList.HotelList.Size)
+		}
+		xmlList.XMLName = xml.Name{}
+		if !reflect.DeepEqual(xmlList, jsonList) {
+			t.Errorf("%s: lists differ\nxml:  %+v\njson: %+v", tc.name, xmlList, jsonList)
+		}
+		xmlCursor, xmlMore := xmlList.NextCursor()
+		jsonCursor, jsonMore := jsonList.NextCursor()
+		if xmlCursor != jsonCursor || xmlMore != jsonMore {
+			t.Errorf("%s: cursors differ: xml %+v %v, json %+v %v", tc.name, xmlCursor, xmlMore, jsonCursor, jsonMore)
+		}
+	}
+}
+
+func TestDecodeHotelListError(t *testing.T) {
+	for _, tc := range []struct {
+		format, body string
+	}{
+		{"xml", `<HotelListResponse><EanWsError><handling>RECOVERABLE</handling><category>DATA_VALIDATION</category><presentationMessage>bad dates</presentationMessage></EanWsError></HotelListResponse>`},
+		{"json", `{"HotelListResponse": {"EanWsError": {"handling": "RECOVERABLE", "category": "DATA_VALIDATION", "presentationMessage": "bad dates"}}}`},
+	} {
+		_, err := decodeHotelList(tc.format, strings.NewReader(tc.body))
+		if err == nil || err.Error() != "ean: DATA_VALIDATION (RECOVERABLE): bad dates" {
+			t.Errorf("%s: got error %v", tc.format, err)
+		}
+	}
+}
@@ -1,36 +1,54 @@
 package main
 
 // EanApi provides basic GET/POST parameter queries and resposne handling for
-// EAN Hotel List APIs. It also supports both XML and JSON. However, EAN API does not support embedded json requests so the JSON usage is limited: XML should be the defualt for EAN as it seems the support XML encoded requests is much better.
+// EAN Hotel List APIs. It supports both XML and JSON. EAN does not accept an embedded json document the way it accepts the xml param, so in JSON mode the request is sent as plain REST query params built from the json struct tags (see ean_query.go) and the response is parsed as JSON.
 // NOTE: Structs have the minimal required params per Hotel queries; there are many more available filters and request params.
 // TODO: read configuration from a file.
 
 import (
-	"fmt"
-	"strings"
 	"time"
 
-	"bytes"
 	"encoding/json"
 	"encoding/xml"
-	"io/ioutil"
+	"fmt"
+	"net/http"
 	"net/url"
 	"strconv"
 
 	"github.com/go-kit/kit/log"
-	"github.com/go-kit/kit/metrics"
+	"github.com/jbowles/hotel_supply_platform/booking"
+	"github.com/jbowles/hotel_supply_platform/format"
 	"github.com/jbowles/hotel_supply_platform/hspservice"
+	"github.com/jbowles/hotel_supply_platform/trace"
 	"github.com/jbowles/quicksilver/formatter"
+	"golang.org/x/net/context"
 )
 
 // interface to satisfy interface methods
+// When Client is nil the service only builds the supplier request URL.
+// Format is "xml" (the default) or "json".
+// Itineraries records bookings so retried Book calls are not booked twice.
+// Trace returns the redacted supplier calls with each rate breakdown; it is
+// for debugging only.
+// Endpoints are the EAN URLs called, EAN production when left empty, with
+// Credentials.
+// Logger gets what EAN sent that could not be used but did not fail the call,
+// such as a cancellation policy that could not be parsed; nil logs nothing.
 type EanHspService struct {
 	Service                  hspservice.Hsp
+	Client                   *http.Client
+	Endpoints                EanEndpoints
+	Credentials              EanCredentials
+	Format                   string
+	Trace                    bool
+	Logger                   log.Logger
+	ctx                      context.Context
+	Itineraries              booking.Repository
 	cid                      string
 	minorRev                 string
 	apiKey                   string
 	locale                   string
-	currencyCode             string // only for booking and payment type
+	currencyCode             string // of rates and payment; empty is MakeEanSpecs'
 	customerSessionId        string
 	customerIpAddress        string
 	customerUserAgent        string
@@ -42,75 +60,128 @@ type EanHspService struct {
 	options                  string
 }
 
-type eanLoggingMiddleware struct {
-	hspservice.Hsp
-	//hspservice.Hsp
-	logger log.Logger
+// WithContext binds the service to the context of a request; see contextual.
+func (s EanHspService) WithContext(ctx context.Context) hspservice.Hsp {
+	s.ctx = ctx
+	return s
+}
+
+func (s EanHspService) context() context.Context {
+	if s.ctx == nil {
+		return context.Background()
+	}
+	return s.ctx
+}
+
+func (s EanHspService) logger() log.Logger {
+	if s.Logger == nil {
+		return log.NewNopLogger()
+	}
+	return s.Logger
 }
 
-/*
-func eanLoggingMiddleware(logger log.Logger) ServiceMiddleware {
-	return func(next hspservice.Hsp) hspservice.Hsp {
-		return logmw{logger, next}
+func (s EanHspService) endpoints() EanEndpoints {
+	if s.Endpoints == (EanEndpoints{}) {
+		return defaultEanEndpoints
 	}
+	return s.Endpoints
 }
-*/
 
-type eanInstrumentingMiddleware struct {
-	hspservice.Hsp
-	//requestCount        metrics.Counter
-	requestDuration metrics.TimeHistogram
-	//rateBreakdownResult metrics.Histogram
+// sessionID is the customerSessionId EAN calls carry: the request id, so
+// EAN support tickets can be matched to our logs, or the default session.
+func (s EanHspService) sessionID() string {
+	if id := hspservice.RequestIDFromContext(s.context()); id != "" {
+		return id
+	}
+	return MakeEanSpecs(s.Credentials).customerSessionId
 }
 
 // satisfy interface
-func (EanHspService) RateBreakdown(rbreq hspservice.RateBreakdownRequest) (rbres hspservice.RateBreakdownResponse) {
-	h := HotelAvail{Format: "xml"}
+func (s EanHspService) RateBreakdown(rbreq hspservice.RateBreakdownRequest) (rbres hspservice.RateBreakdownResponse) {
+	h := HotelAvail{Format: s.Format, Endpoint: s.endpoints().HotelList, Credentials: s.Credentials, CurrencyCode: rbreq.Currency}
+	if h.Format == "" {
+		h.Format = "xml"
+	}
 	//
 	h.HotelId.List = []int{225697, 116908}
 	h.RoomGroup.Rm = []Room{{NumberOfAdults: 2, NumberOfChildren: 0, ChildAges: []int{}}}
 
-	rbreq.RequestUrl = hspservice.Build(&h, 14)
 	rbres.Request = rbreq
-	return
-}
-
-func (m eanLoggingMiddleware) RateBreakdown(rbreq hspservice.RateBreakdownRequest) (rbres hspservice.RateBreakdownResponse) {
-	defer func(begin time.Time) {
-		_ = m.logger.Log(
-			"method", "ean_rate_breakdown",
-			"took", time.Since(begin),
-		)
-	}(time.Now())
-
-	//rbres = hspservice.RateBreakdownResponse{rbreq, nil}
-	return
-}
-
-func (m eanInstrumentingMiddleware) RateBreakdown(rbreq hspservice.RateBreakdownRequest) (rbres hspservice.RateBreakdownResponse) {
-	defer func(begin time.Time) {
-		methodField := metrics.Field{Key: "method", Value: "ean_rate_breakdown"}
-		errorField := metrics.Field{Key: "error", Value: fmt.Sprintf("%v", rbres.Error)}
-		//m.requestCount.With(methodField).With(errorField).Add(1)
-		m.requestDuration.With(methodField).With(errorField).Observe(time.Since(begin))
-	}(time.Now())
+	if rbres.Error = h.Stay(rbreq.Arrival, rbreq.Departure); rbres.Error != nil {
+		return
+	}
+	var u *url.URL
+	if rbreq.PageToken != "" {
+		u, rbres.Error = hspservice.BuildPage(&h, rbreq)
+	} else {
+		u, rbres.Error = h.Params()
+	}
+	if rbres.Error != nil || s.Client == nil {
+		return
+	}
+	q := u.Query()
+	q.Set("customerSessionId", s.sessionID())
+	u.RawQuery = q.Encode()
 
-	//rbres = hspservice.RateBreakdownResponse{rbreq, nil}
+	countAttempt(s.context())
+	span, _ := trace.StartSpan(s.context(), "supplier.ean")
+	span.SetAttribute("http.url", hspservice.RedactURL(u))
+	begin := time.Now()
+	// With a rate sink, each hotel's rates are handed on as it is decoded and
+	// only one hotel's are held; otherwise they are gathered in the response.
+	// A cancellation policy that cannot be parsed is logged; its rate is kept
+	// with the policy's text and no tiers, so its penalty is unknown.
+	var (
+		hotel []hspservice.HotelRate
+		sink  = hspservice.RateSinkFromContext(s.context())
+	)
+	list, err := fetchHotelList(s.context(), s.Client, u, h.Format, func(hs HotelSummary) error {
+		var perr error
+		if sink == nil {
+			rbres.Rates, perr = hs.appendRates(rbres.Rates, h.ArrivalDate)
+		} else {
+			hotel, perr = hs.appendRates(hotel[:0], h.ArrivalDate)
+		}
+		if perr != nil {
+			_ = s.logger().Log("supplier", eanSupplierName, "request_id", hspservice.RequestIDFromContext(s.context()), "err", perr)
+		}
+		for _, r := range hotel {
+			if err := sink(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	span.SetError(err)
+	span.Finish()
+	if s.Trace {
+		rbres.Trace = append(rbres.Trace, hspservice.NewSupplierCall(eanSupplierName, "GET", u, begin, err))
+	}
+	if err != nil {
+		rbres.Rates, rbres.Error = nil, err
+		return
+	}
+	if c, ok := list.NextCursor(); ok {
+		c.Search = rbreq.SearchKey(h.Criteria()...)
+		rbres.NextPageToken = c.Token()
+	}
 	return
 }
 
 const (
-	hotelListPath = "http://api.ean.com/ean-services/rs/hotel/v3/list?"
-	//roomAvailPath = "http://api.ean.com/ean-services/rs/hotel/v3/avail?"
+	eanSupplierName    = "ean"
+	maxNumberOfResults = 200 // EAN rejects hotel list requests above this
+	defaultStayDays    = 14  // stay searched when a request has no dates
 )
 
 // MakeEanSpecs is a convenience function for building static EanSpecs.
 // It is format agnostic and called inside the Ean interface build() method.
-func MakeEanSpecs() EanHspService {
+// The credentials are c's.
+func MakeEanSpecs(c EanCredentials) EanHspService {
 	return EanHspService{
-		cid:                      "",
+		cid:                      c.CID,
 		minorRev:                 "26",
-		apiKey:                   "",
+		apiKey:                   c.APIKey,
 		locale:                   "en_US",
 		customerSessionId:        "theother",
 		customerIpAddress:        "that",
@@ -125,6 +196,16 @@ func MakeEanSpecs() EanHspService {
 	}
 }
 
+// commonParams adds the key-values every EAN API call carries: credentials,
+// API revision and locale.
+func (e EanHspService) commonParams(v url.Values) {
+	v.Add("cid", e.cid)
+	v.Add("minorRev", e.minorRev)
+	v.Add("apiKey", e.apiKey)
+	v.Add("locale", e.locale)
+	v.Add("currencyCode", e.currencyCode)
+}
+
 // HotelAvail is the toplevel struct for buidling EAN requests.
 type HotelAvail struct {
 	XMLName         xml.Name `xml:"HotelListRequest" json:"-"`
@@ -134,13 +215,19 @@ type HotelAvail struct {
 	DepartDate      string   `xml:"departureDate" json:"departureDate"`
 	RoomGroup       `xml:"RoomGroup" json:"-"`
 	NumberOfResults int    `xml:"numberOfResults,omitempty" json:"numberOfResults,omitempty"` // range == [1,200], default == 20 //HOTEL
+	CacheKey        string `xml:"cacheKey,omitempty" json:"cacheKey,omitempty"`               // set by Page for follow up requests
+	CacheLocation   string `xml:"cacheLocation,omitempty" json:"cacheLocation,omitempty"`     // set by Page for follow up requests
+	CurrencyCode    string `xml:"-" json:"-"`                                                 // currency of the rates; empty is EAN's default, USD
 	Format          string `xml:"-" json:"-"`
+	Endpoint        string `xml:"-" json:"-"` // hotel list URL; empty is EAN production's
+
+	Credentials EanCredentials `xml:"-" json:"-"` // cid and apiKey of the call
 	hspservice.Supplier
 }
 
 // HotelIdList contains list of hotel ids in EAN requests.
 type HotelId struct {
-	List []int `xml:"hotelIdList" json:"-"`
+	List []int `xml:"hotelIdList" json:"hotelIdList,omitempty"`
 }
 
 //Address sets fields for XML or JSON
@@ -174,40 +261,91 @@ func (h *HotelAvail) DateRange(days int) {
 	h.ArrivalDate, h.DepartDate = formatter.TimeInStringsOut(formatter.EanDateLayout, a, d)
 }
 
+// Stay sets the arrival and departure dates from YYYY-MM-DD request dates.
+// Without them the stay is the default range from today.
+func (h *HotelAvail) Stay(arrival, departure string) (err error) {
+	if arrival == "" && departure == "" {
+		h.DateRange(defaultStayDays)
+		return nil
+	}
+	if h.ArrivalDate, err = eanDate(arrival); err != nil {
+		return fmt.Errorf("ean: arrival: %v", err)
+	}
+	if h.DepartDate, err = eanDate(departure); err != nil {
+		return fmt.Errorf("ean: departure: %v", err)
+	}
+	return nil
+}
+
+// eanDate converts a YYYY-MM-DD request date to EAN's MM/DD/YYYY.
+func eanDate(s string) (string, error) {
+	t, err := time.Parse(format.StandardDateLayout, s)
+	if err != nil {
+		return "", err
+	}
+	return t.Format(format.EanDateLayout), nil
+}
+
+// Name implements Supplier interface.
+func (h *HotelAvail) Name() string { return eanSupplierName }
+
+// Page implements hspservice.Pager. EAN serves follow up pages from its own cache,
+// keyed by the cacheKey/cacheLocation pair of the previous response.
+func (h *HotelAvail) Page(c hspservice.Cursor) error {
+	h.CacheKey = c.CacheKey
+	h.CacheLocation = c.CacheLocation
+	return nil
+}
+
+// Criteria implements hspservice.Pager: the hotels, destination and rooms
+// searched.
+func (h *HotelAvail) Criteria() []string {
+	c := make([]string, 0, len(h.HotelId.List)+len(h.RoomGroup.Rm)+4)
+	for _, id := range h.HotelId.List {
+		c = append(c, "hotel="+strconv.Itoa(id))
+	}
+	c = append(c, "city="+h.City, "state="+h.StateProvinceCode, "country="+h.CountryCode)
+	for _, r := range h.RoomGroup.Rm {
+		c = append(c, fmt.Sprintf("room=%d,%d,%v", r.NumberOfAdults, r.NumberOfChildren, r.ChildAges))
+	}
+	return c
+}
+
 // encode provides xml/json Marshalling and returns the formatted bytes.
 // XML is the default, as the Ean API has better support for XML.
-func (h *HotelAvail) encode() *bytes.Buffer {
+func (h *HotelAvail) encode() ([]byte, error) {
 	switch h.Format {
-	case "xml":
-		buff, _ := xml.Marshal(h)
-		return bytes.NewBuffer(buff)
 	case "json":
-		buff, _ := json.Marshal(h)
-		return bytes.NewBuffer(buff)
+		return json.Marshal(h)
 	default:
-		buff, _ := xml.Marshal(h)
-		return bytes.NewBuffer(buff)
+		return xml.Marshal(h)
 	}
 }
 
 // Params implements Supplier interface. It creates the url with common key-values as well as
 // query params that will be used for making the Ean request.
 // XML is the default format for query params, as the Ean API has better support for XML.
-// TODO: make the json encoding to URL format cleaner, especially want it to automatically drop empty fields and pick up popuated fields and then format them as '&key=value&'... as of now DO NOT use the JSON configuration because EAN API does not support requests in JSON.
-func (h *HotelAvail) Params() *url.URL {
-	r, _ := url.Parse(hotelListPath)
+// In JSON mode every populated field is sent as its own REST query param, keyed by its json tag.
+func (h *HotelAvail) Params() (*url.URL, error) {
+	endpoint := h.Endpoint
+	if endpoint == "" {
+		endpoint = defaultEanEndpoints.HotelList
+	}
+	r, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
 	v := r.Query()
-	e := MakeEanSpecs()
+	e := MakeEanSpecs(h.Credentials)
+	if h.CurrencyCode != "" {
+		e.currencyCode = h.CurrencyCode
+	}
 
-	enc := h.encode()
-	//log.Printf("Format: %q, Supplier: %q Adults: %v Rooms: %v Arrival %q Depart %q Domain: %q\n", h.Format, h.Supplier, h.RoomGroup.Rm[0].NumberOfAdults, len(h.RoomGroup.Rm), h.ArrivalDate, h.DepartDate, r)
-	enc_to_byte, _ := ioutil.ReadAll(enc)
+	if h.NumberOfResults > maxNumberOfResults {
+		h.NumberOfResults = maxNumberOfResults
+	}
 
-	v.Add("cid", e.cid)
-	v.Add("minorRev", e.minorRev)
-	v.Add("apiKey", e.apiKey)
-	v.Add("locale", e.locale)
-	v.Add("currencyCode", e.currencyCode)
+	e.commonParams(v)
 	v.Add("supplierCacheTolerance", e.supplierCacheTolerance)
 	v.Add("includeHotelFeeBreakdown", e.includeHotelFeeBreakdown)
 	v.Add("supplierType", e.supplierType)
@@ -215,22 +353,15 @@ func (h *HotelAvail) Params() *url.URL {
 	v.Add("includeDetails", e.includeDetails)
 	v.Add("options", e.options)
 	switch h.Format {
-	case "xml":
-		v.Add("xml", string(enc_to_byte))
 	case "json":
-		v.Add("arrivalDate", h.ArrivalDate)
-		v.Add("departureDate", h.DepartDate)
-		hotelList := []string{}
-		for i := 0; i < len(h.HotelId.List); i++ {
-			hotelList = append(hotelList, strconv.Itoa(h.HotelId.List[i]))
-		}
-		v.Add("hotelIdList", strings.Join(hotelList, ","))
-		for i := 0; i < len(h.RoomGroup.Rm); i++ {
-			v.Add(("room" + strconv.Itoa(i+1)), strconv.Itoa(h.RoomGroup.Rm[i].NumberOfAdults))
-		}
+		queryValues(v, h)
 	default:
-		v.Add("xml", string(enc_to_byte))
+		enc, err := h.encode()
+		if err != nil {
+			return nil, err
+		}
+		v.Add("xml", string(enc))
 	}
 	r.RawQuery = v.Encode()
-	return r
+	return r, nil
 }
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/jbowles/hotel_supply_platform/eanmock"
+	"github.com/jbowles/hotel_supply_platform/hspservice"
+)
+
+// newMockEan returns an EanHspService talking format to a mock EAN server
+// configured by c, and a func that shuts the server down.
+func newMockEan(t *testing.T, format string, c eanmock.Config) (EanHspService, func()) {
+	return newMockEanFixtures(t, format, eanmock.DefaultFixtures(), c)
+}
+
+// newMockEanFixtures is newMockEan serving the fixtures f.
+func newMockEanFixtures(t *testing.T, format string, f eanmock.Fixtures, c eanmock.Config) (EanHspService, func()) {
+	srv := httptest.NewServer(eanmock.New(f, c))
+	cfg := eanEnvironments["mock"]
+	cfg.BaseURL = srv.URL
+	endpoints, err := cfg.Endpoints()
+	if err != nil {
+		srv.Close()
+		t.Fatal(err)
+	}
+	return EanHspService{Client: &http.Client{}, Endpoints: endpoints, Format: format}, srv.Close
+}
+
+// allRates follows the page tokens of svc from the first page and returns the
+// rates of every page.
+func allRates(t *testing.T, svc EanHspService, rbreq hspservice.RateBreakdownRequest) []hspservice.HotelRate {
+	var rates []hspservice.HotelRate
+	for page := 1; ; page++ {
+		res := svc.RateBreakdown(rbreq)
+		if res.Error != nil {
+			t.Fatalf("%s page %d: %v", svc.Format, page, res.Error)
+		}
+		rates = append(rates, res.Rates...)
+		if res.NextPageToken == "" {
+			return rates
+		}
+		rbreq.PageToken = res.NextPageToken
+	}
+}
+
+func TestRateBreakdownFormatsAgree(t *testing.T) {
+	rbreq := hspservice.RateBreakdownRequest{Currency: "USD"}
+	for _, pageSize := range []int{1, 20} {
+		xmlSvc, done := newMockEan(t, "xml", eanmock.Config{PageSize: pageSize})
+		xmlRates := allRates(t, xmlSvc, rbreq)
+		done()
+		jsonSvc, done := newMockEan(t, "json", eanmock.Config{PageSize: pageSize})
+		jsonRates := allRates(t, jsonSvc, rbreq)
+		done()
+
+		if len(xmlRates) == 0 {
+			t.Fatalf("page size %d: no rates", pageSize)
+		}
+		if !reflect.DeepEqual(xmlRates, jsonRates) {
+			t.Errorf("page size %d: rates differ\nxml:  %+v\njson: %+v", pageSize, xmlRates, jsonRates)
+		}
+	}
+}
+
+func TestRateBreakdownSearch(t *testing.T) {
+	rec := &recorder{body: "<HotelListResponse><HotelList size=\"0\"></HotelList></HotelListResponse>"}
+	svc := EanHspService{Client: &http.Client{Transport: rec}}
+	res := svc.RateBreakdown(hspservice.RateBreakdownRequest{Arrival: "2026-11-02", Departure: "2026-11-05", Currency: "EUR"})
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	req, _ := rec.only(t)
+	q := req.URL.Query()
+	if c := q.Get("currencyCode"); c != "EUR" {
+		t.Errorf("currencyCode %q, want EUR", c)
+	}
+	var h HotelAvail
+	if err := xml.Unmarshal([]byte(q.Get("xml")), &h); err != nil {
+		t.Fatal(err)
+	}
+	if h.ArrivalDate != "11/02/2026" || h.DepartDate != "11/05/2026" {
+		t.Errorf("stay %s to %s, want 11/02/2026 to 11/05/2026", h.ArrivalDate, h.DepartDate)
+	}
+
+	res = svc.RateBreakdown(hspservice.RateBreakdownRequest{Arrival: "11/02/2026", Departure: "2026-11-05"})
+	if res.Error == nil {
+		t.Error("no error for an arrival date not in YYYY-MM-DD")
+	}
+}
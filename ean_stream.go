@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// streamHotelList decodes an XML hotel list response from r token by token,
+// calling hotel with each hotel summary as soon as it is decoded, so hotels
+// are handled while the rest of the body is still arriving and a page is
+// never held whole in memory. The returned response has all but the hotels.
+// An error from hotel stops decoding. A returned EanWsError is surfaced as
+// the error, as by decodeEan; hotel may have been called before it.
+func streamHotelList(r io.Reader, hotel func(HotelSummary) error) (list HotelListResponse, err error) {
+	d := xml.NewDecoder(r)
+	root, err := nextStart(d)
+	if err != nil {
+		return list, err
+	}
+	if root.Name.Local != "HotelListResponse" {
+		return list, fmt.Errorf("ean: response has no HotelListResponse, got %s", root.Name.Local)
+	}
+	list.XMLName = root.Name
+	for {
+		se, err := nextChild(d)
+		if err != nil {
+			return list, err
+		}
+		if se == nil {
+			break
+		}
+		switch se.Name.Local {
+		case "customerSessionId":
+			err = d.DecodeElement(&list.CustomerSessionId, se)
+		case "moreResultsAvailable":
+			err = d.DecodeElement(&list.MoreResultsAvailable, se)
+		case "cacheKey":
+			err = d.DecodeElement(&list.CacheKey, se)
+		case "cacheLocation":
+			err = d.DecodeElement(&list.CacheLocation, se)
+		case "EanWsError":
+			list.EanWsError = new(EanWsError)
+			err = d.DecodeElement(list.EanWsError, se)
+		case "HotelList":
+			err = streamHotels(d, se, &list.HotelList, hotel)
+		default:
+			err = d.Skip()
+		}
+		if err != nil {
+			return list, err
+		}
+	}
+	if list.EanWsError != nil {
+		return list, list.EanWsError
+	}
+	return list, nil
+}
+
+// streamHotels reads the HotelList element start, its attributes into hl and
+// its hotel summaries into hotel.
+func streamHotels(d *xml.Decoder, start *xml.StartElement, hl *HotelList, hotel func(HotelSummary) error) error {
+	for _, a := range start.Attr {
+		var n *int
+		switch a.Name.Local {
+		case "size":
+			n = &hl.Size
+		case "activePropertyCount":
+			n = &hl.ActivePropertyCount
+		default:
+			continue
+		}
+		v, err := strconv.Atoi(strings.TrimSpace(a.Value))
+		if err != nil {
+			return fmt.Errorf("ean: HotelList %s: %v", a.Name.Local, err)
+		}
+		*n = v
+	}
+	for {
+		se, err := nextChild(d)
+		if err != nil || se == nil {
+			return err
+		}
+		if se.Name.Local != "HotelSummary" {
+			if err := d.Skip(); err != nil {
+				return err
+			}
+			continue
+		}
+		var h HotelSummary
+		if err := d.DecodeElement(&h, se); err != nil {
+			return err
+		}
+		if err := hotel(h); err != nil {
+			return err
+		}
+	}
+}
+
+// nextStart returns the first start element of the document, the root.
+func nextStart(d *xml.Decoder) (*xml.StartElement, error) {
+	for {
+		t, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		if se, ok := t.(xml.StartElement); ok {
+			return &se, nil
+		}
+	}
+}
+
+// nextChild returns the next child element of the element being read, or nil
+// at its end.
+func nextChild(d *xml.Decoder) (*xml.StartElement, error) {
+	for {
+		t, err := d.Token()
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			return &t, nil
+		case xml.EndElement:
+			return nil, nil
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/log"
+	"github.com/jbowles/hotel_supply_platform/hspservice"
+)
+
+// The recorded hotel list page of 200 hotels, 15 room rates each.
+const (
+	hotelListPayloadFile  = "testdata/hotel_list_200.xml.gz"
+	hotelListPayloadRates = 3000
+	hotelListArrival      = "11/02/2026"
+)
+
+// hotelListPayload returns the recorded hotel list page, uncompressed.
+func hotelListPayload(tb testing.TB) []byte {
+	f, err := os.Open(hotelListPayloadFile)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	payload, err := ioutil.ReadAll(zr)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return payload
+}
+
+// pipeTransport answers a request with what is written to its pipe.
+type pipeTransport struct{ body *io.PipeReader }
+
+func (t pipeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/xml"}},
+		Body:       t.body,
+		Request:    req,
+	}, nil
+}
+
+// gatedPayload returns a transport sending the recorded page up to its first
+// hotel, then the rest once released is closed. Without it, the rest is never
+// sent and the response fails.
+func gatedPayload(t *testing.T, released <-chan struct{}) http.RoundTripper {
+	payload := hotelListPayload(t)
+	first := bytes.Index(payload, []byte("</HotelSummary>")) + len("</HotelSummary>")
+	pr, pw := io.Pipe()
+	go func() {
+		pw.Write(payload[:first])
+		select {
+		case <-released:
+			pw.Write(payload[first:])
+			pw.Close()
+		case <-time.After(5 * time.Second):
+			pw.CloseWithError(errors.New("no rate handed on before the rest of the page"))
+		}
+	}()
+	return pipeTransport{pr}
+}
+
+// releaseOnce returns a func closing c the first time it is called.
+func releaseOnce(c chan struct{}) func() {
+	return func() {
+		select {
+		case <-c:
+		default:
+			close(c)
+		}
+	}
+}
+
+func TestRateBreakdownRateSink(t *testing.T) {
+	released := make(chan struct{})
+	release := releaseOnce(released)
+	svc := EanHspService{Client: &http.Client{Transport: gatedPayload(t, released)}}
+
+	var rates int
+	ctx := hspservice.NewRateSinkContext(context.Background(), func(hspservice.HotelRate) error {
+		rates++
+		release()
+		return nil
+	})
+	res := svc.WithContext(ctx).RateBreakdown(hspservice.RateBreakdownRequest{})
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	if rates != hotelListPayloadRates {
+		t.Errorf("sink got %d rates, want %d", rates, hotelListPayloadRates)
+	}
+	if len(res.Rates) != 0 {
+		t.Errorf("response holds %d rates, want them all handed to the sink", len(res.Rates))
+	}
+}
+
+func TestRateBreakdownRateSinkError(t *testing.T) {
+	released := make(chan struct{})
+	defer close(released)
+	svc := EanHspService{Client: &http.Client{Transport: gatedPayload(t, released)}}
+	stop := errors.New("enough")
+	ctx := hspservice.NewRateSinkContext(context.Background(), func(hspservice.HotelRate) error { return stop })
+	if res := svc.WithContext(ctx).RateBreakdown(hspservice.RateBreakdownRequest{}); res.Error != stop {
+		t.Errorf("error %v, want the sink's", res.Error)
+	}
+}
+
+func TestRateStreamHandler(t *testing.T) {
+	released := make(chan struct{})
+	release := releaseOnce(released)
+	svc := EanHspService{Client: &http.Client{Transport: gatedPayload(t, released)}}
+	srv := httptest.NewServer(newRateStreamHandler(context.Background(), "ean.rate_breakdown.stream", svc, nil, log.NewNopLogger()))
+	defer srv.Close()
+
+	req, _ := http.NewRequest("GET", srv.URL+"?currency=USD", nil)
+	req.Header.Set(hspservice.RequestIDHeader, "req-stream-1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != hspservice.RateStreamMediaType {
+		t.Errorf("Content-Type %q, want %s", ct, hspservice.RateStreamMediaType)
+	}
+	if id := resp.Header.Get(hspservice.RequestIDHeader); id != "req-stream-1" {
+		t.Errorf("request id %q, want the caller's", id)
+	}
+
+	var rates int
+	res, err := hspservice.DecodeRateStream(resp.Body, func(r hspservice.HotelRate) error {
+		if r.Supplier != eanSupplierName || r.HotelId == "" {
+			t.Errorf("rate %+v, want an EAN one", r)
+		}
+		rates++
+		release()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Error != nil || res.Request.Currency != "USD" || len(res.Rates) != 0 {
+		t.Errorf("last line %+v, want the request with no error or rates", res)
+	}
+	if rates != hotelListPayloadRates {
+		t.Errorf("streamed %d rates, want %d", rates, hotelListPayloadRates)
+	}
+}
+
+func TestRateStreamHandlerBadRequest(t *testing.T) {
+	srv := httptest.NewServer(newRateStreamHandler(context.Background(), "stream", HspService{}, nil, log.NewNopLogger()))
+	defer srv.Close()
+	resp, err := http.Post(srv.URL, "text/csv", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnsupportedMediaType {
+		t.Errorf("status %d, want %d", resp.StatusCode, http.StatusUnsupportedMediaType)
+	}
+}
+
+// BenchmarkStreamHotelList decodes the recorded page as RateBreakdown does
+// with a rate sink: hotel by hotel, holding one hotel's rates at a time.
+func BenchmarkStreamHotelList(b *testing.B) {
+	payload := hotelListPayload(b)
+	b.SetBytes(int64(len(payload)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	var hotel []hspservice.HotelRate
+	for i := 0; i < b.N; i++ {
+		rates := 0
+		_, err := streamHotelList(bytes.NewReader(payload), func(h HotelSummary) error {
+			hotel, _ = h.appendRates(hotel[:0], hotelListArrival)
+			rates += len(hotel)
+			return nil
+		})
+		if err != nil || rates != hotelListPayloadRates {
+			b.Fatalf("%d rates, error %v", rates, err)
+		}
+	}
+}
+
+// BenchmarkDecodeHotelList decodes the recorded page whole, then its rates.
+func BenchmarkDecodeHotelList(b *testing.B) {
+	payload := hotelListPayload(b)
+	b.SetBytes(int64(len(payload)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		list, err := decodeHotelList("xml", bytes.NewReader(payload))
+		if err != nil {
+			b.Fatal(err)
+		}
+		if rates, _ := list.Rates(hotelListArrival); len(rates) != hotelListPayloadRates {
+			b.Fatalf("%d rates", len(rates))
+		}
+	}
+}
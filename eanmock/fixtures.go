@@ -0,0 +1,113 @@
+package eanmock
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Fixtures are the hotels a Server sells.
+type Fixtures struct {
+	Hotels []Hotel `json:"hotels"`
+}
+
+// Hotel is a fixture hotel and the rooms it sells.
+type Hotel struct {
+	Id          int    `json:"id"`
+	Name        string `json:"name"`
+	City        string `json:"city"`
+	CountryCode string `json:"country_code"`
+	Currency    string `json:"currency"`
+	Rooms       []Room `json:"rooms"`
+}
+
+// Room is a room type and rate of a fixture hotel. Rate is the total of a
+// stay, as decimal text; a booking must agree to it exactly. SoldOut rooms
+// are listed but cannot be booked. CancelPolicies are the penalty windows
+// sent with the CancellationPolicy text.
+type Room struct {
+	RoomTypeCode       int            `json:"room_type_code"`
+	RateCode           int            `json:"rate_code"`
+	Description        string         `json:"description"`
+	Rate               string         `json:"rate"`
+	NonRefundable      bool           `json:"non_refundable,omitempty"`
+	CancellationPolicy string         `json:"cancellation_policy,omitempty"`
+	CancelPolicies     []CancelPolicy `json:"cancel_policies,omitempty"`
+	SoldOut            bool           `json:"sold_out,omitempty"`
+}
+
+// CancelPolicy is a penalty window of a room, as EAN's CancelPolicyInfo: the
+// penalty starts StartWindowHours before CancelTime (HH:MM:SS) on the arrival
+// day, in the hotel's TimeZone, and is NightCount nights, Amount in the
+// hotel's currency or Percent of the total.
+type CancelPolicy struct {
+	CancelTime       string `json:"cancel_time"`
+	StartWindowHours int    `json:"start_window_hours"`
+	NightCount       int    `json:"night_count,omitempty"`
+	Amount           string `json:"amount,omitempty"`
+	Percent          string `json:"percent,omitempty"`
+	TimeZone         string `json:"time_zone"`
+}
+
+// rateKey is the rateKey of room r of hotel h, which booking requests quote.
+func rateKey(h Hotel, r Room) string {
+	return fmt.Sprintf("mock-%d-%d-%d", h.Id, r.RoomTypeCode, r.RateCode)
+}
+
+// LoadFixtures reads fixtures in JSON from r.
+func LoadFixtures(r io.Reader) (Fixtures, error) {
+	var f Fixtures
+	err := json.NewDecoder(r).Decode(&f)
+	return f, err
+}
+
+// OpenFixtures reads the JSON fixtures in the file path.
+func OpenFixtures(path string) (Fixtures, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Fixtures{}, err
+	}
+	defer f.Close()
+	return LoadFixtures(f)
+}
+
+// DefaultFixtures returns a few hotels, including the ones hsp asks EAN for,
+// with a room of each kind: refundable, non refundable and sold out.
+func DefaultFixtures() Fixtures {
+	return Fixtures{Hotels: []Hotel{
+		{
+			Id: 225697, Name: "Mock Harbour Hotel", City: "Seattle", CountryCode: "US", Currency: "USD",
+			Rooms: []Room{
+				{RoomTypeCode: 200, RateCode: 2001, Description: "Queen Room", Rate: "289.50",
+					CancellationPolicy: "Free cancellation until 24 hours before arrival. Later cancellations are charged one night, no shows the full stay.",
+					CancelPolicies: []CancelPolicy{
+						{CancelTime: "18:00:00", StartWindowHours: 24, NightCount: 1, TimeZone: "(GMT-08:00) Pacific Time (US & Canada)"},
+						{CancelTime: "18:00:00", StartWindowHours: 0, Percent: "100", TimeZone: "(GMT-08:00) Pacific Time (US & Canada)"},
+					}},
+				{RoomTypeCode: 201, RateCode: 2011, Description: "King Room, Non Refundable", Rate: "259.00", NonRefundable: true},
+				{RoomTypeCode: 202, RateCode: 2021, Description: "Suite", Rate: "640.00", SoldOut: true},
+			},
+		},
+		{
+			Id: 116908, Name: "Mock Riverside Inn", City: "Portland", CountryCode: "US", Currency: "USD",
+			Rooms: []Room{
+				{RoomTypeCode: 300, RateCode: 3001, Description: "Double Room", Rate: "172.20",
+					CancellationPolicy: "Cancellations within 48 hours of arrival are charged 50.00 USD.",
+					CancelPolicies: []CancelPolicy{
+						{CancelTime: "15:00:00", StartWindowHours: 48, Amount: "50.00", TimeZone: "(GMT-08:00) Pacific Time (US & Canada)"},
+					}},
+			},
+		},
+		{
+			Id: 401234, Name: "Mock Canal House", City: "Amsterdam", CountryCode: "NL", Currency: "EUR",
+			Rooms: []Room{
+				{RoomTypeCode: 400, RateCode: 4001, Description: "Canal View Room", Rate: "410.00",
+					CancellationPolicy: "Cancellations from 12:00 the day before arrival are charged 50% of the stay.",
+					CancelPolicies: []CancelPolicy{
+						{CancelTime: "12:00:00", StartWindowHours: 24, Percent: "50", TimeZone: "(GMT+01:00) Amsterdam, Berlin, Bern, Rome, Stockholm, Vienna"},
+					}},
+			},
+		},
+	}}
+}
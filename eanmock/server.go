@@ -0,0 +1,537 @@
+// Package eanmock is a stand-in for the EAN hotel API, for local development
+// and integration tests without EAN credentials or network.
+//
+// A Server serves the hotel list, room availability, reservation, itinerary
+// and cancellation endpoints from Fixtures, in XML or, when the request
+// accepts it, EAN's JSON. It pages hotel lists through cacheKey and
+// cacheLocation as EAN does, answers booking problems with EanWsErrors
+// (SOLD_OUT for sold out rooms, PRICE_MISMATCH when the chargeable rate is not
+// the fixture rate), refuses to book an affiliateConfirmationId twice and
+// looks itineraries up by it, and can add latency, force an error category on
+// an endpoint and answer over its rate limit with EAN's 403.
+//
+// Point hsp at a Server with -ean.env mock or -ean.url, or serve it from an
+// httptest.Server in tests.
+package eanmock
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/juju/ratelimit"
+)
+
+// PathPrefix is the path of the EAN hotel API; the endpoints are below it.
+const PathPrefix = "/ean-services/rs/hotel/v3/"
+
+// The endpoints, by the last element of their path.
+const (
+	HotelList        = "list"
+	RoomAvailability = "avail"
+	Reservation      = "res"
+	Itinerary        = "itin"
+	Cancellation     = "cancel"
+)
+
+// The EanWsError categories the Server answers booking problems with.
+const (
+	SoldOut        = "SOLD_OUT"
+	PriceMismatch  = "PRICE_MISMATCH"
+	DataValidation = "DATA_VALIDATION"
+)
+
+// Config tunes a Server.
+type Config struct {
+	// Latency is added to every response, plus up to Jitter at random.
+	Latency time.Duration
+	Jitter  time.Duration
+
+	// QPS limits the requests served a second; over it the Server answers
+	// 403 Developer Over Qps, as EAN does. Zero is unlimited.
+	QPS int
+
+	// PageSize is the number of hotels in a hotel list page when the
+	// request does not ask for fewer. The default is 20.
+	PageSize int
+
+	// Fail forces an EanWsError of the given category on every request to
+	// an endpoint, such as {"res": "SOLD_OUT"}.
+	Fail map[string]string
+}
+
+// Server is a mock EAN hotel API. It is an http.Handler.
+type Server struct {
+	fixtures Fixtures
+	config   Config
+	bucket   *ratelimit.Bucket
+
+	mtx         sync.Mutex
+	nextId      int64
+	itineraries map[int64]*itineraryInfo
+	itinEmails  map[int64]string
+	byAffiliate map[string]*reservationResponse
+}
+
+// New returns a Server selling the hotels of f.
+func New(f Fixtures, c Config) *Server {
+	if c.PageSize <= 0 {
+		c.PageSize = 20
+	}
+	s := &Server{
+		fixtures:    f,
+		config:      c,
+		nextId:      1000,
+		itineraries: map[int64]*itineraryInfo{},
+		itinEmails:  map[int64]string{},
+		byAffiliate: map[string]*reservationResponse{},
+	}
+	if c.QPS > 0 {
+		s.bucket = ratelimit.NewBucketWithRate(float64(c.QPS), int64(c.QPS))
+	}
+	return s
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.URL.Path, PathPrefix) {
+		http.NotFound(w, r)
+		return
+	}
+	if s.bucket != nil && s.bucket.TakeAvailable(1) == 0 {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusForbidden)
+		io.WriteString(w, "<h1>Developer Over Qps</h1>\n")
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.delay()
+
+	var (
+		name     = strings.TrimPrefix(r.URL.Path, PathPrefix)
+		session  = r.Form.Get("customerSessionId")
+		root     string
+		response interface{}
+	)
+	switch name {
+	case HotelList:
+		root, response = "HotelListResponse", s.hotelList(r, session)
+	case RoomAvailability:
+		root, response = "HotelRoomAvailabilityResponse", s.roomAvailability(r, session)
+	case Reservation:
+		if r.Method != "POST" {
+			http.Error(w, "reservations must be POSTed", http.StatusMethodNotAllowed)
+			return
+		}
+		root, response = "HotelRoomReservationResponse", s.reserve(r, session)
+	case Itinerary:
+		root, response = "HotelItineraryResponse", s.itinerary(r, session)
+	case Cancellation:
+		root, response = "HotelRoomCancellationResponse", s.cancel(r, session)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	write(w, r, root, response)
+}
+
+func (s *Server) delay() {
+	d := s.config.Latency
+	if s.config.Jitter > 0 {
+		d += time.Duration(rand.Int63n(int64(s.config.Jitter)))
+	}
+	time.Sleep(d)
+}
+
+// forced returns the error forced on endpoint by Config.Fail, if any.
+func (s *Server) forced(endpoint string) *EanWsError {
+	if category, ok := s.config.Fail[endpoint]; ok {
+		return wsError(category, "")
+	}
+	return nil
+}
+
+// wsError returns an EanWsError of category, with message or a default one.
+func wsError(category, message string) *EanWsError {
+	handling := "RECOVERABLE"
+	switch category {
+	case SoldOut:
+		handling = "UNRECOVERABLE"
+		if message == "" {
+			message = "The room is no longer available."
+		}
+	case PriceMismatch:
+		if message == "" {
+			message = "The price of the room has changed."
+		}
+	case DataValidation:
+		handling = "UNRECOVERABLE"
+	}
+	if message == "" {
+		message = "Mock " + category + " error."
+	}
+	return &EanWsError{
+		ItineraryId:         -1,
+		Handling:            handling,
+		Category:            category,
+		PresentationMessage: message,
+		VerboseMessage:      message,
+	}
+}
+
+// write writes response as the document root, in JSON when the request
+// accepts it and XML otherwise.
+func write(w http.ResponseWriter, r *http.Request, root string, response interface{}) {
+	if strings.Contains(r.Header.Get("Accept"), "json") {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{root: response})
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	io.WriteString(w, xml.Header)
+	xml.NewEncoder(w).Encode(response)
+}
+
+// decode decodes the xml parameter of r into v; it reports false when there
+// is none.
+func decode(r *http.Request, v interface{}) (bool, error) {
+	doc := r.Form.Get("xml")
+	if doc == "" {
+		return false, nil
+	}
+	return true, xml.Unmarshal([]byte(doc), v)
+}
+
+// readHotelListRequest reads a hotel list request from the xml parameter,
+// or in JSON mode from the REST parameters.
+func readHotelListRequest(r *http.Request) (rq hotelListRequest, err error) {
+	if ok, err := decode(r, &rq); ok || err != nil {
+		return rq, err
+	}
+	for _, id := range strings.Split(r.Form.Get("hotelIdList"), ",") {
+		if id == "" {
+			continue
+		}
+		n, err := strconv.Atoi(id)
+		if err != nil {
+			return rq, fmt.Errorf("invalid hotelIdList %q", id)
+		}
+		rq.HotelIds = append(rq.HotelIds, n)
+	}
+	rq.ArrivalDate = r.Form.Get("arrivalDate")
+	rq.DepartureDate = r.Form.Get("departureDate")
+	rq.NumberOfResults, _ = strconv.Atoi(r.Form.Get("numberOfResults"))
+	rq.CacheKey = r.Form.Get("cacheKey")
+	rq.CacheLocation = r.Form.Get("cacheLocation")
+	return rq, nil
+}
+
+const cacheLocation = "mock"
+
+func (s *Server) hotelList(r *http.Request, session string) *hotelListResponse {
+	rs := &hotelListResponse{CustomerSessionId: session}
+	if rs.EanWsError = s.forced(HotelList); rs.EanWsError != nil {
+		return rs
+	}
+	rq, err := readHotelListRequest(r)
+	if err != nil {
+		rs.EanWsError = wsError(DataValidation, err.Error())
+		return rs
+	}
+
+	var hotels []Hotel
+	for _, h := range s.fixtures.Hotels {
+		if len(rq.HotelIds) == 0 || containsInt(rq.HotelIds, h.Id) {
+			hotels = append(hotels, h)
+		}
+	}
+	offset := 0
+	if rq.CacheKey != "" {
+		if rq.CacheLocation != cacheLocation {
+			rs.EanWsError = wsError(DataValidation, "Unknown cacheLocation.")
+			return rs
+		}
+		if _, err := fmt.Sscanf(rq.CacheKey, "page-%d", &offset); err != nil || offset < 0 || offset > len(hotels) {
+			rs.EanWsError = wsError(DataValidation, "Unknown cacheKey.")
+			return rs
+		}
+	}
+	size := s.config.PageSize
+	if rq.NumberOfResults > 0 && rq.NumberOfResults < size {
+		size = rq.NumberOfResults
+	}
+	end := offset + size
+	if end >= len(hotels) {
+		end = len(hotels)
+	} else {
+		rs.MoreResultsAvailable = true
+		rs.CacheKey, rs.CacheLocation = fmt.Sprintf("page-%d", end), cacheLocation
+	}
+
+	rs.HotelList = &hotelList{ActivePropertyCount: len(hotels)}
+	for _, h := range hotels[offset:end] {
+		rs.HotelList.Hotels = append(rs.HotelList.Hotels, summary(h))
+	}
+	rs.HotelList.Size = len(rs.HotelList.Hotels)
+	return rs
+}
+
+// summary returns the hotel list entry of h, with its room rate details.
+func summary(h Hotel) hotelSummary {
+	hs := hotelSummary{
+		HotelId:          h.Id,
+		Name:             h.Name,
+		City:             h.City,
+		CountryCode:      h.CountryCode,
+		RateCurrencyCode: h.Currency,
+	}
+	var low, high float64
+	for i, room := range h.Rooms {
+		rate, _ := strconv.ParseFloat(room.Rate, 64)
+		if i == 0 || rate < low {
+			low, hs.LowRate = rate, room.Rate
+		}
+		if i == 0 || rate > high {
+			high, hs.HighRate = rate, room.Rate
+		}
+		d := roomRateDetails{
+			RoomTypeCode:    room.RoomTypeCode,
+			RateCode:        room.RateCode,
+			RoomDescription: room.Description,
+		}
+		d.RateInfos.List = []rateInfo{newRateInfo(h, room)}
+		hs.RoomRateDetails.List = append(hs.RoomRateDetails.List, d)
+	}
+	return hs
+}
+
+func newRateInfo(h Hotel, room Room) rateInfo {
+	ri := rateInfo{
+		NonRefundable:      room.NonRefundable,
+		CancellationPolicy: room.CancellationPolicy,
+		ChargeableRateInfo: chargeableRateInfo{Total: room.Rate, CurrencyCode: h.Currency},
+	}
+	ri.RoomGroup.Rooms = []rateRoom{{NumberOfAdults: 2, RateKey: rateKey(h, room)}}
+	if len(room.CancelPolicies) > 0 {
+		ri.CancelPolicyInfoList = new(struct {
+			List []cancelPolicyInfo `xml:"CancelPolicyInfo" json:"CancelPolicyInfo"`
+		})
+		for _, c := range room.CancelPolicies {
+			ri.CancelPolicyInfoList.List = append(ri.CancelPolicyInfoList.List, cancelPolicyInfo{
+				CancelTime:          c.CancelTime,
+				StartWindowHours:    c.StartWindowHours,
+				NightCount:          c.NightCount,
+				Amount:              c.Amount,
+				Percent:             c.Percent,
+				CurrencyCode:        h.Currency,
+				TimeZoneDescription: c.TimeZone,
+			})
+		}
+	}
+	return ri
+}
+
+func (s *Server) roomAvailability(r *http.Request, session string) *roomAvailabilityResponse {
+	rs := &roomAvailabilityResponse{CustomerSessionId: session}
+	if rs.EanWsError = s.forced(RoomAvailability); rs.EanWsError != nil {
+		return rs
+	}
+	var rq roomAvailabilityRequest
+	if ok, err := decode(r, &rq); !ok || err != nil {
+		rq.HotelId, _ = strconv.Atoi(r.Form.Get("hotelId"))
+		rq.ArrivalDate, rq.DepartureDate = r.Form.Get("arrivalDate"), r.Form.Get("departureDate")
+	}
+	h, ok := s.hotel(rq.HotelId)
+	if !ok {
+		rs.EanWsError = wsError(DataValidation, "Unknown hotelId.")
+		return rs
+	}
+	rs.HotelId, rs.ArrivalDate, rs.DepartureDate = h.Id, rq.ArrivalDate, rq.DepartureDate
+	for _, room := range h.Rooms {
+		if room.SoldOut {
+			continue
+		}
+		rr := roomResponse{RoomTypeCode: room.RoomTypeCode, RateCode: room.RateCode, RateDescription: room.Description}
+		rr.RateInfos.List = []rateInfo{newRateInfo(h, room)}
+		rs.Rooms = append(rs.Rooms, rr)
+	}
+	if len(rs.Rooms) == 0 {
+		rs.EanWsError = wsError(SoldOut, "No rooms are available for the dates requested.")
+	}
+	rs.Size = len(rs.Rooms)
+	return rs
+}
+
+func (s *Server) reserve(r *http.Request, session string) *reservationResponse {
+	rs := &reservationResponse{CustomerSessionId: session}
+	if rs.EanWsError = s.forced(Reservation); rs.EanWsError != nil {
+		return rs
+	}
+	var rq reservationRequest
+	if ok, err := decode(r, &rq); !ok || err != nil {
+		rs.EanWsError = wsError(DataValidation, "Missing or invalid xml parameter.")
+		return rs
+	}
+	h, room, ok := s.room(rq)
+	switch {
+	case !ok:
+		rs.EanWsError = wsError(DataValidation, "Unknown hotel, room or rateKey.")
+		return rs
+	case room.SoldOut:
+		rs.EanWsError = wsError(SoldOut, "")
+		return rs
+	case !sameAmount(rq.ChargeableRate, room.Rate):
+		rs.EanWsError = wsError(PriceMismatch, fmt.Sprintf("The rate is now %s %s.", room.Rate, h.Currency))
+		return rs
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if _, ok := s.byAffiliate[rq.AffiliateConfirmationId]; ok && rq.AffiliateConfirmationId != "" {
+		rs.EanWsError = wsError(DataValidation, "An itinerary with this affiliateConfirmationId already exists.")
+		return rs
+	}
+	s.nextId++
+	id := s.nextId
+	it := &itineraryInfo{ItineraryId: id}
+	rooms := len(rq.Rooms)
+	if rooms == 0 {
+		rooms = 1
+	}
+	for i := 0; i < rooms; i++ {
+		n := id*10 + int64(i) + 1
+		it.Confirmations = append(it.Confirmations, confirmation{
+			ConfirmationNumber: n,
+			Status:             "CF",
+			HotelId:            h.Id,
+			ArrivalDate:        rq.ArrivalDate,
+			DepartureDate:      rq.DepartureDate,
+		})
+		rs.ConfirmationNumbers = append(rs.ConfirmationNumbers, n)
+	}
+	s.itineraries[id] = it
+	s.itinEmails[id] = rq.Email
+	rs.ItineraryId = id
+	rs.ProcessedWithConfirmation = true
+	rs.ReservationStatusCode = "CF"
+	rs.NonRefundable = room.NonRefundable
+	if rq.AffiliateConfirmationId != "" {
+		stored := *rs
+		s.byAffiliate[rq.AffiliateConfirmationId] = &stored
+	}
+	return rs
+}
+
+func (s *Server) itinerary(r *http.Request, session string) *itineraryResponse {
+	rs := &itineraryResponse{CustomerSessionId: session}
+	if rs.EanWsError = s.forced(Itinerary); rs.EanWsError != nil {
+		return rs
+	}
+	var rq itineraryRequest
+	if ok, err := decode(r, &rq); !ok || err != nil {
+		rs.EanWsError = wsError(DataValidation, "Missing or invalid xml parameter.")
+		return rs
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	id := rq.ItineraryId
+	if res, ok := s.byAffiliate[rq.AffiliateConfirmationId]; ok && rq.AffiliateConfirmationId != "" {
+		id = res.ItineraryId
+	}
+	if it, ok := s.itineraries[id]; ok && s.emailMatches(id, rq.Email) {
+		rs.Itineraries = []itineraryInfo{*it}
+	}
+	return rs
+}
+
+func (s *Server) cancel(r *http.Request, session string) *cancellationResponse {
+	rs := &cancellationResponse{CustomerSessionId: session}
+	if rs.EanWsError = s.forced(Cancellation); rs.EanWsError != nil {
+		return rs
+	}
+	var rq cancellationRequest
+	if ok, err := decode(r, &rq); !ok || err != nil {
+		rs.EanWsError = wsError(DataValidation, "Missing or invalid xml parameter.")
+		return rs
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	it, ok := s.itineraries[rq.ItineraryId]
+	if !ok || !s.emailMatches(rq.ItineraryId, rq.Email) {
+		rs.EanWsError = wsError(DataValidation, "Unknown itinerary.")
+		return rs
+	}
+	cancelled := false
+	for i, c := range it.Confirmations {
+		if rq.ConfirmationNumber == 0 || c.ConfirmationNumber == rq.ConfirmationNumber {
+			it.Confirmations[i].Status = "CX"
+			cancelled = true
+		}
+	}
+	if !cancelled {
+		rs.EanWsError = wsError(DataValidation, "Unknown confirmationNumber.")
+		return rs
+	}
+	rs.CancellationNumber = fmt.Sprintf("CX%d", rq.ItineraryId)
+	return rs
+}
+
+// emailMatches reports whether email may see the itinerary id; an empty
+// email is not checked. The caller holds s.mtx.
+func (s *Server) emailMatches(id int64, email string) bool {
+	return email == "" || strings.EqualFold(email, s.itinEmails[id])
+}
+
+func (s *Server) hotel(id int) (Hotel, bool) {
+	for _, h := range s.fixtures.Hotels {
+		if h.Id == id {
+			return h, true
+		}
+	}
+	return Hotel{}, false
+}
+
+// room finds the room a reservation request is for, by its rateKey or else
+// by its hotel, room type and rate codes.
+func (s *Server) room(rq reservationRequest) (Hotel, Room, bool) {
+	for _, h := range s.fixtures.Hotels {
+		for _, room := range h.Rooms {
+			if rq.RateKey != "" && rq.RateKey == rateKey(h, room) {
+				return h, room, true
+			}
+			if rq.RateKey == "" && h.Id == rq.HotelId && room.RoomTypeCode == rq.RoomTypeCode && room.RateCode == rq.RateCode {
+				return h, room, true
+			}
+		}
+	}
+	return Hotel{}, Room{}, false
+}
+
+// sameAmount reports whether the decimal texts a and b are the same amount.
+func sameAmount(a, b string) bool {
+	x, err := strconv.ParseFloat(a, 64)
+	if err != nil {
+		return false
+	}
+	y, err := strconv.ParseFloat(b, 64)
+	return err == nil && x == y
+}
+
+func containsInt(s []int, n int) bool {
+	for _, v := range s {
+		if v == n {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,191 @@
+package eanmock
+
+import "encoding/xml"
+
+// The EAN documents the mock reads and writes. They model only what hsp
+// sends and reads, with EAN's element names, in both XML and EAN's JSON.
+
+type hotelListRequest struct {
+	XMLName         xml.Name `xml:"HotelListRequest"`
+	HotelIds        []int    `xml:"hotelIdList"`
+	ArrivalDate     string   `xml:"arrivalDate"`
+	DepartureDate   string   `xml:"departureDate"`
+	NumberOfResults int      `xml:"numberOfResults"`
+	CacheKey        string   `xml:"cacheKey"`
+	CacheLocation   string   `xml:"cacheLocation"`
+}
+
+type hotelListResponse struct {
+	XMLName              xml.Name    `xml:"HotelListResponse" json:"-"`
+	CustomerSessionId    string      `xml:"customerSessionId" json:"customerSessionId"`
+	MoreResultsAvailable bool        `xml:"moreResultsAvailable" json:"moreResultsAvailable"`
+	CacheKey             string      `xml:"cacheKey,omitempty" json:"cacheKey,omitempty"`
+	CacheLocation        string      `xml:"cacheLocation,omitempty" json:"cacheLocation,omitempty"`
+	EanWsError           *EanWsError `xml:"EanWsError,omitempty" json:"EanWsError,omitempty"`
+	HotelList            *hotelList  `xml:"HotelList,omitempty" json:"HotelList,omitempty"`
+}
+
+type hotelList struct {
+	Size                int            `xml:"size,attr" json:"@size,string"`
+	ActivePropertyCount int            `xml:"activePropertyCount,attr" json:"@activePropertyCount,string"`
+	Hotels              []hotelSummary `xml:"HotelSummary" json:"HotelSummary"`
+}
+
+type hotelSummary struct {
+	HotelId          int    `xml:"hotelId" json:"hotelId"`
+	Name             string `xml:"name" json:"name"`
+	City             string `xml:"city" json:"city"`
+	CountryCode      string `xml:"countryCode" json:"countryCode"`
+	LowRate          string `xml:"lowRate" json:"lowRate"`
+	HighRate         string `xml:"highRate" json:"highRate"`
+	RateCurrencyCode string `xml:"rateCurrencyCode" json:"rateCurrencyCode"`
+	RoomRateDetails  struct {
+		List []roomRateDetails `xml:"RoomRateDetails" json:"RoomRateDetails"`
+	} `xml:"RoomRateDetailsList" json:"RoomRateDetailsList"`
+}
+
+type roomRateDetails struct {
+	RoomTypeCode    int    `xml:"roomTypeCode" json:"roomTypeCode"`
+	RateCode        int    `xml:"rateCode" json:"rateCode"`
+	RoomDescription string `xml:"roomDescription" json:"roomDescription"`
+	RateInfos       struct {
+		List []rateInfo `xml:"RateInfo" json:"RateInfo"`
+	} `xml:"RateInfos" json:"RateInfos"`
+}
+
+type rateInfo struct {
+	NonRefundable        bool   `xml:"nonRefundable" json:"nonRefundable"`
+	CancellationPolicy   string `xml:"cancellationPolicy,omitempty" json:"cancellationPolicy,omitempty"`
+	CancelPolicyInfoList *struct {
+		List []cancelPolicyInfo `xml:"CancelPolicyInfo" json:"CancelPolicyInfo"`
+	} `xml:"CancelPolicyInfoList,omitempty" json:"CancelPolicyInfoList,omitempty"`
+	RoomGroup struct {
+		Rooms []rateRoom `xml:"Room" json:"Room"`
+	} `xml:"RoomGroup" json:"RoomGroup"`
+	ChargeableRateInfo chargeableRateInfo `xml:"ChargeableRateInfo" json:"ChargeableRateInfo"`
+}
+
+type cancelPolicyInfo struct {
+	CancelTime          string `xml:"cancelTime" json:"cancelTime"`
+	StartWindowHours    int    `xml:"startWindowHours" json:"startWindowHours"`
+	NightCount          int    `xml:"nightCount,omitempty" json:"nightCount,omitempty"`
+	Amount              string `xml:"amount,omitempty" json:"amount,omitempty"`
+	Percent             string `xml:"percent,omitempty" json:"percent,omitempty"`
+	CurrencyCode        string `xml:"currencyCode" json:"currencyCode"`
+	TimeZoneDescription string `xml:"timeZoneDescription" json:"timeZoneDescription"`
+}
+
+type rateRoom struct {
+	NumberOfAdults int    `xml:"numberOfAdults" json:"numberOfAdults"`
+	RateKey        string `xml:"rateKey" json:"rateKey"`
+}
+
+type chargeableRateInfo struct {
+	Total        string `xml:"total,attr" json:"@total"`
+	CurrencyCode string `xml:"currencyCode,attr" json:"@currencyCode"`
+}
+
+type roomAvailabilityRequest struct {
+	XMLName       xml.Name `xml:"HotelRoomAvailabilityRequest"`
+	HotelId       int      `xml:"hotelId"`
+	ArrivalDate   string   `xml:"arrivalDate"`
+	DepartureDate string   `xml:"departureDate"`
+}
+
+type roomAvailabilityResponse struct {
+	XMLName           xml.Name       `xml:"HotelRoomAvailabilityResponse" json:"-"`
+	CustomerSessionId string         `xml:"customerSessionId" json:"customerSessionId"`
+	HotelId           int            `xml:"hotelId,omitempty" json:"hotelId,omitempty"`
+	ArrivalDate       string         `xml:"arrivalDate,omitempty" json:"arrivalDate,omitempty"`
+	DepartureDate     string         `xml:"departureDate,omitempty" json:"departureDate,omitempty"`
+	Size              int            `xml:"size,attr" json:"@size,string"`
+	EanWsError        *EanWsError    `xml:"EanWsError,omitempty" json:"EanWsError,omitempty"`
+	Rooms             []roomResponse `xml:"HotelRoomResponse" json:"HotelRoomResponse,omitempty"`
+}
+
+type roomResponse struct {
+	RoomTypeCode    int    `xml:"roomTypeCode" json:"roomTypeCode"`
+	RateCode        int    `xml:"rateCode" json:"rateCode"`
+	RateDescription string `xml:"rateDescription" json:"rateDescription"`
+	RateInfos       struct {
+		List []rateInfo `xml:"RateInfo" json:"RateInfo"`
+	} `xml:"RateInfos" json:"RateInfos"`
+}
+
+type reservationRequest struct {
+	XMLName                 xml.Name `xml:"HotelRoomReservationRequest"`
+	HotelId                 int      `xml:"hotelId"`
+	ArrivalDate             string   `xml:"arrivalDate"`
+	DepartureDate           string   `xml:"departureDate"`
+	RateKey                 string   `xml:"rateKey"`
+	RoomTypeCode            int      `xml:"roomTypeCode"`
+	RateCode                int      `xml:"rateCode"`
+	ChargeableRate          string   `xml:"chargeableRate"`
+	AffiliateConfirmationId string   `xml:"affiliateConfirmationId"`
+	Email                   string   `xml:"ReservationInfo>email"`
+	Rooms                   []struct {
+		NumberOfAdults int `xml:"numberOfAdults"`
+	} `xml:"RoomGroup>Room"`
+}
+
+type reservationResponse struct {
+	XMLName                   xml.Name    `xml:"HotelRoomReservationResponse" json:"-"`
+	CustomerSessionId         string      `xml:"customerSessionId" json:"customerSessionId"`
+	ItineraryId               int64       `xml:"itineraryId,omitempty" json:"itineraryId,omitempty"`
+	ConfirmationNumbers       []int64     `xml:"confirmationNumbers" json:"confirmationNumbers,omitempty"`
+	ProcessedWithConfirmation bool        `xml:"processedWithConfirmation" json:"processedWithConfirmation"`
+	ReservationStatusCode     string      `xml:"reservationStatusCode,omitempty" json:"reservationStatusCode,omitempty"`
+	NonRefundable             bool        `xml:"nonRefundable" json:"nonRefundable"`
+	EanWsError                *EanWsError `xml:"EanWsError,omitempty" json:"EanWsError,omitempty"`
+}
+
+type itineraryRequest struct {
+	XMLName                 xml.Name `xml:"HotelItineraryRequest"`
+	ItineraryId             int64    `xml:"itineraryId"`
+	AffiliateConfirmationId string   `xml:"affiliateConfirmationId"`
+	Email                   string   `xml:"email"`
+}
+
+type itineraryResponse struct {
+	XMLName           xml.Name        `xml:"HotelItineraryResponse" json:"-"`
+	CustomerSessionId string          `xml:"customerSessionId" json:"customerSessionId"`
+	Itineraries       []itineraryInfo `xml:"Itinerary" json:"Itinerary,omitempty"`
+	EanWsError        *EanWsError     `xml:"EanWsError,omitempty" json:"EanWsError,omitempty"`
+}
+
+type itineraryInfo struct {
+	ItineraryId   int64          `xml:"itineraryId" json:"itineraryId"`
+	Confirmations []confirmation `xml:"HotelConfirmation" json:"HotelConfirmation"`
+}
+
+type confirmation struct {
+	ConfirmationNumber int64  `xml:"confirmationNumber" json:"confirmationNumber"`
+	Status             string `xml:"status" json:"status"`
+	HotelId            int    `xml:"hotelId" json:"hotelId"`
+	ArrivalDate        string `xml:"arrivalDate" json:"arrivalDate"`
+	DepartureDate      string `xml:"departureDate" json:"departureDate"`
+}
+
+type cancellationRequest struct {
+	XMLName            xml.Name `xml:"HotelRoomCancellationRequest"`
+	ItineraryId        int64    `xml:"itineraryId"`
+	Email              string   `xml:"email"`
+	ConfirmationNumber int64    `xml:"confirmationNumber"`
+}
+
+type cancellationResponse struct {
+	XMLName            xml.Name    `xml:"HotelRoomCancellationResponse" json:"-"`
+	CustomerSessionId  string      `xml:"customerSessionId" json:"customerSessionId"`
+	CancellationNumber string      `xml:"cancellationNumber,omitempty" json:"cancellationNumber,omitempty"`
+	EanWsError         *EanWsError `xml:"EanWsError,omitempty" json:"EanWsError,omitempty"`
+}
+
+// EanWsError is the error element EAN answers with in place of a result.
+type EanWsError struct {
+	ItineraryId          int64  `xml:"itineraryId" json:"itineraryId"`
+	Handling             string `xml:"handling" json:"handling"`
+	Category             string `xml:"category" json:"category"`
+	ExceptionConditionId int    `xml:"exceptionConditionId" json:"exceptionConditionId"`
+	PresentationMessage  string `xml:"presentationMessage" json:"presentationMessage"`
+	VerboseMessage       string `xml:"verboseMessage" json:"verboseMessage"`
+}
@@ -10,29 +10,36 @@ import (
 func makeRateBreakdownEndpoint(svc hspservice.Hsp) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		req := request.(hspservice.RateBreakdownRequest)
-		result := svc.RateBreakdown(
+		result := withContext(ctx, svc).RateBreakdown(
 			hspservice.RateBreakdownRequest{
-				req.RequestUrl,
-				req.Arrival,
-				req.Departure,
-				req.Currency,
+				Arrival:   req.Arrival,
+				Departure: req.Departure,
+				Currency:  req.Currency,
+				PageToken: req.PageToken,
+				Channel:   req.Channel,
 			},
 		)
 		return result, nil
 	}
 }
 
-func makeEanRateBreakdownEndpoint(svc EanHspService) endpoint.Endpoint {
+func makeBookEndpoint(svc hspservice.Hsp) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
-		req := request.(hspservice.RateBreakdownRequest)
-		result := svc.RateBreakdown(
-			hspservice.RateBreakdownRequest{
-				req.RequestUrl,
-				req.Arrival,
-				req.Departure,
-				req.Currency,
-			},
-		)
-		return result, nil
+		req := request.(hspservice.BookRequest)
+		return withContext(ctx, svc).Book(req), nil
+	}
+}
+
+func makeItineraryEndpoint(svc hspservice.Hsp) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(hspservice.ItineraryRequest)
+		return withContext(ctx, svc).GetItinerary(req), nil
+	}
+}
+
+func makeCancelEndpoint(svc hspservice.Hsp) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(hspservice.CancelRequest)
+		return withContext(ctx, svc).Cancel(req), nil
 	}
 }
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+	grpctransport "github.com/go-kit/kit/transport/grpc"
+	"github.com/jbowles/hotel_supply_platform/hspservice"
+	"github.com/jbowles/hotel_supply_platform/pb"
+	"github.com/jbowles/hotel_supply_platform/trace"
+)
+
+// grpcBinding serves an Hsp over gRPC by implementing pb.HspServer with a go-kit
+// grpc transport handler per method. Like the HTTP handlers, each call takes
+// its request id and the caller's trace context from the call's metadata, and
+// runs in a span when tracer is not nil.
+type grpcBinding struct {
+	rateBreakdown, book, itinerary, cancel grpctransport.Handler
+}
+
+func newGRPCBinding(ctx context.Context, svc hspservice.Hsp, tracer *trace.Tracer, logger log.Logger) grpcBinding {
+	opts := []grpctransport.ServerOption{
+		grpctransport.ServerBefore(hspservice.RequestIDToGRPCContext, trace.GRPCToContext(tracer)),
+		grpctransport.ServerErrorLogger(logger),
+	}
+	return grpcBinding{
+		rateBreakdown: grpctransport.NewServer(
+			ctx,
+			trace.Endpoint("grpc.rate_breakdown")(makeRateBreakdownEndpoint(svc)),
+			hspservice.DecodeGRPCRateBreakdownRequest,
+			hspservice.EncodeGRPCRateBreakdownResponse,
+			opts...,
+		),
+		book: grpctransport.NewServer(
+			ctx,
+			trace.Endpoint("grpc.book")(makeBookEndpoint(svc)),
+			hspservice.DecodeGRPCBookRequest,
+			hspservice.EncodeGRPCBookResponse,
+			opts...,
+		),
+		itinerary: grpctransport.NewServer(
+			ctx,
+			trace.Endpoint("grpc.itinerary")(makeItineraryEndpoint(svc)),
+			hspservice.DecodeGRPCItineraryRequest,
+			hspservice.EncodeGRPCItineraryResponse,
+			opts...,
+		),
+		cancel: grpctransport.NewServer(
+			ctx,
+			trace.Endpoint("grpc.cancel")(makeCancelEndpoint(svc)),
+			hspservice.DecodeGRPCCancelRequest,
+			hspservice.EncodeGRPCCancelResponse,
+			opts...,
+		),
+	}
+}
+
+func (b grpcBinding) RateBreakdown(ctx context.Context, req *pb.RateBreakdownRequest) (*pb.RateBreakdownReply, error) {
+	_, resp, err := b.rateBreakdown.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*pb.RateBreakdownReply), nil
+}
+
+func (b grpcBinding) Book(ctx context.Context, req *pb.BookRequest) (*pb.BookReply, error) {
+	_, resp, err := b.book.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*pb.BookReply), nil
+}
+
+func (b grpcBinding) GetItinerary(ctx context.Context, req *pb.ItineraryRequest) (*pb.ItineraryReply, error) {
+	_, resp, err := b.itinerary.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*pb.ItineraryReply), nil
+}
+
+func (b grpcBinding) Cancel(ctx context.Context, req *pb.CancelRequest) (*pb.CancelReply, error) {
+	_, resp, err := b.cancel.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*pb.CancelReply), nil
+}
+
+// grpcClient is an Hsp backed by a remote Hsp gRPC server. Transport errors
+// are returned as the response Error, like the service's own errors. Calls
+// carry the request id and span of ctx in their metadata.
+type grpcClient struct {
+	ctx                                    context.Context
+	rateBreakdown, book, itinerary, cancel endpoint.Endpoint
+}
+
+func newGRPCClient(ctx context.Context, cc *grpc.ClientConn) hspservice.Hsp {
+	before := grpctransport.ClientBefore(hspservice.RequestIDToGRPC, trace.ContextToGRPC)
+	return grpcClient{
+		ctx: ctx,
+		rateBreakdown: grpctransport.NewClient(
+			cc, "pb.Hsp", "RateBreakdown",
+			hspservice.EncodeGRPCRateBreakdownRequest,
+			hspservice.DecodeGRPCRateBreakdownResponse,
+			pb.RateBreakdownReply{},
+			before,
+		).Endpoint(),
+		book: grpctransport.NewClient(
+			cc, "pb.Hsp", "Book",
+			hspservice.EncodeGRPCBookRequest,
+			hspservice.DecodeGRPCBookResponse,
+			pb.BookReply{},
+			before,
+		).Endpoint(),
+		itinerary: grpctransport.NewClient(
+			cc, "pb.Hsp", "GetItinerary",
+			hspservice.EncodeGRPCItineraryRequest,
+			hspservice.DecodeGRPCItineraryResponse,
+			pb.ItineraryReply{},
+			before,
+		).Endpoint(),
+		cancel: grpctransport.NewClient(
+			cc, "pb.Hsp", "Cancel",
+			hspservice.EncodeGRPCCancelRequest,
+			hspservice.DecodeGRPCCancelResponse,
+			pb.CancelReply{},
+			before,
+		).Endpoint(),
+	}
+}
+
+func (c grpcClient) RateBreakdown(req hspservice.RateBreakdownRequest) hspservice.RateBreakdownResponse {
+	resp, err := c.rateBreakdown(c.ctx, req)
+	if err != nil {
+		return hspservice.RateBreakdownResponse{Request: req, Error: err}
+	}
+	return resp.(hspservice.RateBreakdownResponse)
+}
+
+func (c grpcClient) Book(req hspservice.BookRequest) hspservice.BookResponse {
+	resp, err := c.book(c.ctx, req)
+	if err != nil {
+		return hspservice.BookResponse{Error: err}
+	}
+	return resp.(hspservice.BookResponse)
+}
+
+func (c grpcClient) GetItinerary(req hspservice.ItineraryRequest) hspservice.ItineraryResponse {
+	resp, err := c.itinerary(c.ctx, req)
+	if err != nil {
+		return hspservice.ItineraryResponse{Error: err}
+	}
+	return resp.(hspservice.ItineraryResponse)
+}
+
+func (c grpcClient) Cancel(req hspservice.CancelRequest) hspservice.CancelResponse {
+	resp, err := c.cancel(c.ctx, req)
+	if err != nil {
+		return hspservice.CancelResponse{Error: err}
+	}
+	return resp.(hspservice.CancelResponse)
+}
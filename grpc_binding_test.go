@@ -0,0 +1,143 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/go-kit/kit/log"
+	"github.com/jbowles/hotel_supply_platform/hspservice"
+	"github.com/jbowles/hotel_supply_platform/pb"
+	"github.com/jbowles/hotel_supply_platform/trace"
+)
+
+// grpcFake is an Hsp answering every call with its canned responses. It keeps
+// the request id each call was served under.
+type grpcFake struct {
+	ids *[]string
+	mtx *sync.Mutex
+}
+
+func (f grpcFake) WithContext(ctx context.Context) hspservice.Hsp {
+	f.mtx.Lock()
+	*f.ids = append(*f.ids, hspservice.RequestIDFromContext(ctx))
+	f.mtx.Unlock()
+	return f
+}
+
+var grpcFakeRate = hspservice.HotelRate{
+	Supplier: "ean", HotelId: "225697", HotelName: "Mock Harbour Hotel", CountryCode: "US",
+	RoomTypeCode: "200", RateCode: "2001", RateKey: "mock-225697-200-2001", Total: "289.50", Currency: "USD",
+}
+
+func (f grpcFake) RateBreakdown(r hspservice.RateBreakdownRequest) hspservice.RateBreakdownResponse {
+	return hspservice.RateBreakdownResponse{Request: r, Rates: []hspservice.HotelRate{grpcFakeRate}, NextPageToken: "next"}
+}
+
+func (f grpcFake) Book(r hspservice.BookRequest) hspservice.BookResponse {
+	it := hspservice.Itinerary{ItineraryId: "1001", IdempotencyKey: r.IdempotencyKey, Email: r.Email}
+	it.SetRooms([]hspservice.ItineraryRoom{{ConfirmationNumber: "1234", Status: hspservice.StatusConfirmed}})
+	return hspservice.BookResponse{Itinerary: it}
+}
+
+func (f grpcFake) GetItinerary(r hspservice.ItineraryRequest) hspservice.ItineraryResponse {
+	return hspservice.ItineraryResponse{Error: errors.New("no itinerary " + r.ItineraryId)}
+}
+
+func (f grpcFake) Cancel(r hspservice.CancelRequest) hspservice.CancelResponse {
+	it := hspservice.Itinerary{ItineraryId: r.ItineraryId}
+	it.SetRooms([]hspservice.ItineraryRoom{{ConfirmationNumber: r.ConfirmationNumber, Status: hspservice.StatusCancelled, CancellationNumber: "C1"}})
+	return hspservice.CancelResponse{Itinerary: it}
+}
+
+// serveGRPC serves svc over an in-memory gRPC connection, tracing to
+// exporter, and returns a client of it and a func that shuts both down.
+func serveGRPC(t *testing.T, svc hspservice.Hsp, exporter trace.Exporter) (*grpc.ClientConn, func()) {
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer()
+	pb.RegisterHspServer(s, newGRPCBinding(context.Background(), svc, trace.NewTracer(exporter), log.NewNopLogger()))
+	go s.Serve(lis)
+	cc, err := grpc.Dial("bufnet", grpc.WithInsecure(), grpc.WithDialer(func(string, time.Duration) (net.Conn, error) {
+		return lis.Dial()
+	}))
+	if err != nil {
+		s.Stop()
+		t.Fatal(err)
+	}
+	return cc, func() { cc.Close(); s.Stop() }
+}
+
+func TestGRPCRoundTrip(t *testing.T) {
+	fake := grpcFake{ids: new([]string), mtx: new(sync.Mutex)}
+	cc, done := serveGRPC(t, fake, trace.NewInMemoryExporter())
+	defer done()
+
+	ctx := hspservice.NewRequestIDContext(context.Background(), "req-grpc-1")
+	client := newGRPCClient(ctx, cc)
+
+	rb := client.RateBreakdown(hspservice.RateBreakdownRequest{Arrival: "2026-11-02", Departure: "2026-11-05", Currency: "USD", PageToken: "tok", Channel: "web"})
+	if rb.Error != nil {
+		t.Fatal(rb.Error)
+	}
+	if rb.Request.PageToken != "tok" || rb.Request.Channel != "web" || rb.NextPageToken != "next" {
+		t.Errorf("rate breakdown %+v", rb)
+	}
+	if !reflect.DeepEqual(rb.Rates, []hspservice.HotelRate{grpcFakeRate}) {
+		t.Errorf("rates\ngot:  %+v\nwant: %+v", rb.Rates, []hspservice.HotelRate{grpcFakeRate})
+	}
+
+	book := client.Book(hspservice.BookRequest{IdempotencyKey: "key-grpc", Email: "guest@example.com"})
+	if it := book.Itinerary; book.Error != nil || it.ItineraryId != "1001" || it.IdempotencyKey != "key-grpc" ||
+		it.Status != hspservice.StatusConfirmed || len(it.Rooms) != 1 || it.Rooms[0].ConfirmationNumber != "1234" {
+		t.Errorf("book %+v, %v", it, book.Error)
+	}
+
+	if res := client.GetItinerary(hspservice.ItineraryRequest{ItineraryId: "404"}); res.Error == nil || res.Error.Error() != "no itinerary 404" {
+		t.Errorf("itinerary error %v, want the service's", res.Error)
+	}
+
+	cancel := client.Cancel(hspservice.CancelRequest{ItineraryId: "1001", ConfirmationNumber: "1234"})
+	if it := cancel.Itinerary; cancel.Error != nil || it.Status != hspservice.StatusCancelled || len(it.Rooms) != 1 || it.Rooms[0].CancellationNumber != "C1" {
+		t.Errorf("cancel %+v, %v", it, cancel.Error)
+	}
+
+	if want := []string{"req-grpc-1", "req-grpc-1", "req-grpc-1", "req-grpc-1"}; !reflect.DeepEqual(*fake.ids, want) {
+		t.Errorf("served under request ids %q, want the caller's", *fake.ids)
+	}
+}
+
+func TestGRPCTraceContext(t *testing.T) {
+	fake := grpcFake{ids: new([]string), mtx: new(sync.Mutex)}
+	server := trace.NewInMemoryExporter()
+	cc, done := serveGRPC(t, fake, server)
+	defer done()
+
+	span, ctx := trace.StartSpan(trace.NewContext(context.Background(), trace.NewTracer(trace.NewInMemoryExporter())), "caller")
+	newGRPCClient(ctx, cc).RateBreakdown(hspservice.RateBreakdownRequest{})
+	newGRPCClient(context.Background(), cc).RateBreakdown(hspservice.RateBreakdownRequest{})
+	span.Finish()
+
+	spans := server.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("server exported %d spans, want 2", len(spans))
+	}
+	caller := span.Context()
+	if s := spans[0]; s.Name != "grpc.rate_breakdown" || s.Context.TraceID != caller.TraceID || s.Parent != caller.SpanID {
+		t.Errorf("server span %+v, want a child of %+v", s, caller)
+	}
+	if s := spans[1]; s.Context.TraceID == caller.TraceID || s.Parent != (trace.SpanID{}) {
+		t.Errorf("untraced call got span %+v, want a new trace", s)
+	}
+
+	ids := *fake.ids
+	if len(ids) != 2 || ids[0] == "" || ids[0] == ids[1] {
+		t.Errorf("request ids %q, want a new one per call without one", ids)
+	}
+}
@@ -0,0 +1,35 @@
+package hspservice
+
+import "net/http"
+
+// DecodeBookRequest decodes the request from the provided HTTP request body,
+// in the codec of its Content-Type (JSON when there is none). It's designed to
+// be used in transport/http.Server.
+func DecodeBookRequest(r *http.Request) (interface{}, error) {
+	var request BookRequest
+	err := decodeRequest(r, &request)
+	return request, err
+}
+
+// EncodeBookRequest encodes the request to the provided HTTP request, simply
+// by JSON encoding to the request body. It's designed to be used in
+// transport/http.Client.
+func EncodeBookRequest(r *http.Request, request interface{}) error {
+	return encodeRequest(r, request)
+}
+
+// DecodeBookResponse decodes the response from the provided HTTP response,
+// in the codec of its Content-Type. It's designed to be used in
+// transport/http.Client.
+func DecodeBookResponse(resp *http.Response) (interface{}, error) {
+	var response BookResponse
+	err := decodeResponse(resp, &response)
+	return response, err
+}
+
+// EncodeBookResponse encodes the response to the provided HTTP response
+// writer, in the media type negotiated by NegotiateContentType. It's designed
+// to be used in transport/http.Server.
+func EncodeBookResponse(w http.ResponseWriter, response interface{}) error {
+	return encodeResponse(w, response)
+}
@@ -0,0 +1,48 @@
+package hspservice
+
+// BookRequest is the business domain type for a Book method request.
+// RateKey, RoomTypeCode and RateCode come from a HotelRate returned by
+// RateBreakdown; ChargeableRate is the Total the guest agreed to, which the
+// supplier checks against its current price.
+type BookRequest struct {
+	IdempotencyKey string      `json:"idempotency_key" xml:"idempotency_key"`
+	Supplier       string      `json:"supplier" xml:"supplier"`
+	HotelId        string      `json:"hotel_id" xml:"hotel_id"`
+	Arrival        string      `json:"arrival" xml:"arrival"`
+	Departure      string      `json:"departure" xml:"departure"`
+	RateKey        string      `json:"rate_key" xml:"rate_key"`
+	RoomTypeCode   string      `json:"room_type_code" xml:"room_type_code"`
+	RateCode       string      `json:"rate_code" xml:"rate_code"`
+	ChargeableRate string      `json:"chargeable_rate" xml:"chargeable_rate"`
+	Currency       string      `json:"currency" xml:"currency"`
+	Email          string      `json:"email" xml:"email"`
+	Phone          string      `json:"phone,omitempty" xml:"phone,omitempty"`
+	Rooms          []BookRoom  `json:"rooms" xml:"rooms>room"`
+	Payment        BookPayment `json:"payment" xml:"payment"`
+}
+
+// BookRoom is a single room of a booking and the guest it is booked for.
+type BookRoom struct {
+	Adults            int    `json:"adults" xml:"adults"`
+	ChildAges         []int  `json:"child_ages,omitempty" xml:"child_ages>age,omitempty"`
+	FirstName         string `json:"first_name" xml:"first_name"`
+	LastName          string `json:"last_name" xml:"last_name"`
+	BedTypeId         string `json:"bed_type_id,omitempty" xml:"bed_type_id,omitempty"`
+	SmokingPreference string `json:"smoking_preference,omitempty" xml:"smoking_preference,omitempty"` // NS, S or E (either)
+}
+
+// BookPayment is the card the booking is charged to, and its billing address.
+type BookPayment struct {
+	CardType          string `json:"card_type" xml:"card_type"`
+	CardNumber        string `json:"card_number" xml:"card_number"`
+	CardIdentifier    string `json:"card_identifier" xml:"card_identifier"`
+	ExpirationMonth   string `json:"expiration_month" xml:"expiration_month"`
+	ExpirationYear    string `json:"expiration_year" xml:"expiration_year"`
+	FirstName         string `json:"first_name" xml:"first_name"`
+	LastName          string `json:"last_name" xml:"last_name"`
+	Address1          string `json:"address1" xml:"address1"`
+	City              string `json:"city" xml:"city"`
+	StateProvinceCode string `json:"state_province_code,omitempty" xml:"state_province_code,omitempty"`
+	CountryCode       string `json:"country_code" xml:"country_code"`
+	PostalCode        string `json:"postal_code" xml:"postal_code"`
+}
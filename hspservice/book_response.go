@@ -0,0 +1,7 @@
+package hspservice
+
+// BookResponse is the business domain type for a Book method response.
+type BookResponse struct {
+	Itinerary Itinerary `json:"itinerary" xml:"itinerary"`
+	Error     error     `json:"error" xml:"-"`
+}
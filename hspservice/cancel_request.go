@@ -0,0 +1,11 @@
+package hspservice
+
+// CancelRequest is the business domain type for a Cancel method request.
+// ConfirmationNumber picks the room to cancel; it is required on multi room
+// itineraries.
+type CancelRequest struct {
+	ItineraryId        string `json:"itinerary_id" xml:"itinerary_id"`
+	ConfirmationNumber string `json:"confirmation_number" xml:"confirmation_number"`
+	Email              string `json:"email" xml:"email"`
+	Reason             string `json:"reason,omitempty" xml:"reason,omitempty"`
+}
@@ -0,0 +1,7 @@
+package hspservice
+
+// CancelResponse is the business domain type for a Cancel method response.
+type CancelResponse struct {
+	Itinerary Itinerary `json:"itinerary" xml:"itinerary"`
+	Error     error     `json:"error" xml:"-"`
+}
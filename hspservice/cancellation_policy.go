@@ -0,0 +1,116 @@
+package hspservice
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/jbowles/hotel_supply_platform/currency"
+)
+
+// ErrPenaltyCurrency is returned when a penalty tier's amount is not in the
+// currency of the total it is charged against.
+var ErrPenaltyCurrency = errors.New("cancellation: penalty amount currency does not match total")
+
+// ErrUnknownPenalty is returned for a refundable rate whose policy has no
+// tiers, as when the supplier's windows could not be parsed: cancelling it is
+// not known to be free.
+var ErrUnknownPenalty = errors.New("cancellation: penalty unknown, policy has no tiers")
+
+// CancellationPolicy is a supplier's cancellation policy, normalized. Times are
+// in the hotel's time zone. Text is the supplier's own wording, for display.
+//
+// A refundable rate can be cancelled free of charge until FreeCancelUntil;
+// from then on the Tier with the latest Start at or before the time of
+// cancelling applies. A rate that is not Refundable costs its full total to
+// cancel at any time.
+type CancellationPolicy struct {
+	Refundable      bool          `json:"refundable" xml:"refundable"`
+	FreeCancelUntil *time.Time    `json:"free_cancel_until,omitempty" xml:"free_cancel_until,omitempty"`
+	Tiers           []PenaltyTier `json:"tiers,omitempty" xml:"tiers>tier,omitempty"`
+	Text            string        `json:"text,omitempty" xml:"text,omitempty"`
+}
+
+// PenaltyTier is the charge for cancelling at or after Start. Exactly one of
+// Nights, Amount or Percent (of the total, as a decimal string) is set.
+type PenaltyTier struct {
+	Start   time.Time       `json:"start" xml:"start"`
+	Nights  int             `json:"nights,omitempty" xml:"nights,omitempty"`
+	Amount  *currency.Money `json:"amount,omitempty" xml:"amount,omitempty"`
+	Percent string          `json:"percent,omitempty" xml:"percent,omitempty"`
+}
+
+// NewCancellationPolicy builds a policy from its tiers, sorting them and
+// setting FreeCancelUntil to the start of the first one.
+func NewCancellationPolicy(refundable bool, tiers []PenaltyTier, text string) CancellationPolicy {
+	p := CancellationPolicy{Refundable: refundable, Tiers: tiers, Text: text}
+	sort.Slice(p.Tiers, func(i, j int) bool { return p.Tiers[i].Start.Before(p.Tiers[j].Start) })
+	if refundable && len(p.Tiers) > 0 {
+		free := p.Tiers[0].Start
+		p.FreeCancelUntil = &free
+	}
+	return p
+}
+
+// Penalty returns what it costs to cancel at time at a stay of nights nights
+// that costs total. Night penalties are charged at the average nightly rate.
+// The penalty never exceeds total. A refundable policy without tiers returns
+// ErrUnknownPenalty.
+func (p CancellationPolicy) Penalty(at time.Time, total currency.Money, nights int) (currency.Money, error) {
+	zero := currency.Money{Currency: total.Currency}
+	if !p.Refundable {
+		return total, nil
+	}
+	if len(p.Tiers) == 0 {
+		return zero, ErrUnknownPenalty
+	}
+	var tier *PenaltyTier
+	for i := range p.Tiers {
+		if p.Tiers[i].Start.After(at) {
+			break
+		}
+		tier = &p.Tiers[i]
+	}
+	if tier == nil {
+		return zero, nil
+	}
+
+	var penalty currency.Money
+	switch {
+	case tier.Amount != nil:
+		if tier.Amount.Currency != total.Currency {
+			return zero, fmt.Errorf("%v: %s, %s", ErrPenaltyCurrency, tier.Amount.Currency, total.Currency)
+		}
+		penalty = *tier.Amount
+	case tier.Percent != "":
+		pct, ok := new(big.Rat).SetString(tier.Percent)
+		if !ok {
+			return zero, fmt.Errorf("cancellation: invalid percent %q", tier.Percent)
+		}
+		penalty = total.Mul(pct.Quo(pct, big.NewRat(100, 1)))
+	case tier.Nights > 0:
+		if nights <= 0 {
+			return zero, fmt.Errorf("cancellation: %d night penalty on a stay of %d nights", tier.Nights, nights)
+		}
+		penalty = total.Mul(big.NewRat(int64(tier.Nights), int64(nights)))
+	}
+	if penalty.Cmp(total) > 0 {
+		return total, nil
+	}
+	return penalty, nil
+}
+
+// CancellationPenalty returns what it costs to cancel the rate at time at; see
+// CancellationPolicy.Penalty. Rates without a policy cannot be priced.
+func (r HotelRate) CancellationPenalty(at time.Time, nights int) (currency.Money, error) {
+	if r.CancellationPolicy == nil {
+		return currency.Money{}, errors.New("cancellation: rate has no cancellation policy")
+	}
+	total, err := currency.Parse(r.Total, r.Currency)
+	if err != nil {
+		return currency.Money{}, err
+	}
+	return r.CancellationPolicy.Penalty(at, total, nights)
+}
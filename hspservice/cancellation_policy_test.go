@@ -0,0 +1,72 @@
+package hspservice
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jbowles/hotel_supply_platform/currency"
+)
+
+func money(t *testing.T, amount, code string) currency.Money {
+	m, err := currency.Parse(amount, code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestPenalty(t *testing.T) {
+	loc := time.FixedZone("GMT-08:00", -8*60*60)
+	free := time.Date(2026, 11, 1, 18, 0, 0, 0, loc)
+	noShow := time.Date(2026, 11, 2, 18, 0, 0, 0, loc)
+	fifty := money(t, "50.00", "USD")
+	total := money(t, "289.50", "USD")
+
+	for _, tc := range []struct {
+		name  string
+		tiers []PenaltyTier
+		at    time.Time
+		want  string
+	}{
+		{"before the first tier", []PenaltyTier{{Start: free, Nights: 1}}, free.Add(-time.Second), "0.00"},
+		{"one night", []PenaltyTier{{Start: free, Nights: 1}}, free, "96.50"},
+		{"amount", []PenaltyTier{{Start: free, Amount: &fifty}}, free.Add(time.Hour), "50.00"},
+		{"percent", []PenaltyTier{{Start: free, Percent: "50"}}, free, "144.75"},
+		{"latest tier applies", []PenaltyTier{{Start: noShow, Percent: "100"}, {Start: free, Nights: 1}}, noShow, "289.50"},
+		{"capped at the total", []PenaltyTier{{Start: free, Nights: 5}}, free, "289.50"},
+	} {
+		p := NewCancellationPolicy(true, tc.tiers, "")
+		got, err := p.Penalty(tc.at, total, 3)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if got.String() != tc.want || got.Currency != "USD" {
+			t.Errorf("%s: penalty %s %s, want %s USD", tc.name, got, got.Currency, tc.want)
+		}
+	}
+}
+
+func TestPenaltyPolicies(t *testing.T) {
+	at := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	total := money(t, "289.50", "USD")
+
+	p := NewCancellationPolicy(false, nil, "Non refundable.")
+	if got, err := p.Penalty(at, total, 3); err != nil || got.Cmp(total) != 0 {
+		t.Errorf("non refundable: got %s, %v; want the total", got, err)
+	}
+
+	p = NewCancellationPolicy(true, nil, "Free cancellation until 24 hours before arrival.")
+	if p.FreeCancelUntil != nil {
+		t.Errorf("no tiers: free cancel until %v", p.FreeCancelUntil)
+	}
+	if _, err := p.Penalty(at, total, 3); err != ErrUnknownPenalty {
+		t.Errorf("refundable without tiers: got %v, want ErrUnknownPenalty", err)
+	}
+
+	eur := money(t, "50.00", "EUR")
+	p = NewCancellationPolicy(true, []PenaltyTier{{Start: at, Amount: &eur}}, "")
+	if _, err := p.Penalty(at, total, 3); err == nil {
+		t.Error("no error for a penalty in another currency")
+	}
+}
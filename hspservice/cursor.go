@@ -0,0 +1,55 @@
+package hspservice
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// ErrInvalidPageToken is returned when a client sends a page token that was not
+// issued by this service (or was issued for another supplier or search).
+var ErrInvalidPageToken = errors.New("invalid page token")
+
+// Cursor is the supplier side position in a paged result set. EAN, for example,
+// pages hotel lists through a cacheKey/cacheLocation pair. Clients never see a
+// Cursor directly, only the opaque token returned by Token.
+// Search binds the cursor to the search that issued it, see SearchKey.
+type Cursor struct {
+	Supplier      string `json:"s"`
+	CacheKey      string `json:"k"`
+	CacheLocation string `json:"l,omitempty"`
+	Search        string `json:"q"`
+}
+
+// Token encodes the cursor as an opaque, URL safe page token.
+func (c Cursor) Token() string {
+	b, _ := json.Marshal(c) // cannot fail on a struct of strings
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// ParseCursor decodes a page token produced by Cursor.Token.
+func ParseCursor(token string) (Cursor, error) {
+	var c Cursor
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return c, ErrInvalidPageToken
+	}
+	if err := json.Unmarshal(b, &c); err != nil || c.Supplier == "" || c.CacheKey == "" || c.Search == "" {
+		return c, ErrInvalidPageToken
+	}
+	return c, nil
+}
+
+// SearchKey returns a hash of the search criteria of r, everything but the
+// page token, and of the supplier side criteria the rates were searched with,
+// such as the hotels and destination. A page token is only good for the
+// search it was issued for.
+func (r RateBreakdownRequest) SearchKey(criteria ...string) string {
+	h := sha256.New()
+	for _, f := range append([]string{r.Arrival, r.Departure, r.Currency, r.Channel}, criteria...) {
+		h.Write([]byte(f))
+		h.Write([]byte{0})
+	}
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil)[:16])
+}
@@ -0,0 +1,109 @@
+package hspservice
+
+import (
+	"encoding/base64"
+	"net/url"
+	"testing"
+)
+
+// pagedSupplier is a Pager searching hotels, whose Params carry the page.
+type pagedSupplier struct {
+	name   string
+	hotels []string
+	page   Cursor
+}
+
+func (s *pagedSupplier) Name() string       { return s.name }
+func (s *pagedSupplier) DateRange(days int) {}
+func (s *pagedSupplier) Criteria() []string { return s.hotels }
+
+func (s *pagedSupplier) Page(c Cursor) error {
+	s.page = c
+	return nil
+}
+
+func (s *pagedSupplier) Params() (*url.URL, error) {
+	return &url.URL{Scheme: "https", Host: "supplier.example.com", Path: "/list", RawQuery: url.Values{"cacheKey": {s.page.CacheKey}}.Encode()}, nil
+}
+
+// unpagedSupplier cannot page.
+type unpagedSupplier struct{}
+
+func (unpagedSupplier) Name() string              { return "ean" }
+func (unpagedSupplier) DateRange(days int)        {}
+func (unpagedSupplier) Params() (*url.URL, error) { return &url.URL{}, nil }
+
+func TestParseCursor(t *testing.T) {
+	c := Cursor{Supplier: "ean", CacheKey: "k1", CacheLocation: "10.1.2.3:7300", Search: "s"}
+	got, err := ParseCursor(c.Token())
+	if err != nil || got != c {
+		t.Errorf("round trip %+v, %v; want %+v", got, err, c)
+	}
+
+	for _, token := range []string{
+		"",
+		"not base64!",
+		base64.RawURLEncoding.EncodeToString([]byte("not json")),
+		base64.RawURLEncoding.EncodeToString([]byte(`{"k": "k1", "q": "s"}`)),
+		base64.RawURLEncoding.EncodeToString([]byte(`{"s": "ean", "q": "s"}`)),
+		base64.RawURLEncoding.EncodeToString([]byte(`{"s": "ean", "k": "k1"}`)),
+		base64.StdEncoding.EncodeToString([]byte(`{"s": "ean", "k": "k1+/", "q": "s"}`)),
+	} {
+		if _, err := ParseCursor(token); err != ErrInvalidPageToken {
+			t.Errorf("%q: %v, want %v", token, err, ErrInvalidPageToken)
+		}
+	}
+}
+
+func TestBuildPage(t *testing.T) {
+	search := RateBreakdownRequest{Arrival: "2026-11-02", Departure: "2026-11-05", Currency: "EUR", Channel: "mobile"}
+	hotels := []string{"hotel=225697", "hotel=116908"}
+	issue := func(supplier string) string {
+		return Cursor{Supplier: supplier, CacheKey: "k1", Search: search.SearchKey(hotels...)}.Token()
+	}
+
+	r := search
+	r.PageToken = issue("ean")
+	u, err := BuildPage(&pagedSupplier{name: "ean", hotels: hotels}, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k := u.Query().Get("cacheKey"); k != "k1" {
+		t.Errorf("cacheKey %q, want the cursor's", k)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		supplier Supplier
+		change   func(*RateBreakdownRequest)
+	}{
+		{"garbage", &pagedSupplier{name: "ean", hotels: hotels}, func(r *RateBreakdownRequest) { r.PageToken = "garbage" }},
+		{"another supplier's", &pagedSupplier{name: "ean", hotels: hotels}, func(r *RateBreakdownRequest) { r.PageToken = issue("ota") }},
+		{"not a pager", unpagedSupplier{}, func(r *RateBreakdownRequest) {}},
+		{"other dates", &pagedSupplier{name: "ean", hotels: hotels}, func(r *RateBreakdownRequest) { r.Departure = "2026-11-06" }},
+		{"other currency", &pagedSupplier{name: "ean", hotels: hotels}, func(r *RateBreakdownRequest) { r.Currency = "USD" }},
+		{"other channel", &pagedSupplier{name: "ean", hotels: hotels}, func(r *RateBreakdownRequest) { r.Channel = "" }},
+		{"other hotels", &pagedSupplier{name: "ean", hotels: hotels[:1]}, func(r *RateBreakdownRequest) {}},
+		{"other destination", &pagedSupplier{name: "ean", hotels: append(hotels[:2:2], "city=Paris")}, func(r *RateBreakdownRequest) {}},
+	} {
+		r := search
+		r.PageToken = issue("ean")
+		tc.change(&r)
+		if _, err := BuildPage(tc.supplier, r); err != ErrInvalidPageToken {
+			t.Errorf("%s: %v, want %v", tc.name, err, ErrInvalidPageToken)
+		}
+	}
+}
+
+func TestSearchKey(t *testing.T) {
+	r := RateBreakdownRequest{Arrival: "2026-11-02", Departure: "2026-11-05", Currency: "EUR"}
+	withToken := r
+	withToken.PageToken = "token"
+	if r.SearchKey("hotel=1") != withToken.SearchKey("hotel=1") {
+		t.Error("the page token changes the search key")
+	}
+	// Fields are separated, so criteria cannot run into each other.
+	if r.SearchKey("hotel=1", "hotel=2") == r.SearchKey("hotel=1hotel=2") {
+		t.Error("criteria run together hash alike")
+	}
+}
@@ -0,0 +1,213 @@
+package hspservice
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+)
+
+// Error is a response error as it is sent over the HTTP API. Code identifies
+// the errors clients are expected to handle; it is empty for any other error.
+type Error struct {
+	Code    string `json:"code,omitempty" xml:"code,omitempty"`
+	Message string `json:"message" xml:"message"`
+}
+
+func (e *Error) Error() string { return e.Message }
+
+// errorCodes are the errors sent with a Code, and decoded back to the same
+// error value, so clients can compare against them.
+var errorCodes = map[string]error{
+	"invalid_page_token":     ErrInvalidPageToken,
+	"no_idempotency_key":     ErrNoIdempotencyKey,
+	"no_confirmation_number": ErrNoConfirmationNumber,
+	"not_supported":          ErrNotSupported,
+	"itinerary_not_found":    ErrItineraryNotFound,
+}
+
+func encodeError(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	if e, ok := err.(*Error); ok {
+		return e
+	}
+	for code, known := range errorCodes {
+		if err == known {
+			return &Error{Code: code, Message: err.Error()}
+		}
+	}
+	return &Error{Message: err.Error()}
+}
+
+func decodeError(e *Error) error {
+	if e == nil {
+		return nil
+	}
+	if known, ok := errorCodes[e.Code]; ok {
+		return known
+	}
+	return e
+}
+
+// The response types carry an error interface, which encoding/json and
+// encoding/xml cannot round trip; they are encoded through a wire, which
+// swaps it for an *Error.
+
+// MarshalJSON implements json.Marshaler.
+func (r RateBreakdownResponse) MarshalJSON() ([]byte, error) { return wireOf(&r).MarshalJSON() }
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (r *RateBreakdownResponse) UnmarshalJSON(b []byte) error { return wireOf(r).UnmarshalJSON(b) }
+
+// MarshalXML implements xml.Marshaler.
+func (r RateBreakdownResponse) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return wireOf(&r).MarshalXML(e, start)
+}
+
+// UnmarshalXML implements xml.Unmarshaler.
+func (r *RateBreakdownResponse) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	return wireOf(r).UnmarshalXML(d, start)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (r BookResponse) MarshalJSON() ([]byte, error) { return wireOf(&r).MarshalJSON() }
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (r *BookResponse) UnmarshalJSON(b []byte) error { return wireOf(r).UnmarshalJSON(b) }
+
+// MarshalXML implements xml.Marshaler.
+func (r BookResponse) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return wireOf(&r).MarshalXML(e, start)
+}
+
+// UnmarshalXML implements xml.Unmarshaler.
+func (r *BookResponse) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	return wireOf(r).UnmarshalXML(d, start)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (r ItineraryResponse) MarshalJSON() ([]byte, error) { return wireOf(&r).MarshalJSON() }
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (r *ItineraryResponse) UnmarshalJSON(b []byte) error { return wireOf(r).UnmarshalJSON(b) }
+
+// MarshalXML implements xml.Marshaler.
+func (r ItineraryResponse) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return wireOf(&r).MarshalXML(e, start)
+}
+
+// UnmarshalXML implements xml.Unmarshaler.
+func (r *ItineraryResponse) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	return wireOf(r).UnmarshalXML(d, start)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (r CancelResponse) MarshalJSON() ([]byte, error) { return wireOf(&r).MarshalJSON() }
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (r *CancelResponse) UnmarshalJSON(b []byte) error { return wireOf(r).UnmarshalJSON(b) }
+
+// MarshalXML implements xml.Marshaler.
+func (r CancelResponse) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return wireOf(&r).MarshalXML(e, start)
+}
+
+// UnmarshalXML implements xml.Unmarshaler.
+func (r *CancelResponse) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	return wireOf(r).UnmarshalXML(d, start)
+}
+
+// wire is a response as it is sent: its fields, with its error as an *Error.
+// fields points to the response converted to a type without the methods
+// above, so encoding it does not recurse; err points to its Error field. name
+// is the XML document element.
+type wire struct {
+	name   string
+	fields interface{}
+	err    *error
+}
+
+// wireOf returns the wire of r, a pointer to one of the response types.
+func wireOf(r interface{}) *wire {
+	switch r := r.(type) {
+	case *RateBreakdownResponse:
+		type plain RateBreakdownResponse
+		return &wire{"rate_breakdown_response", (*plain)(r), &r.Error}
+	case *BookResponse:
+		type plain BookResponse
+		return &wire{"book_response", (*plain)(r), &r.Error}
+	case *ItineraryResponse:
+		type plain ItineraryResponse
+		return &wire{"itinerary_response", (*plain)(r), &r.Error}
+	case *CancelResponse:
+		type plain CancelResponse
+		return &wire{"cancel_response", (*plain)(r), &r.Error}
+	}
+	panic(fmt.Sprintf("hspservice: no wire for %T", r))
+}
+
+// MarshalJSON implements json.Marshaler. The error is an *Error while the
+// fields are encoded.
+func (w *wire) MarshalJSON() ([]byte, error) {
+	err := *w.err
+	defer func() { *w.err = err }()
+	*w.err = nil
+	if e := encodeError(err); e != nil {
+		*w.err = e
+	}
+	return json.Marshal(w.fields)
+}
+
+// UnmarshalJSON implements json.Unmarshaler. The error is decoded into an
+// *Error, which encoding/json fills in through the interface.
+func (w *wire) UnmarshalJSON(b []byte) error {
+	e := new(Error)
+	*w.err = e
+	if err := json.Unmarshal(b, w.fields); err != nil {
+		return err
+	}
+	if *w.err == nil || *e == (Error{}) {
+		*w.err = nil // "error": null, or no error member
+		return nil
+	}
+	*w.err = decodeError(e)
+	return nil
+}
+
+// xmlWire is the document element of a wire: the fields' elements, then the
+// error.
+type xmlWire struct {
+	Fields []byte `xml:",innerxml"`
+	Error  *Error `xml:"error,omitempty"`
+}
+
+// fieldsElement wraps the fields while they are encoded on their own.
+var fieldsElement = xml.StartElement{Name: xml.Name{Local: "fields"}}
+
+// MarshalXML implements xml.Marshaler.
+func (w *wire) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	var buf bytes.Buffer
+	if err := xml.NewEncoder(&buf).EncodeElement(w.fields, fieldsElement); err != nil {
+		return err
+	}
+	fields := bytes.TrimPrefix(buf.Bytes(), []byte("<fields>"))
+	fields = bytes.TrimSuffix(fields, []byte("</fields>"))
+	start.Name = xml.Name{Local: w.name}
+	return e.EncodeElement(xmlWire{fields, encodeError(*w.err)}, start)
+}
+
+// UnmarshalXML implements xml.Unmarshaler.
+func (w *wire) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var v xmlWire
+	if err := d.DecodeElement(&v, &start); err != nil {
+		return err
+	}
+	fields := append(append([]byte("<fields>"), v.Fields...), "</fields>"...)
+	if err := xml.Unmarshal(fields, w.fields); err != nil {
+		return err
+	}
+	*w.err = decodeError(v.Error)
+	return nil
+}
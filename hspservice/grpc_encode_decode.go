@@ -0,0 +1,452 @@
+package hspservice
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jbowles/hotel_supply_platform/currency"
+	"github.com/jbowles/hotel_supply_platform/pb"
+	"golang.org/x/net/context"
+)
+
+// The gRPC codecs convert between the domain types and their pb messages. The
+// Decode*Request and Encode*Response funcs are designed to be used in
+// transport/grpc.Server, the Encode*Request and Decode*Response funcs in
+// transport/grpc.Client. Errors travel as text in the reply's err field, like
+// the JSON API, so a supplier error is not a failed RPC.
+
+// DecodeGRPCRateBreakdownRequest converts a pb.RateBreakdownRequest.
+func DecodeGRPCRateBreakdownRequest(ctx context.Context, request interface{}) (interface{}, error) {
+	return rateBreakdownRequestFromPB(request.(*pb.RateBreakdownRequest)), nil
+}
+
+// EncodeGRPCRateBreakdownResponse converts a RateBreakdownResponse to a pb.RateBreakdownReply.
+func EncodeGRPCRateBreakdownResponse(ctx context.Context, response interface{}) (interface{}, error) {
+	resp := response.(RateBreakdownResponse)
+	reply := &pb.RateBreakdownReply{
+		Request:       rateBreakdownRequestToPB(resp.Request),
+		NextPageToken: resp.NextPageToken,
+		Err:           errToPB(resp.Error),
+	}
+	for _, r := range resp.Rates {
+		reply.Rates = append(reply.Rates, hotelRateToPB(r))
+	}
+	return reply, nil
+}
+
+// EncodeGRPCRateBreakdownRequest converts a RateBreakdownRequest to a pb.RateBreakdownRequest.
+func EncodeGRPCRateBreakdownRequest(ctx context.Context, request interface{}) (interface{}, error) {
+	return rateBreakdownRequestToPB(request.(RateBreakdownRequest)), nil
+}
+
+// DecodeGRPCRateBreakdownResponse converts a pb.RateBreakdownReply.
+func DecodeGRPCRateBreakdownResponse(ctx context.Context, response interface{}) (interface{}, error) {
+	reply := response.(*pb.RateBreakdownReply)
+	resp := RateBreakdownResponse{
+		NextPageToken: reply.NextPageToken,
+		Error:         errFromPB(reply.Err),
+	}
+	if reply.Request != nil {
+		resp.Request = rateBreakdownRequestFromPB(reply.Request)
+	}
+	for _, r := range reply.Rates {
+		rate, err := hotelRateFromPB(r)
+		if err != nil {
+			return nil, err
+		}
+		resp.Rates = append(resp.Rates, rate)
+	}
+	return resp, nil
+}
+
+// DecodeGRPCBookRequest converts a pb.BookRequest.
+func DecodeGRPCBookRequest(ctx context.Context, request interface{}) (interface{}, error) {
+	r := request.(*pb.BookRequest)
+	req := BookRequest{
+		IdempotencyKey: r.IdempotencyKey,
+		Supplier:       r.Supplier,
+		HotelId:        r.HotelId,
+		Arrival:        r.Arrival,
+		Departure:      r.Departure,
+		RateKey:        r.RateKey,
+		RoomTypeCode:   r.RoomTypeCode,
+		RateCode:       r.RateCode,
+		ChargeableRate: r.ChargeableRate,
+		Currency:       r.Currency,
+		Email:          r.Email,
+		Phone:          r.Phone,
+	}
+	for _, rm := range r.Rooms {
+		room := BookRoom{
+			Adults:            int(rm.Adults),
+			FirstName:         rm.FirstName,
+			LastName:          rm.LastName,
+			BedTypeId:         rm.BedTypeId,
+			SmokingPreference: rm.SmokingPreference,
+		}
+		for _, a := range rm.ChildAges {
+			room.ChildAges = append(room.ChildAges, int(a))
+		}
+		req.Rooms = append(req.Rooms, room)
+	}
+	if p := r.Payment; p != nil {
+		req.Payment = BookPayment{
+			CardType:          p.CardType,
+			CardNumber:        p.CardNumber,
+			CardIdentifier:    p.CardIdentifier,
+			ExpirationMonth:   p.ExpirationMonth,
+			ExpirationYear:    p.ExpirationYear,
+			FirstName:         p.FirstName,
+			LastName:          p.LastName,
+			Address1:          p.Address1,
+			City:              p.City,
+			StateProvinceCode: p.StateProvinceCode,
+			CountryCode:       p.CountryCode,
+			PostalCode:        p.PostalCode,
+		}
+	}
+	return req, nil
+}
+
+// EncodeGRPCBookResponse converts a BookResponse to a pb.BookReply.
+func EncodeGRPCBookResponse(ctx context.Context, response interface{}) (interface{}, error) {
+	resp := response.(BookResponse)
+	return &pb.BookReply{Itinerary: itineraryToPB(resp.Itinerary), Err: errToPB(resp.Error)}, nil
+}
+
+// EncodeGRPCBookRequest converts a BookRequest to a pb.BookRequest.
+func EncodeGRPCBookRequest(ctx context.Context, request interface{}) (interface{}, error) {
+	r := request.(BookRequest)
+	req := &pb.BookRequest{
+		IdempotencyKey: r.IdempotencyKey,
+		Supplier:       r.Supplier,
+		HotelId:        r.HotelId,
+		Arrival:        r.Arrival,
+		Departure:      r.Departure,
+		RateKey:        r.RateKey,
+		RoomTypeCode:   r.RoomTypeCode,
+		RateCode:       r.RateCode,
+		ChargeableRate: r.ChargeableRate,
+		Currency:       r.Currency,
+		Email:          r.Email,
+		Phone:          r.Phone,
+		Payment: &pb.BookPayment{
+			CardType:          r.Payment.CardType,
+			CardNumber:        r.Payment.CardNumber,
+			CardIdentifier:    r.Payment.CardIdentifier,
+			ExpirationMonth:   r.Payment.ExpirationMonth,
+			ExpirationYear:    r.Payment.ExpirationYear,
+			FirstName:         r.Payment.FirstName,
+			LastName:          r.Payment.LastName,
+			Address1:          r.Payment.Address1,
+			City:              r.Payment.City,
+			StateProvinceCode: r.Payment.StateProvinceCode,
+			CountryCode:       r.Payment.CountryCode,
+			PostalCode:        r.Payment.PostalCode,
+		},
+	}
+	for _, rm := range r.Rooms {
+		room := &pb.BookRoom{
+			Adults:            int32(rm.Adults),
+			FirstName:         rm.FirstName,
+			LastName:          rm.LastName,
+			BedTypeId:         rm.BedTypeId,
+			SmokingPreference: rm.SmokingPreference,
+		}
+		for _, a := range rm.ChildAges {
+			room.ChildAges = append(room.ChildAges, int32(a))
+		}
+		req.Rooms = append(req.Rooms, room)
+	}
+	return req, nil
+}
+
+// DecodeGRPCBookResponse converts a pb.BookReply.
+func DecodeGRPCBookResponse(ctx context.Context, response interface{}) (interface{}, error) {
+	reply := response.(*pb.BookReply)
+	it, err := itineraryFromPB(reply.Itinerary)
+	if err != nil {
+		return nil, err
+	}
+	return BookResponse{Itinerary: it, Error: errFromPB(reply.Err)}, nil
+}
+
+// DecodeGRPCItineraryRequest converts a pb.ItineraryRequest.
+func DecodeGRPCItineraryRequest(ctx context.Context, request interface{}) (interface{}, error) {
+	r := request.(*pb.ItineraryRequest)
+	return ItineraryRequest{ItineraryId: r.ItineraryId, Email: r.Email}, nil
+}
+
+// EncodeGRPCItineraryResponse converts an ItineraryResponse to a pb.ItineraryReply.
+func EncodeGRPCItineraryResponse(ctx context.Context, response interface{}) (interface{}, error) {
+	resp := response.(ItineraryResponse)
+	return &pb.ItineraryReply{Itinerary: itineraryToPB(resp.Itinerary), Err: errToPB(resp.Error)}, nil
+}
+
+// EncodeGRPCItineraryRequest converts an ItineraryRequest to a pb.ItineraryRequest.
+func EncodeGRPCItineraryRequest(ctx context.Context, request interface{}) (interface{}, error) {
+	r := request.(ItineraryRequest)
+	return &pb.ItineraryRequest{ItineraryId: r.ItineraryId, Email: r.Email}, nil
+}
+
+// DecodeGRPCItineraryResponse converts a pb.ItineraryReply.
+func DecodeGRPCItineraryResponse(ctx context.Context, response interface{}) (interface{}, error) {
+	reply := response.(*pb.ItineraryReply)
+	it, err := itineraryFromPB(reply.Itinerary)
+	if err != nil {
+		return nil, err
+	}
+	return ItineraryResponse{Itinerary: it, Error: errFromPB(reply.Err)}, nil
+}
+
+// DecodeGRPCCancelRequest converts a pb.CancelRequest.
+func DecodeGRPCCancelRequest(ctx context.Context, request interface{}) (interface{}, error) {
+	r := request.(*pb.CancelRequest)
+	return CancelRequest{
+		ItineraryId:        r.ItineraryId,
+		ConfirmationNumber: r.ConfirmationNumber,
+		Email:              r.Email,
+		Reason:             r.Reason,
+	}, nil
+}
+
+// EncodeGRPCCancelResponse converts a CancelResponse to a pb.CancelReply.
+func EncodeGRPCCancelResponse(ctx context.Context, response interface{}) (interface{}, error) {
+	resp := response.(CancelResponse)
+	return &pb.CancelReply{Itinerary: itineraryToPB(resp.Itinerary), Err: errToPB(resp.Error)}, nil
+}
+
+// EncodeGRPCCancelRequest converts a CancelRequest to a pb.CancelRequest.
+func EncodeGRPCCancelRequest(ctx context.Context, request interface{}) (interface{}, error) {
+	r := request.(CancelRequest)
+	return &pb.CancelRequest{
+		ItineraryId:        r.ItineraryId,
+		ConfirmationNumber: r.ConfirmationNumber,
+		Email:              r.Email,
+		Reason:             r.Reason,
+	}, nil
+}
+
+// DecodeGRPCCancelResponse converts a pb.CancelReply.
+func DecodeGRPCCancelResponse(ctx context.Context, response interface{}) (interface{}, error) {
+	reply := response.(*pb.CancelReply)
+	it, err := itineraryFromPB(reply.Itinerary)
+	if err != nil {
+		return nil, err
+	}
+	return CancelResponse{Itinerary: it, Error: errFromPB(reply.Err)}, nil
+}
+
+func rateBreakdownRequestToPB(r RateBreakdownRequest) *pb.RateBreakdownRequest {
+	return &pb.RateBreakdownRequest{
+		Arrival:   r.Arrival,
+		Departure: r.Departure,
+		Currency:  r.Currency,
+		PageToken: r.PageToken,
+		Channel:   r.Channel,
+	}
+}
+
+func rateBreakdownRequestFromPB(r *pb.RateBreakdownRequest) RateBreakdownRequest {
+	return RateBreakdownRequest{
+		Arrival:   r.Arrival,
+		Departure: r.Departure,
+		Currency:  r.Currency,
+		PageToken: r.PageToken,
+		Channel:   r.Channel,
+	}
+}
+
+func hotelRateToPB(r HotelRate) *pb.HotelRate {
+	rate := &pb.HotelRate{
+		Supplier:     r.Supplier,
+		HotelId:      r.HotelId,
+		HotelName:    r.HotelName,
+		CountryCode:  r.CountryCode,
+		ChainCode:    r.ChainCode,
+		RoomTypeCode: r.RoomTypeCode,
+		RateCode:     r.RateCode,
+		RateKey:      r.RateKey,
+		Total:        r.Total,
+		Currency:     r.Currency,
+		Converted:    moneyToPB(r.Converted),
+		Net:          moneyToPB(r.Net),
+		Sell:         moneyToPB(r.Sell),
+		PricingRules: r.PricingRules,
+	}
+	if p := r.CancellationPolicy; p != nil {
+		rate.CancellationPolicy = &pb.CancellationPolicy{
+			Refundable:      p.Refundable,
+			FreeCancelUntil: timeToPB(p.FreeCancelUntil),
+			Text:            p.Text,
+		}
+		for _, t := range p.Tiers {
+			rate.CancellationPolicy.Tiers = append(rate.CancellationPolicy.Tiers, &pb.PenaltyTier{
+				Start:   timeToPB(&t.Start),
+				Nights:  int32(t.Nights),
+				Amount:  moneyToPB(t.Amount),
+				Percent: t.Percent,
+			})
+		}
+	}
+	return rate
+}
+
+func hotelRateFromPB(r *pb.HotelRate) (rate HotelRate, err error) {
+	rate = HotelRate{
+		Supplier:     r.Supplier,
+		HotelId:      r.HotelId,
+		HotelName:    r.HotelName,
+		CountryCode:  r.CountryCode,
+		ChainCode:    r.ChainCode,
+		RoomTypeCode: r.RoomTypeCode,
+		RateCode:     r.RateCode,
+		RateKey:      r.RateKey,
+		Total:        r.Total,
+		Currency:     r.Currency,
+		PricingRules: r.PricingRules,
+	}
+	if rate.Converted, err = moneyFromPB(r.Converted); err != nil {
+		return rate, err
+	}
+	if rate.Net, err = moneyFromPB(r.Net); err != nil {
+		return rate, err
+	}
+	if rate.Sell, err = moneyFromPB(r.Sell); err != nil {
+		return rate, err
+	}
+	if p := r.CancellationPolicy; p != nil {
+		policy := CancellationPolicy{Refundable: p.Refundable, Text: p.Text}
+		if policy.FreeCancelUntil, err = timeFromPB(p.FreeCancelUntil); err != nil {
+			return rate, err
+		}
+		for _, t := range p.Tiers {
+			tier := PenaltyTier{Nights: int(t.Nights), Percent: t.Percent}
+			start, err := timeFromPB(t.Start)
+			if err != nil {
+				return rate, err
+			}
+			if start != nil {
+				tier.Start = *start
+			}
+			if tier.Amount, err = moneyFromPB(t.Amount); err != nil {
+				return rate, err
+			}
+			policy.Tiers = append(policy.Tiers, tier)
+		}
+		rate.CancellationPolicy = &policy
+	}
+	return rate, nil
+}
+
+func itineraryToPB(it Itinerary) *pb.Itinerary {
+	var rooms []*pb.ItineraryRoom
+	for _, r := range it.Rooms {
+		rooms = append(rooms, &pb.ItineraryRoom{ConfirmationNumber: r.ConfirmationNumber, Status: r.Status, CancellationNumber: r.CancellationNumber})
+	}
+	return &pb.Itinerary{
+		IdempotencyKey:      it.IdempotencyKey,
+		Supplier:            it.Supplier,
+		ItineraryId:         it.ItineraryId,
+		ConfirmationNumbers: it.ConfirmationNumbers,
+		CancellationNumber:  it.CancellationNumber,
+		Status:              it.Status,
+		HotelId:             it.HotelId,
+		Arrival:             it.Arrival,
+		Departure:           it.Departure,
+		Total:               it.Total,
+		Currency:            it.Currency,
+		Email:               it.Email,
+		Created:             timeToPB(&it.Created),
+		Updated:             timeToPB(&it.Updated),
+		Rooms:               rooms,
+	}
+}
+
+func itineraryFromPB(it *pb.Itinerary) (Itinerary, error) {
+	if it == nil {
+		return Itinerary{}, nil
+	}
+	r := Itinerary{
+		IdempotencyKey:      it.IdempotencyKey,
+		Supplier:            it.Supplier,
+		ItineraryId:         it.ItineraryId,
+		ConfirmationNumbers: it.ConfirmationNumbers,
+		CancellationNumber:  it.CancellationNumber,
+		Status:              it.Status,
+		HotelId:             it.HotelId,
+		Arrival:             it.Arrival,
+		Departure:           it.Departure,
+		Total:               it.Total,
+		Currency:            it.Currency,
+		Email:               it.Email,
+	}
+	for _, rm := range it.Rooms {
+		r.Rooms = append(r.Rooms, ItineraryRoom{ConfirmationNumber: rm.ConfirmationNumber, Status: rm.Status, CancellationNumber: rm.CancellationNumber})
+	}
+	for _, t := range []struct {
+		dst *time.Time
+		src string
+	}{{&r.Created, it.Created}, {&r.Updated, it.Updated}} {
+		v, err := timeFromPB(t.src)
+		if err != nil {
+			return r, err
+		}
+		if v != nil {
+			*t.dst = *v
+		}
+	}
+	return r, nil
+}
+
+func moneyToPB(m *currency.Money) *pb.Money {
+	if m == nil {
+		return nil
+	}
+	return &pb.Money{Amount: m.String(), Currency: m.Currency}
+}
+
+func moneyFromPB(m *pb.Money) (*currency.Money, error) {
+	if m == nil {
+		return nil, nil
+	}
+	v, err := currency.Parse(m.Amount, m.Currency)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// timeToPB formats t as RFC 3339, keeping its zone offset. Zero and nil
+// times are sent as "".
+func timeToPB(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+func timeFromPB(s string) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func errToPB(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func errFromPB(s string) error {
+	if s == "" {
+		return nil
+	}
+	return errors.New(s)
+}
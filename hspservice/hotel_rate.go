@@ -0,0 +1,28 @@
+package hspservice
+
+import "github.com/jbowles/hotel_supply_platform/currency"
+
+// HotelRate is a single supplier rate, normalized so rates from different
+// suppliers can be compared. Total is the decimal amount as the supplier sent
+// it, in Currency. Converted holds Total in the requested currency, when the
+// request asked for one and the rate could be converted. Net and Sell are set
+// by the pricing layer, along with the IDs of the PricingRules that took Net
+// to Sell. CancellationPolicy is set when the supplier sent one.
+type HotelRate struct {
+	Supplier     string          `json:"supplier" xml:"supplier"`
+	HotelId      string          `json:"hotel_id" xml:"hotel_id"`
+	HotelName    string          `json:"hotel_name,omitempty" xml:"hotel_name,omitempty"`
+	CountryCode  string          `json:"country_code,omitempty" xml:"country_code,omitempty"`
+	ChainCode    string          `json:"chain_code,omitempty" xml:"chain_code,omitempty"`
+	RoomTypeCode string          `json:"room_type_code,omitempty" xml:"room_type_code,omitempty"`
+	RateCode     string          `json:"rate_code,omitempty" xml:"rate_code,omitempty"`
+	RateKey      string          `json:"rate_key,omitempty" xml:"rate_key,omitempty"` // pass back in BookRequest
+	Total        string          `json:"total" xml:"total"`
+	Currency     string          `json:"currency" xml:"currency"`
+	Converted    *currency.Money `json:"converted,omitempty" xml:"converted,omitempty"`
+	Net          *currency.Money `json:"net,omitempty" xml:"net,omitempty"`
+	Sell         *currency.Money `json:"sell,omitempty" xml:"sell,omitempty"`
+	PricingRules []string        `json:"pricing_rules,omitempty" xml:"pricing_rules>rule,omitempty"`
+
+	CancellationPolicy *CancellationPolicy `json:"cancellation_policy,omitempty" xml:"cancellation_policy,omitempty"`
+}
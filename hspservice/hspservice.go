@@ -1,28 +1,97 @@
 package hspservice
 
-import "net/url"
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+)
 
 // Hsp is the abstract representation of the HotelSupplyPlatform service
 type Hsp interface {
 	RateBreakdown(r RateBreakdownRequest) RateBreakdownResponse
+	Book(r BookRequest) BookResponse
+	GetItinerary(r ItineraryRequest) ItineraryResponse
+	Cancel(r CancelRequest) CancelResponse
 	//ProviderSelection([]string) ([]string, error)
 	//Auction()
 	//RateValidation()
 	//HotelRateSearch()
 }
 
-// Affiliate interface defines two methods for all affiliate APIs.
-// Params builds and returns the full URI needed for making query.
+// Affiliate interface defines the methods for all affiliate APIs.
+// Name identifies the supplier, e.g. in page tokens and logs.
+// Params builds and returns the full URI needed for making query, or the error
+// that kept it from being built.
 // DateRange defines the range of dates for request.
 type Supplier interface {
-	Params() *url.URL
+	Name() string
+	Params() (*url.URL, error)
 	DateRange(days int)
 }
 
+// Poster is implemented by suppliers that POST a request document to the Params
+// URL (OTA, for example) instead of sending everything in the query string.
+type Poster interface {
+	Body() (contentType string, body []byte, err error)
+}
+
+// Pager is implemented by suppliers that page their results server side.
+// Page points the next Params call at the page the cursor refers to.
+// Criteria returns the supplier side search criteria, hotels and destination,
+// that a page token is bound to along with the request's; see SearchKey.
+type Pager interface {
+	Page(c Cursor) error
+	Criteria() []string
+}
+
 // Build is an exported function that implements the Affiliate interface.
 // It accepts and number of days from current date to define date range and returns
 // the URL needed to make the request.
-func Build(supplier Supplier, days int) *url.URL {
+func Build(supplier Supplier, days int) (*url.URL, error) {
 	supplier.DateRange(days)
 	return supplier.Params()
 }
+
+// BuildPage returns the URL for the follow up page identified by the page token
+// of r. The supplier must implement Pager, and the token must have been issued
+// for the search of r.
+func BuildPage(supplier Supplier, r RateBreakdownRequest) (*url.URL, error) {
+	p, ok := supplier.(Pager)
+	if !ok {
+		return nil, ErrInvalidPageToken
+	}
+	c, err := ParseCursor(r.PageToken)
+	if err != nil {
+		return nil, err
+	}
+	if c.Supplier != supplier.Name() || c.Search != r.SearchKey(p.Criteria()...) {
+		return nil, ErrInvalidPageToken
+	}
+	if err := p.Page(c); err != nil {
+		return nil, err
+	}
+	return supplier.Params()
+}
+
+// NewRequest builds the outbound HTTP request for a prepared supplier: a GET of
+// the Params URL, or a POST of the Body when the supplier is a Poster.
+func NewRequest(supplier Supplier) (*http.Request, error) {
+	u, err := supplier.Params()
+	if err != nil {
+		return nil, err
+	}
+	p, ok := supplier.(Poster)
+	if !ok {
+		return http.NewRequest("GET", u.String(), nil)
+	}
+	contentType, body, err := p.Body()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("POST", u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	return req, nil
+}
@@ -0,0 +1,260 @@
+package hspservice
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// HTTPError is a request the HTTP transport refuses, with the status to
+// answer it with.
+type HTTPError struct {
+	Status int
+	Err    error
+}
+
+func (e HTTPError) Error() string { return e.Err.Error() }
+
+// codec reads and writes the API types in one media type.
+type codec struct {
+	mediaType string
+	encode    func(w io.Writer, v interface{}) error
+	decode    func(r io.Reader, v interface{}) error
+}
+
+// codecs are the media types of the HTTP API, the default first.
+var codecs = []codec{
+	{"application/json", encodeJSON, decodeJSON},
+	{"application/xml", encodeXML, decodeXML},
+	{"text/xml", encodeXML, decodeXML},
+}
+
+func encodeJSON(w io.Writer, v interface{}) error { return json.NewEncoder(w).Encode(v) }
+func decodeJSON(r io.Reader, v interface{}) error { return json.NewDecoder(r).Decode(v) }
+
+func encodeXML(w io.Writer, v interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	if err := xml.NewEncoder(w).Encode(v); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func decodeXML(r io.Reader, v interface{}) error { return xml.NewDecoder(r).Decode(v) }
+
+func mediaTypes() string {
+	types := make([]string, len(codecs))
+	for i, c := range codecs {
+		types[i] = c.mediaType
+	}
+	return strings.Join(types, ", ")
+}
+
+// codecFor returns the codec of a Content-Type. An empty one is the default.
+func codecFor(contentType string) (codec, bool) {
+	if contentType == "" {
+		return codecs[0], true
+	}
+	t, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return codec{}, false
+	}
+	for _, c := range codecs {
+		if c.mediaType == t {
+			return c, true
+		}
+	}
+	return codec{}, false
+}
+
+// negotiate picks the response codec for the request's Accept header. Each
+// supported type takes the quality of the most specific range matching it, so
+// "application/json;q=0, */*" excludes JSON. The type with the highest
+// quality wins, then the one whose range comes first, then the default. It
+// returns a 406 HTTPError when no supported type is acceptable.
+func negotiate(r *http.Request) (codec, error) {
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return codecs[0], nil
+	}
+	type match struct {
+		q           float64
+		specificity int // 0 for */*, 1 for type/*, 2 for the exact type
+		pos         int // of the range in the header
+	}
+	matches := make([]match, len(codecs))
+	for i := range matches {
+		matches[i].specificity = -1
+	}
+	for pos, part := range strings.Split(accept, ",") {
+		t, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if s, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(s, 64); err != nil {
+				continue
+			}
+		}
+		for i, c := range codecs {
+			specificity := -1
+			switch t {
+			case c.mediaType:
+				specificity = 2
+			case strings.SplitN(c.mediaType, "/", 2)[0] + "/*":
+				specificity = 1
+			case "*/*":
+				specificity = 0
+			}
+			if specificity > matches[i].specificity {
+				matches[i] = match{q, specificity, pos}
+			}
+		}
+	}
+	best := -1
+	for i, m := range matches {
+		if m.specificity < 0 || m.q <= 0 {
+			continue
+		}
+		if best < 0 || m.q > matches[best].q || m.q == matches[best].q && m.pos < matches[best].pos {
+			best = i
+		}
+	}
+	if best < 0 {
+		return codec{}, HTTPError{http.StatusNotAcceptable, fmt.Errorf("cannot produce any of %q; supported: %s", accept, mediaTypes())}
+	}
+	return codecs[best], nil
+}
+
+type contextKey int
+
+const responseCodecKey contextKey = 0
+
+// NegotiateContentType picks the response media type from the request's
+// Accept header. It's designed to be used as a transport/http.Server before
+// func, with SetContentType as an after func; request decoders refuse
+// requests with no acceptable type.
+func NegotiateContentType(ctx context.Context, r *http.Request) context.Context {
+	if c, err := negotiate(r); err == nil {
+		ctx = context.WithValue(ctx, responseCodecKey, c)
+	}
+	return ctx
+}
+
+// SetContentType sets the Content-Type picked by NegotiateContentType, which
+// the response encoders then write. It's designed to be used as a
+// transport/http.Server after func.
+func SetContentType(ctx context.Context, w http.ResponseWriter) {
+	if c, ok := ctx.Value(responseCodecKey).(codec); ok {
+		w.Header().Set("Content-Type", c.mediaType)
+	}
+}
+
+// decodeRequest decodes the request body into request in the codec of its
+// Content-Type, after checking a response can be produced for it.
+func decodeRequest(r *http.Request, request interface{}) error {
+	if _, err := negotiate(r); err != nil {
+		return err
+	}
+	return decodeBody(r, request)
+}
+
+// decodeBody decodes the request body into request in the codec of its
+// Content-Type.
+func decodeBody(r *http.Request, request interface{}) error {
+	ct := r.Header.Get("Content-Type")
+	c, ok := codecFor(ct)
+	if !ok {
+		return HTTPError{http.StatusUnsupportedMediaType, fmt.Errorf("unsupported Content-Type %q; supported: %s", ct, mediaTypes())}
+	}
+	return c.decode(r.Body, request)
+}
+
+// encodeRequest encodes request as the JSON body of r and asks for a JSON
+// response.
+func encodeRequest(r *http.Request, request interface{}) error {
+	var buf bytes.Buffer
+	if err := encodeJSON(&buf, request); err != nil {
+		return err
+	}
+	r.Header.Set("Content-Type", codecs[0].mediaType)
+	r.Header.Set("Accept", codecs[0].mediaType)
+	r.Body = ioutil.NopCloser(&buf)
+	return nil
+}
+
+// decodeResponse decodes the response body into response in the codec of its
+// Content-Type.
+func decodeResponse(resp *http.Response, response interface{}) error {
+	ct := resp.Header.Get("Content-Type")
+	c, ok := codecFor(ct)
+	if !ok {
+		return fmt.Errorf("unsupported response Content-Type %q", ct)
+	}
+	return c.decode(resp.Body, response)
+}
+
+// encodeResponse writes response in the codec of the Content-Type set by
+// SetContentType, or the default.
+func encodeResponse(w http.ResponseWriter, response interface{}) error {
+	c, ok := codecFor(w.Header().Get("Content-Type"))
+	if !ok {
+		c = codecs[0]
+	}
+	w.Header().Set("Content-Type", c.mediaType)
+	return c.encode(w, response)
+}
+
+// queryFields lists the string fields of the struct type t that have a JSON
+// name, by that name. These are the fields a GET request takes as query
+// parameters, so a GET and a POST with a JSON body accept the same request.
+func queryFields(t reflect.Type) map[string]int {
+	fields := map[string]int{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if f.Type.Kind() != reflect.String || name == "" || name == "-" {
+			continue
+		}
+		fields[name] = i
+	}
+	return fields
+}
+
+// fromQuery sets the query fields of the struct v points to from q.
+// Parameters with no field are ignored, as unknown JSON keys are.
+func fromQuery(q url.Values, v interface{}) {
+	rv := reflect.ValueOf(v).Elem()
+	for name, i := range queryFields(rv.Type()) {
+		if s := q.Get(name); s != "" {
+			rv.Field(i).SetString(s)
+		}
+	}
+}
+
+// toQuery returns the non empty query fields of the struct v.
+func toQuery(v interface{}) url.Values {
+	rv := reflect.ValueOf(v)
+	q := url.Values{}
+	for name, i := range queryFields(rv.Type()) {
+		if s := rv.Field(i).String(); s != "" {
+			q.Set(name, s)
+		}
+	}
+	return q
+}
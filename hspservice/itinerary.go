@@ -0,0 +1,96 @@
+package hspservice
+
+import (
+	"errors"
+	"time"
+)
+
+// Itinerary statuses.
+const (
+	StatusPending   = "pending"
+	StatusConfirmed = "confirmed"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+)
+
+var (
+	// ErrNoIdempotencyKey is returned by Book when the request has no idempotency key.
+	ErrNoIdempotencyKey = errors.New("booking requires an idempotency key")
+	// ErrNoConfirmationNumber is returned by Cancel when the request names no
+	// room of an itinerary with several.
+	ErrNoConfirmationNumber = errors.New("cancelling a multi room itinerary requires a confirmation number")
+	// ErrNotSupported is returned by suppliers that do not implement an operation.
+	ErrNotSupported = errors.New("operation not supported by supplier")
+	// ErrItineraryNotFound is returned when no itinerary matches the request.
+	ErrItineraryNotFound = errors.New("itinerary not found")
+)
+
+// Itinerary is the record of a booking, as we keep it and return it to clients.
+// IdempotencyKey is the key the booking was made with; retries of the same
+// BookRequest return this Itinerary rather than booking again.
+// Rooms hold the state of each room booked; Status and ConfirmationNumbers
+// follow from them, see SetRooms.
+type Itinerary struct {
+	IdempotencyKey      string          `json:"idempotency_key" xml:"idempotency_key"`
+	Supplier            string          `json:"supplier" xml:"supplier"`
+	ItineraryId         string          `json:"itinerary_id,omitempty" xml:"itinerary_id,omitempty"`
+	ConfirmationNumbers []string        `json:"confirmation_numbers,omitempty" xml:"confirmation_numbers>confirmation_number,omitempty"`
+	Rooms               []ItineraryRoom `json:"rooms,omitempty" xml:"rooms>room,omitempty"`
+	CancellationNumber  string          `json:"cancellation_number,omitempty" xml:"cancellation_number,omitempty"`
+	Status              string          `json:"status" xml:"status"`
+	HotelId             string          `json:"hotel_id" xml:"hotel_id"`
+	Arrival             string          `json:"arrival" xml:"arrival"`
+	Departure           string          `json:"departure" xml:"departure"`
+	Total               string          `json:"total,omitempty" xml:"total,omitempty"`
+	Currency            string          `json:"currency,omitempty" xml:"currency,omitempty"`
+	Email               string          `json:"email" xml:"email"`
+	Created             time.Time       `json:"created" xml:"created"`
+	Updated             time.Time       `json:"updated" xml:"updated"`
+}
+
+// ItineraryRoom is the state of one room of an itinerary, identified by its
+// supplier confirmation number.
+type ItineraryRoom struct {
+	ConfirmationNumber string `json:"confirmation_number" xml:"confirmation_number"`
+	Status             string `json:"status" xml:"status"`
+	CancellationNumber string `json:"cancellation_number,omitempty" xml:"cancellation_number,omitempty"`
+}
+
+// SetRooms sets the rooms of the itinerary, and its ConfirmationNumbers and
+// Status from them: cancelled once every room is cancelled, else confirmed
+// while any room is, else pending while any room is, else failed. Without
+// rooms the Status is left as it is.
+func (it *Itinerary) SetRooms(rooms []ItineraryRoom) {
+	it.Rooms = rooms
+	it.ConfirmationNumbers = nil
+	count := map[string]int{}
+	for _, r := range rooms {
+		it.ConfirmationNumbers = append(it.ConfirmationNumbers, r.ConfirmationNumber)
+		count[r.Status]++
+	}
+	switch {
+	case len(rooms) == 0:
+	case count[StatusCancelled] == len(rooms):
+		it.Status = StatusCancelled
+	case count[StatusConfirmed] > 0:
+		it.Status = StatusConfirmed
+	case count[StatusPending] > 0:
+		it.Status = StatusPending
+	default:
+		it.Status = StatusFailed
+	}
+}
+
+// RoomList returns the rooms of the itinerary. Itineraries stored before
+// rooms were tracked have only ConfirmationNumbers; their rooms all have the
+// itinerary's Status.
+func (it Itinerary) RoomList() []ItineraryRoom {
+	if len(it.Rooms) > 0 || len(it.ConfirmationNumbers) == 0 {
+		return append([]ItineraryRoom(nil), it.Rooms...)
+	}
+	rooms := make([]ItineraryRoom, len(it.ConfirmationNumbers))
+	for i, n := range it.ConfirmationNumbers {
+		rooms[i] = ItineraryRoom{ConfirmationNumber: n, Status: it.Status}
+	}
+	return rooms
+}
@@ -0,0 +1,7 @@
+package hspservice
+
+// ItineraryRequest is the business domain type for a GetItinerary method request.
+type ItineraryRequest struct {
+	ItineraryId string `json:"itinerary_id" xml:"itinerary_id"`
+	Email       string `json:"email" xml:"email"`
+}
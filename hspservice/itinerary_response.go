@@ -0,0 +1,7 @@
+package hspservice
+
+// ItineraryResponse is the business domain type for a GetItinerary method response.
+type ItineraryResponse struct {
+	Itinerary Itinerary `json:"itinerary" xml:"itinerary"`
+	Error     error     `json:"error" xml:"-"`
+}
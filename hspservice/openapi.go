@@ -0,0 +1,188 @@
+package hspservice
+
+//go:generate go run ../cmd/openapi -o ../openapi.json
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jbowles/hotel_supply_platform/currency"
+)
+
+// OpenAPI returns the OpenAPI 3 spec of the HTTP API. The schemas are
+// generated from the request and response types, and the rate breakdown query
+// parameters from the same fields DecodeRateBreakdownRequest reads, so the
+// spec follows the code. openapi.json at the repository root is its output;
+// go generate rewrites it.
+func OpenAPI() ([]byte, error) {
+	s := schemas{}
+	// Transport errors are always written as JSON.
+	errorResponses := map[string]interface{}{}
+	for status, desc := range map[string]string{
+		"400": "The request could not be decoded.",
+		"405": "The method is not allowed.",
+		"406": "The Accept header allows none of the supported media types.",
+		"415": "The request body is not in a supported media type.",
+		"500": "The request failed.",
+	} {
+		errorResponses[status] = map[string]interface{}{
+			"description": desc,
+			"content":     map[string]interface{}{"application/json": map[string]interface{}{"schema": s.of(reflect.TypeOf(errorResponse{}))}},
+		}
+	}
+	operation := func(summary string, req, resp interface{}) map[string]interface{} {
+		responses := map[string]interface{}{"200": map[string]interface{}{"description": "OK", "content": content(s.of(reflect.TypeOf(resp)))}}
+		for status, r := range errorResponses {
+			responses[status] = r
+		}
+		op := map[string]interface{}{"summary": summary, "responses": responses}
+		if req != nil {
+			op["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content":  content(s.of(reflect.TypeOf(req))),
+			}
+		}
+		return op
+	}
+
+	var params []interface{}
+	fields := queryFields(reflect.TypeOf(RateBreakdownRequest{}))
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		params = append(params, map[string]interface{}{
+			"name":   name,
+			"in":     "query",
+			"schema": map[string]interface{}{"type": "string"},
+		})
+	}
+	rateBreakdownGet := operation("Rate breakdown, request in query parameters", nil, RateBreakdownResponse{})
+	rateBreakdownGet["parameters"] = params
+
+	spec := map[string]interface{}{
+		"openapi": "3.0.0",
+		"info":    map[string]interface{}{"title": "hsp", "version": "1"},
+		"paths": map[string]interface{}{
+			"/rate_breakdown": map[string]interface{}{
+				"get":  rateBreakdownGet,
+				"post": operation("Rate breakdown", RateBreakdownRequest{}, RateBreakdownResponse{}),
+			},
+			"/book":      map[string]interface{}{"post": operation("Book a rate", BookRequest{}, BookResponse{})},
+			"/itinerary": map[string]interface{}{"post": operation("Look up an itinerary", ItineraryRequest{}, ItineraryResponse{})},
+			"/cancel":    map[string]interface{}{"post": operation("Cancel a booking", CancelRequest{}, CancelResponse{})},
+		},
+		"components": map[string]interface{}{"schemas": s},
+	}
+	b, err := json.MarshalIndent(spec, "", "  ")
+	return append(b, '\n'), err
+}
+
+// errorResponse is the body the transport writes for requests it refuses.
+type errorResponse struct {
+	Error Error `json:"error"`
+}
+
+// content lists schema under each media type of the API.
+func content(schema interface{}) map[string]interface{} {
+	c := map[string]interface{}{}
+	for _, codec := range codecs {
+		c[codec.mediaType] = map[string]interface{}{"schema": schema}
+	}
+	return c
+}
+
+// schemas collects the component schemas of the named types it is asked for.
+type schemas map[string]interface{}
+
+var (
+	timeType  = reflect.TypeOf(time.Time{})
+	moneyType = reflect.TypeOf(currency.Money{})
+	errorType = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// of returns the schema of t, as a reference for named struct types.
+func (s schemas) of(t reflect.Type) map[string]interface{} {
+	if t == errorType {
+		return s.of(reflect.TypeOf(Error{}))
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t {
+	case timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case moneyType:
+		s["Money"] = map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"amount":   map[string]interface{}{"type": "string", "description": "Decimal amount in major units."},
+				"currency": map[string]interface{}{"type": "string"},
+			},
+			"required": []string{"amount", "currency"},
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/Money"}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": s.of(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": s.of(t.Elem())}
+	case reflect.Struct:
+		name := strings.ToUpper(t.Name()[:1]) + t.Name()[1:]
+		if _, ok := s[name]; !ok {
+			s[name] = nil // placeholder, for recursive types
+			s[name] = s.object(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	}
+	return map[string]interface{}{}
+}
+
+// object returns the schema of struct type t, following encoding/json's
+// naming: the tag name, or the field name when there is none.
+func (s schemas) object(t reflect.Type) map[string]interface{} {
+	props := map[string]interface{}{}
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		tag := strings.Split(f.Tag.Get("json"), ",")
+		name := tag[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = s.of(f.Type)
+		omitempty := false
+		for _, opt := range tag[1:] {
+			omitempty = omitempty || opt == "omitempty"
+		}
+		if !omitempty {
+			required = append(required, name)
+		}
+	}
+	o := map[string]interface{}{"type": "object", "properties": props}
+	if len(required) > 0 {
+		o["required"] = required
+	}
+	return o
+}
@@ -0,0 +1,40 @@
+package hspservice
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+)
+
+func TestOpenAPIUpToDate(t *testing.T) {
+	spec, err := OpenAPI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, err := ioutil.ReadFile("../openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(current, spec) {
+		t.Error("openapi.json is out of date; run go generate ./hspservice")
+	}
+}
+
+func TestOpenAPIPaths(t *testing.T) {
+	spec, err := OpenAPI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"/rate_breakdown", "/book", "/itinerary", "/cancel"} {
+		if _, ok := doc.Paths[path]["post"]; !ok {
+			t.Errorf("no POST %s in the spec", path)
+		}
+	}
+}
@@ -1,45 +1,58 @@
 package hspservice
 
 import (
-	"bytes"
-	"encoding/json"
-	"io/ioutil"
+	"fmt"
 	"net/http"
 )
 
-// DecodeRateBreakdownRequest decodes the request from the provided HTTP request, simply
-// by JSON decoding from the request body. It's designed to be used in
-// transport/http.Server.
+// The rate breakdown HTTP contract: GET with the request in query parameters
+// named like its JSON fields, or POST with the request as the body, in any
+// codec the API speaks. The response is written in the media type negotiated
+// from Accept; a request that accepts none of them is refused with 406, and a
+// POST body of another type with 415.
+
+// DecodeRateBreakdownRequest decodes the request from the provided HTTP request,
+// from the query string of a GET or the body of a POST. It's designed to be
+// used in transport/http.Server.
 func DecodeRateBreakdownRequest(r *http.Request) (interface{}, error) {
 	var request RateBreakdownRequest
-	err := json.NewDecoder(r.Body).Decode(&request)
-	return request, err
+	switch r.Method {
+	case "GET", "HEAD":
+		_, err := negotiate(r)
+		fromQuery(r.URL.Query(), &request)
+		return request, err
+	case "POST":
+		err := decodeRequest(r, &request)
+		return request, err
+	default:
+		return request, HTTPError{http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method)}
+	}
 }
 
-// EncodeRateBreakdownRequest encodes the request to the provided HTTP request, simply
-// by JSON encoding to the request body. It's designed to be used in
-// transport/http.Client.
+// EncodeRateBreakdownRequest encodes the request to the provided HTTP request:
+// as query parameters when it is a GET, otherwise as a JSON body. It's designed
+// to be used in transport/http.Client.
 func EncodeRateBreakdownRequest(r *http.Request, request interface{}) error {
-	var buf bytes.Buffer
-	if err := json.NewEncoder(&buf).Encode(request); err != nil {
-		return err
+	if r.Method == "GET" {
+		r.Header.Set("Accept", codecs[0].mediaType)
+		r.URL.RawQuery = toQuery(request.(RateBreakdownRequest)).Encode()
+		return nil
 	}
-	r.Body = ioutil.NopCloser(&buf)
-	return nil
+	return encodeRequest(r, request)
 }
 
 // DecodeRateBreakdownResponse decodes the response from the provided HTTP response,
-// simply by JSON decoding from the response body. It's designed to be used in
+// in the codec of its Content-Type. It's designed to be used in
 // transport/http.Client.
 func DecodeRateBreakdownResponse(resp *http.Response) (interface{}, error) {
 	var response RateBreakdownResponse
-	err := json.NewDecoder(resp.Body).Decode(&response)
+	err := decodeResponse(resp, &response)
 	return response, err
 }
 
 // EncodeRateBreakdownResponse encodes the response to the provided HTTP response
-// writer, simply by JSON encoding to the writer. It's designed to be used in
-// transport/http.Server.
+// writer, in the media type negotiated by NegotiateContentType. It's designed
+// to be used in transport/http.Server.
 func EncodeRateBreakdownResponse(w http.ResponseWriter, response interface{}) error {
-	return json.NewEncoder(w).Encode(response)
+	return encodeResponse(w, response)
 }
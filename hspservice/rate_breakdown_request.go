@@ -1,13 +1,14 @@
 package hspservice
 
-import "net/url"
-
 // RateBreakdownRequest is the business domain type for a RateBreakdown method request.
+// It is the public request as clients send it; how it is sent on to suppliers
+// is internal, see SupplierCall.
 type RateBreakdownRequest struct {
 	//Arrival   time.Time `json:"arrival"`
 	//Departure time.Time `json:"departure"`
-	RequestUrl *url.URL
-	Arrival    string `json:"arrival"`
-	Departure  string `json:"departure"`
-	Currency   string `json:"currency"`
+	Arrival   string `json:"arrival" xml:"arrival"`
+	Departure string `json:"departure" xml:"departure"`
+	Currency  string `json:"currency" xml:"currency"`
+	PageToken string `json:"page_token,omitempty" xml:"page_token,omitempty"` // NextPageToken of a previous response
+	Channel   string `json:"channel,omitempty" xml:"channel,omitempty"`       // sales channel, matched by pricing rules
 }
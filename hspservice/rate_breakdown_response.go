@@ -1,7 +1,12 @@
 package hspservice
 
 // RateBreakdownResponse is the business domain type for a RateBreakdownService method response.
+// NextPageToken is empty when the supplier has no more results. Trace is only
+// set in debug mode.
 type RateBreakdownResponse struct {
-	Request RateBreakdownRequest
-	Error   error `json:"error"`
+	Request       RateBreakdownRequest `json:"request" xml:"request"`
+	Rates         []HotelRate          `json:"rates,omitempty" xml:"rates>rate,omitempty"`
+	NextPageToken string               `json:"next_page_token,omitempty" xml:"next_page_token,omitempty"`
+	Trace         []SupplierCall       `json:"trace,omitempty" xml:"trace>call,omitempty"`
+	Error         error                `json:"error" xml:"-"`
 }
@@ -0,0 +1,118 @@
+package hspservice
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"golang.org/x/net/context"
+)
+
+// RateSink takes the rates of a rate breakdown one at a time. A service
+// running a rate breakdown in a context with a sink hands each rate to it as
+// soon as the supplier's response yields it, instead of gathering them in the
+// response's Rates, so rates reach the caller while the rest of the response
+// is still arriving and a page is never held whole. Services that cannot
+// stream return their rates in Rates as usual. An error from the sink stops
+// the rate breakdown, which fails with it.
+type RateSink func(HotelRate) error
+
+const rateSinkKey contextKey = 2
+
+// NewRateSinkContext returns ctx carrying the rate sink sink.
+func NewRateSinkContext(ctx context.Context, sink RateSink) context.Context {
+	return context.WithValue(ctx, rateSinkKey, sink)
+}
+
+// RateSinkFromContext returns the rate sink in ctx, or nil.
+func RateSinkFromContext(ctx context.Context) RateSink {
+	sink, _ := ctx.Value(rateSinkKey).(RateSink)
+	return sink
+}
+
+// The rate stream HTTP contract: the request is the rate breakdown one, GET
+// or POST, but the response is a stream of JSON lines, whatever the Accept
+// header. Each rate is a line {"rate": ...}, written as soon as the service
+// hands it on; the last line is {"response": ...}, the response with the
+// rates the service did not stream, its page token and error.
+
+// RateStreamMediaType is the Content-Type of a rate stream.
+const RateStreamMediaType = "application/x-ndjson"
+
+// rateStreamLine is a line of a rate stream.
+type rateStreamLine struct {
+	Rate     *HotelRate             `json:"rate,omitempty"`
+	Response *RateBreakdownResponse `json:"response,omitempty"`
+}
+
+// DecodeRateStreamRequest decodes a rate breakdown request as
+// DecodeRateBreakdownRequest does, without negotiating the response type.
+func DecodeRateStreamRequest(r *http.Request) (request RateBreakdownRequest, err error) {
+	switch r.Method {
+	case "GET", "HEAD":
+		fromQuery(r.URL.Query(), &request)
+	case "POST":
+		err = decodeBody(r, &request)
+	default:
+		err = HTTPError{http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method)}
+	}
+	return request, err
+}
+
+// RateStreamWriter writes a rate stream to an HTTP response, flushing every
+// line.
+type RateStreamWriter struct {
+	w   http.ResponseWriter
+	enc *json.Encoder
+}
+
+// NewRateStreamWriter returns a RateStreamWriter writing to w.
+func NewRateStreamWriter(w http.ResponseWriter) *RateStreamWriter {
+	w.Header().Set("Content-Type", RateStreamMediaType)
+	return &RateStreamWriter{w, json.NewEncoder(w)}
+}
+
+// Rate writes the line of rate. It is a RateSink.
+func (s *RateStreamWriter) Rate(rate HotelRate) error {
+	return s.line(rateStreamLine{Rate: &rate})
+}
+
+// End writes the last line, of response.
+func (s *RateStreamWriter) End(response RateBreakdownResponse) error {
+	return s.line(rateStreamLine{Response: &response})
+}
+
+func (s *RateStreamWriter) line(l rateStreamLine) error {
+	if err := s.enc.Encode(l); err != nil {
+		return err
+	}
+	if f, ok := s.w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
+
+// DecodeRateStream reads a rate stream from r, calling rate with each rate as
+// it is read, and returns the response of its last line. A stream that ends
+// before that line is an io.ErrUnexpectedEOF.
+func DecodeRateStream(r io.Reader, rate func(HotelRate) error) (RateBreakdownResponse, error) {
+	d := json.NewDecoder(r)
+	for {
+		var l rateStreamLine
+		if err := d.Decode(&l); err == io.EOF {
+			return RateBreakdownResponse{}, io.ErrUnexpectedEOF
+		} else if err != nil {
+			return RateBreakdownResponse{}, err
+		}
+		if l.Response != nil {
+			return *l.Response, nil
+		}
+		if l.Rate == nil {
+			continue
+		}
+		if err := rate(*l.Rate); err != nil {
+			return RateBreakdownResponse{}, err
+		}
+	}
+}
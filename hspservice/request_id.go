@@ -0,0 +1,109 @@
+package hspservice
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/metadata"
+)
+
+// RequestIDHeader carries the id correlating the logs of a request across
+// hsp instances and suppliers.
+const RequestIDHeader = "X-Request-Id"
+
+// maxRequestIDLen bounds the ids accepted from callers, as they are logged
+// and sent on to suppliers.
+const maxRequestIDLen = 128
+
+const requestIDKey contextKey = 1
+
+// NewRequestID returns a random request id, 32 hex digits.
+func NewRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// NewRequestIDContext returns ctx carrying the request id id.
+func NewRequestIDContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestIDFromContext returns the request id in ctx, or "".
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// EnsureRequestID returns ctx with a new request id if it has none.
+func EnsureRequestID(ctx context.Context) context.Context {
+	if RequestIDFromContext(ctx) != "" {
+		return ctx
+	}
+	return NewRequestIDContext(ctx, NewRequestID())
+}
+
+// validRequestID reports whether a caller's id can be used as is: short, and
+// printable ASCII without spaces, so it can be logged and put in a query.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// RequestIDToContext puts the request id of the X-Request-Id header in the
+// context, or a new one when the header is missing or unusable. It's designed
+// to be used as a transport/http.Server before func, with SetRequestIDHeader
+// as an after func.
+func RequestIDToContext(ctx context.Context, r *http.Request) context.Context {
+	if id := r.Header.Get(RequestIDHeader); validRequestID(id) {
+		return NewRequestIDContext(ctx, id)
+	}
+	return NewRequestIDContext(ctx, NewRequestID())
+}
+
+// SetRequestIDHeader echoes the request id in the response, so callers can
+// quote it. It's designed to be used as a transport/http.Server after func.
+func SetRequestIDHeader(ctx context.Context, w http.ResponseWriter) {
+	if id := RequestIDFromContext(ctx); id != "" {
+		w.Header().Set(RequestIDHeader, id)
+	}
+}
+
+// RequestIDToHTTP sets the X-Request-Id header of r from ctx, so the service
+// called logs under the same id. It's designed to be used as a
+// transport/http.Client before func.
+func RequestIDToHTTP(ctx context.Context, r *http.Request) context.Context {
+	if id := RequestIDFromContext(ctx); id != "" {
+		r.Header.Set(RequestIDHeader, id)
+	}
+	return ctx
+}
+
+// RequestIDToGRPCContext is RequestIDToContext for gRPC: it reads the request
+// id from the x-request-id metadata of the call. It's designed to be used as a
+// transport/grpc.Server before func.
+func RequestIDToGRPCContext(ctx context.Context, md *metadata.MD) context.Context {
+	if ids := (*md)[strings.ToLower(RequestIDHeader)]; len(ids) > 0 && validRequestID(ids[0]) {
+		return NewRequestIDContext(ctx, ids[0])
+	}
+	return NewRequestIDContext(ctx, NewRequestID())
+}
+
+// RequestIDToGRPC sets the x-request-id metadata of the call from ctx. It's
+// designed to be used as a transport/grpc.Client before func.
+func RequestIDToGRPC(ctx context.Context, md *metadata.MD) context.Context {
+	if id := RequestIDFromContext(ctx); id != "" {
+		(*md)[strings.ToLower(RequestIDHeader)] = []string{id}
+	}
+	return ctx
+}
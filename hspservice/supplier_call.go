@@ -0,0 +1,75 @@
+package hspservice
+
+import (
+	"net/url"
+	"time"
+)
+
+// SupplierCall is a record of a request made to a supplier, returned in
+// RateBreakdownResponse.Trace when the service runs in debug mode. URL is
+// redacted; request bodies are never recorded, as they may hold credentials.
+type SupplierCall struct {
+	Supplier string `json:"supplier" xml:"supplier"`
+	Method   string `json:"method" xml:"method"`
+	URL      string `json:"url" xml:"url"`
+	Took     string `json:"took" xml:"took"`
+	Error    string `json:"error,omitempty" xml:"error,omitempty"`
+}
+
+// NewSupplierCall records a call to supplier that began at begin and ended
+// now with err.
+func NewSupplierCall(supplier, method string, u *url.URL, begin time.Time, err error) SupplierCall {
+	c := SupplierCall{
+		Supplier: supplier,
+		Method:   method,
+		URL:      RedactURL(u),
+		Took:     time.Since(begin).String(),
+	}
+	if err != nil {
+		c.Error = RedactError(err).Error()
+	}
+	return c
+}
+
+// credentialParams are the query parameters suppliers authenticate with.
+var credentialParams = []string{"apiKey", "cid", "sig", "password", "secret", "token"}
+
+// RedactURL returns u as a string with credential query parameters and user
+// info replaced by "REDACTED".
+func RedactURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	r := *u
+	if r.User != nil {
+		r.User = url.User("REDACTED")
+	}
+	q := r.Query()
+	RedactValues(q)
+	r.RawQuery = q.Encode()
+	return r.String()
+}
+
+// RedactValues replaces the credential parameters of v, a query or a form
+// body, with "REDACTED".
+func RedactValues(v url.Values) {
+	for _, p := range credentialParams {
+		if _, ok := v[p]; ok {
+			v.Set(p, "REDACTED")
+		}
+	}
+}
+
+// RedactError returns err with the URL of a *url.Error redacted, so transport
+// errors can be echoed and logged. Other errors are returned as they are.
+func RedactError(err error) error {
+	e, ok := err.(*url.Error)
+	if !ok {
+		return err
+	}
+	u, perr := url.Parse(e.URL)
+	if perr != nil {
+		return &url.Error{Op: e.Op, URL: "REDACTED", Err: e.Err}
+	}
+	return &url.Error{Op: e.Op, URL: RedactURL(u), Err: e.Err}
+}
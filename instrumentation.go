@@ -0,0 +1,215 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/prometheus"
+	kitratelimit "github.com/go-kit/kit/ratelimit"
+	"github.com/jbowles/hotel_supply_platform/booking"
+	"github.com/jbowles/hotel_supply_platform/hspservice"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+	"github.com/sony/gobreaker"
+	"golang.org/x/net/context"
+)
+
+const (
+	metricsNamespace = "partnerFusion"
+	metricsSubsystem = "hps_service"
+)
+
+// stackMetrics are the metrics of the whole stack, from the HTTP transports
+// down to the supplier instances. They are built once in main and served by
+// the debug server on /metrics.
+type stackMetrics struct {
+	requestDuration metrics.TimeHistogram // method, error
+	requestCount    metrics.Counter       // method, status
+	hotelsReturned  metrics.Histogram     // method
+	supplierCalls   metrics.Counter       // supplier, instance, result
+	supplierLatency metrics.TimeHistogram // supplier, instance
+	breakerChanges  metrics.Counter       // instance, from, to
+	rateLimited     metrics.Counter       // instance
+	cacheLookups    metrics.Counter       // cache, result
+	supplierConns   metrics.Counter       // host, reused
+	supplierHedges  metrics.Counter       // host, result
+}
+
+func newStackMetrics(requestDuration metrics.TimeHistogram) stackMetrics {
+	return stackMetrics{
+		requestDuration: requestDuration,
+		requestCount: prometheus.NewCounter(stdprometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "request_count",
+			Help:      "HTTP requests served, by route and status code.",
+		}, []string{"method", "status"}),
+		hotelsReturned: prometheus.NewHistogram(stdprometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "hotels_returned",
+			Help:      "Distinct hotels in each rate breakdown response.",
+			Buckets:   stdprometheus.ExponentialBuckets(1, 2, 10),
+		}, []string{"method"}),
+		supplierCalls: prometheus.NewCounter(stdprometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "supplier_calls",
+			Help:      "Calls to supplier instances, by result: ok, error or timeout.",
+		}, []string{"supplier", "instance", "result"}),
+		supplierLatency: metrics.NewTimeHistogram(time.Millisecond, prometheus.NewHistogram(stdprometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "supplier_latency_ms",
+			Help:      "Supplier call latency in milliseconds.",
+			Buckets:   stdprometheus.ExponentialBuckets(10, 2, 10),
+		}, []string{"supplier", "instance"})),
+		breakerChanges: prometheus.NewCounter(stdprometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "breaker_state_changes",
+			Help:      "Circuit breaker state changes, by instance.",
+		}, []string{"instance", "from", "to"}),
+		rateLimited: prometheus.NewCounter(stdprometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "rate_limited",
+			Help:      "Supplier calls rejected by the rate limiter, by instance.",
+		}, []string{"instance"}),
+		cacheLookups: prometheus.NewCounter(stdprometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "cache_lookups",
+			Help:      "Cache lookups by cache and result, hit or miss.",
+		}, []string{"cache", "result"}),
+		supplierConns: prometheus.NewCounter(stdprometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "supplier_connections",
+			Help:      "Connections supplier calls were sent on, by host and whether they were reused from the pool.",
+		}, []string{"host", "reused"}),
+		supplierHedges: prometheus.NewCounter(stdprometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "supplier_hedges",
+			Help:      "Hedged supplier requests by host and result: sent, or won when the hedge answered first.",
+		}, []string{"host", "result"}),
+	}
+}
+
+// instrumentHandler counts the requests mux serves by the pattern they
+// matched, so unknown paths do not add label values, and status code.
+func (m stackMetrics) instrumentHandler(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		mux.ServeHTTP(rec, r)
+		_, pattern := mux.Handler(r)
+		if pattern == "" {
+			pattern = "unmatched"
+		}
+		m.requestCount.With(metrics.Field{Key: "method", Value: pattern}).
+			With(metrics.Field{Key: "status", Value: strconv.Itoa(rec.status)}).Add(1)
+	})
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// observeSupplierCall records a supplier call; see supplierHealth.
+func (m stackMetrics) observeSupplierCall(i *supplierInstance, took time.Duration, err error) {
+	supplier := metrics.Field{Key: "supplier", Value: i.Supplier}
+	instance := metrics.Field{Key: "instance", Value: i.Instance}
+	m.supplierLatency.With(supplier).With(instance).Observe(took)
+	m.supplierCalls.With(supplier).With(instance).With(metrics.Field{Key: "result", Value: callResult(err)}).Add(1)
+}
+
+func callResult(err error) string {
+	if err == nil {
+		return "ok"
+	}
+	if err == context.DeadlineExceeded {
+		return "timeout"
+	}
+	if e, ok := err.(net.Error); ok && e.Timeout() {
+		return "timeout"
+	}
+	return "error"
+}
+
+// breakerStateChange counts the state changes of a gobreaker. It's designed
+// to be used as gobreaker.Settings.OnStateChange; the breaker name is the
+// instance.
+func (m stackMetrics) breakerStateChange(name string, from, to gobreaker.State) {
+	m.breakerChanges.With(metrics.Field{Key: "instance", Value: name}).
+		With(metrics.Field{Key: "from", Value: breakerStates[from]}).
+		With(metrics.Field{Key: "to", Value: breakerStates[to]}).Add(1)
+}
+
+// countRateLimited returns an endpoint.Middleware counting the calls to
+// instance a rate limiter below it rejects.
+func (m stackMetrics) countRateLimited(instance string) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			response, err := next(ctx, request)
+			if err == kitratelimit.ErrLimited {
+				m.rateLimited.With(metrics.Field{Key: "instance", Value: instance}).Add(1)
+			}
+			return response, err
+		}
+	}
+}
+
+// observeHotels records the distinct hotels of a rate breakdown.
+func observeHotels(h metrics.Histogram, method string, hotels int) {
+	h.With(metrics.Field{Key: "method", Value: method}).Observe(int64(hotels))
+}
+
+// rateCount counts the rates of a rate breakdown and their distinct hotels,
+// the ones streamed and the ones returned.
+type rateCount struct {
+	rates  int
+	hotels map[string]bool
+}
+
+func (c *rateCount) add(r *hspservice.HotelRate) {
+	if c.hotels == nil {
+		c.hotels = map[string]bool{}
+	}
+	c.rates++
+	c.hotels[r.HotelId] = true
+}
+
+func (c *rateCount) addAll(rates []hspservice.HotelRate) {
+	for i := range rates {
+		c.add(&rates[i])
+	}
+}
+
+// instrumentedRepository counts idempotency key lookups: a Book retried with a
+// key already reserved is a hit.
+type instrumentedRepository struct {
+	booking.Repository
+	lookups metrics.Counter
+}
+
+func (r instrumentedRepository) Reserve(it hspservice.Itinerary) (hspservice.Itinerary, bool, error) {
+	stored, fresh, err := r.Repository.Reserve(it)
+	if err == nil {
+		result := "hit"
+		if fresh {
+			result = "miss"
+		}
+		r.lookups.With(metrics.Field{Key: "cache", Value: "idempotency"}).With(metrics.Field{Key: "result", Value: result}).Add(1)
+	}
+	return stored, fresh, err
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics"
+	kitratelimit "github.com/go-kit/kit/ratelimit"
+	"github.com/jbowles/hotel_supply_platform/booking"
+	"github.com/jbowles/hotel_supply_platform/hspservice"
+	"github.com/sony/gobreaker"
+	"golang.org/x/net/context"
+)
+
+// observed keeps what the fake metrics record, by series: the metric name
+// and its labels, as name{key=value,...} in the order the keys were declared.
+type observed struct {
+	mtx    sync.Mutex
+	counts map[string]uint64
+	values map[string][]int64
+}
+
+func newObserved() *observed {
+	return &observed{counts: map[string]uint64{}, values: map[string][]int64{}}
+}
+
+func (o *observed) count(series string) uint64 {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	return o.counts[series]
+}
+
+func (o *observed) observations(series string) []int64 {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	return o.values[series]
+}
+
+// series returns every series recorded under name, sorted.
+func (o *observed) series(name string) []string {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	var s []string
+	for k := range o.counts {
+		if strings.HasPrefix(k, name+"{") {
+			s = append(s, k)
+		}
+	}
+	for k := range o.values {
+		if strings.HasPrefix(k, name+"{") {
+			s = append(s, k)
+		}
+	}
+	sort.Strings(s)
+	return s
+}
+
+// labels are the labels of a fake metric. As with the Prometheus metrics,
+// fields with keys the metric was not declared with are dropped, and declared
+// keys never set are unknown.
+type labels struct {
+	name  string
+	keys  []string
+	pairs map[string]string
+}
+
+func (l labels) with(f metrics.Field) labels {
+	pairs := map[string]string{}
+	for k, v := range l.pairs {
+		pairs[k] = v
+	}
+	for _, k := range l.keys {
+		if k == f.Key {
+			pairs[k] = f.Value
+		}
+	}
+	l.pairs = pairs
+	return l
+}
+
+func (l labels) String() string {
+	kv := make([]string, len(l.keys))
+	for i, k := range l.keys {
+		v, ok := l.pairs[k]
+		if !ok {
+			v = "unknown"
+		}
+		kv[i] = k + "=" + v
+	}
+	return l.name + "{" + strings.Join(kv, ",") + "}"
+}
+
+type fakeCounter struct {
+	labels
+	o *observed
+}
+
+func (c fakeCounter) Name() string                         { return c.name }
+func (c fakeCounter) With(f metrics.Field) metrics.Counter { return fakeCounter{c.with(f), c.o} }
+
+func (c fakeCounter) Add(delta uint64) {
+	c.o.mtx.Lock()
+	c.o.counts[c.String()] += delta
+	c.o.mtx.Unlock()
+}
+
+type fakeHistogram struct {
+	labels
+	o *observed
+}
+
+func (h fakeHistogram) Name() string                           { return h.name }
+func (h fakeHistogram) With(f metrics.Field) metrics.Histogram { return fakeHistogram{h.with(f), h.o} }
+
+func (h fakeHistogram) Observe(value int64) {
+	h.o.mtx.Lock()
+	h.o.values[h.String()] = append(h.o.values[h.String()], value)
+	h.o.mtx.Unlock()
+}
+
+// fakeTimeHistogram observes durations in milliseconds.
+type fakeTimeHistogram struct{ fakeHistogram }
+
+func (h fakeTimeHistogram) With(f metrics.Field) metrics.TimeHistogram {
+	return fakeTimeHistogram{fakeHistogram{h.with(f), h.o}}
+}
+
+func (h fakeTimeHistogram) Observe(d time.Duration) {
+	h.fakeHistogram.Observe(int64(d / time.Millisecond))
+}
+
+// fakeStackMetrics returns stack metrics recording to o, declared with the
+// labels main declares them with.
+func fakeStackMetrics(o *observed) stackMetrics {
+	counter := func(name string, keys ...string) metrics.Counter {
+		return fakeCounter{labels{name: name, keys: keys}, o}
+	}
+	histogram := func(name string, keys ...string) fakeHistogram {
+		return fakeHistogram{labels{name: name, keys: keys}, o}
+	}
+	return stackMetrics{
+		requestDuration: fakeTimeHistogram{histogram("duration_ns", "method")},
+		requestCount:    counter("request_count", "method", "status"),
+		hotelsReturned:  histogram("hotels_returned", "method"),
+		supplierCalls:   counter("supplier_calls", "supplier", "instance", "result"),
+		supplierLatency: fakeTimeHistogram{histogram("supplier_latency_ms", "supplier", "instance")},
+		breakerChanges:  counter("breaker_state_changes", "instance", "from", "to"),
+		rateLimited:     counter("rate_limited", "instance"),
+		cacheLookups:    counter("cache_lookups", "cache", "result"),
+		supplierConns:   counter("supplier_connections", "host", "reused"),
+		supplierHedges:  counter("supplier_hedges", "host", "result"),
+	}
+}
+
+func TestRequestMetrics(t *testing.T) {
+	o := newObserved()
+	m := fakeStackMetrics(o)
+	svc := instrumentingMiddleware(m.requestDuration, m.hotelsReturned, "ean")(newGrpcFake())
+	mux := http.NewServeMux()
+	mux.Handle("/rate_breakdown", newHTTPHandler(context.Background(), "rate_breakdown", makeRateBreakdownEndpoint(svc),
+		hspservice.DecodeRateBreakdownRequest, hspservice.EncodeRateBreakdownResponse, nil, log.NewNopLogger()))
+	srv := httptest.NewServer(m.instrumentHandler(mux))
+	defer srv.Close()
+
+	for _, path := range []string{"/rate_breakdown?currency=EUR", "/rate_breakdown?currency=USD", "/unknown/1", "/unknown/2"} {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	// Unknown paths share a label value rather than adding one each. A
+	// request is counted once it is served, which may be after its client
+	// has the response.
+	want := []string{"request_count{method=/rate_breakdown,status=200}", "request_count{method=unmatched,status=404}"}
+	waitFor(t, "the requests counted", func() bool { return o.count(want[0])+o.count(want[1]) == 4 })
+	if got := o.series("request_count"); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("request count series %v, want %v", got, want)
+	}
+	for _, series := range want {
+		if n := o.count(series); n != 2 {
+			t.Errorf("%s = %d, want 2", series, n)
+		}
+	}
+	if got := o.observations("hotels_returned{method=ean_rate_breakdown}"); len(got) != 2 || got[0] != 1 || got[1] != 1 {
+		t.Errorf("hotels returned %v, want 1 hotel for each request; series %v", got, o.series("hotels_returned"))
+	}
+	if got := o.observations("duration_ns{method=ean_rate_breakdown}"); len(got) != 2 {
+		t.Errorf("%d durations observed, want 2; series %v", len(got), o.series("duration_ns"))
+	}
+}
+
+func TestSupplierMetrics(t *testing.T) {
+	o := newObserved()
+	m := fakeStackMetrics(o)
+
+	// Calls reach the metrics through the supplier health, as in main.
+	health := newSupplierHealth(m.observeSupplierCall)
+	i := health.Instance("ean", "10.0.0.1:8080")
+	i.Observe(12*time.Millisecond, nil)
+	i.Observe(30*time.Millisecond, context.DeadlineExceeded)
+	i.Observe(5*time.Millisecond, errors.New("refused"))
+	for _, result := range []string{"ok", "timeout", "error"} {
+		series := "supplier_calls{supplier=ean,instance=10.0.0.1:8080,result=" + result + "}"
+		if n := o.count(series); n != 1 {
+			t.Errorf("%s = %d, want 1", series, n)
+		}
+	}
+	if got := o.observations("supplier_latency_ms{supplier=ean,instance=10.0.0.1:8080}"); len(got) != 3 || got[0] != 12 {
+		t.Errorf("supplier latencies %v, want the 3 calls'", got)
+	}
+
+	m.breakerStateChange("10.0.0.1:8080", gobreaker.StateClosed, gobreaker.StateOpen)
+	if n := o.count("breaker_state_changes{instance=10.0.0.1:8080,from=closed,to=open}"); n != 1 {
+		t.Errorf("breaker changes %v, want closed to open", o.series("breaker_state_changes"))
+	}
+
+	limited := m.countRateLimited("10.0.0.1:8080")(func(context.Context, interface{}) (interface{}, error) {
+		return nil, kitratelimit.ErrLimited
+	})
+	limited(context.Background(), nil)
+	if n := o.count("rate_limited{instance=10.0.0.1:8080}"); n != 1 {
+		t.Errorf("rate limited %v, want 1 for the instance", o.series("rate_limited"))
+	}
+
+	repo := instrumentedRepository{booking.NewMemory(), m.cacheLookups}
+	for j := 0; j < 3; j++ {
+		repo.Reserve(hspservice.Itinerary{IdempotencyKey: "key-1", Status: hspservice.StatusPending})
+	}
+	if miss, hit := o.count("cache_lookups{cache=idempotency,result=miss}"), o.count("cache_lookups{cache=idempotency,result=hit}"); miss != 1 || hit != 2 {
+		t.Errorf("idempotency lookups %d missed %d hit, want 1 and 2", miss, hit)
+	}
+}
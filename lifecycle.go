@@ -0,0 +1,245 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/jbowles/hotel_supply_platform/currency"
+	"github.com/jbowles/hotel_supply_platform/pricing"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+// serverTimeouts bound how long an HTTP server waits on a client. Write covers
+// the supplier calls a request makes, so it is the longest.
+type serverTimeouts struct {
+	Read  time.Duration
+	Write time.Duration
+	Idle  time.Duration
+}
+
+func newHTTPServer(addr string, h http.Handler, t serverTimeouts) *http.Server {
+	return &http.Server{
+		Addr:         addr,
+		Handler:      h,
+		ReadTimeout:  t.Read,
+		WriteTimeout: t.Write,
+		IdleTimeout:  t.Idle,
+	}
+}
+
+// lifecycle runs the servers of the process and stops them in order. On
+// SIGINT or SIGTERM, or when a server fails, it reports not ready, waits for
+// load balancers to notice, drains the servers within a deadline and then
+// closes what they used, last registered first. A second signal cuts the
+// drain short.
+type lifecycle struct {
+	logger log.Logger
+	ready  int32 // atomic; 1 while serving
+	errc   chan error
+
+	mtx     sync.Mutex
+	stops   []namedFunc // servers, stopped together
+	closers []namedFunc // resources, closed in reverse after the servers
+	checks  []namedFunc // readiness checks
+}
+
+type namedFunc struct {
+	name string
+	f    func(context.Context) error
+}
+
+func newLifecycle(logger log.Logger) *lifecycle {
+	return &lifecycle{logger: logger, errc: make(chan error, 1)}
+}
+
+// fail reports a server error, the first of which stops the process.
+func (l *lifecycle) fail(name string, err error) {
+	select {
+	case l.errc <- fmt.Errorf("%s: %v", name, err):
+	default:
+	}
+}
+
+// ServeHTTP runs srv in its own goroutine until Run stops it.
+func (l *lifecycle) ServeHTTP(name string, srv *http.Server) {
+	l.mtx.Lock()
+	l.stops = append(l.stops, namedFunc{name, func(ctx context.Context) error { return srv.Shutdown(ctx) }})
+	l.mtx.Unlock()
+	go func() {
+		log.NewContext(l.logger).With("transport", name).Log("addr", srv.Addr)
+		if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+			l.fail(name, err)
+		}
+	}()
+}
+
+// ServeGRPC runs s on addr in its own goroutine until Run stops it. Pending
+// RPCs are cancelled when the drain deadline passes.
+func (l *lifecycle) ServeGRPC(name, addr string, s *grpc.Server) {
+	l.mtx.Lock()
+	l.stops = append(l.stops, namedFunc{name, func(ctx context.Context) error {
+		done := make(chan struct{})
+		go func() {
+			s.GracefulStop()
+			close(done)
+		}()
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			s.Stop()
+			return ctx.Err()
+		}
+	}})
+	l.mtx.Unlock()
+	go func() {
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			l.fail(name, err)
+			return
+		}
+		log.NewContext(l.logger).With("transport", name).Log("addr", addr)
+		if err := s.Serve(ln); err != nil {
+			l.fail(name, err)
+		}
+	}()
+}
+
+// OnStop registers f to be called once the servers have drained.
+func (l *lifecycle) OnStop(name string, f func() error) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.closers = append(l.closers, namedFunc{name, func(context.Context) error { return f() }})
+}
+
+// ReadyWhen adds a readiness check: the process is not ready while check
+// returns an error.
+func (l *lifecycle) ReadyWhen(name string, check func() error) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.checks = append(l.checks, namedFunc{name, func(context.Context) error { return check() }})
+}
+
+// Ready returns nil when the process is serving, not shutting down and passes
+// its readiness checks. Configuration is loaded before Run, so a process that
+// is serving has it.
+func (l *lifecycle) Ready() error {
+	if atomic.LoadInt32(&l.ready) != 1 {
+		return errors.New("not serving")
+	}
+	l.mtx.Lock()
+	checks := l.checks
+	l.mtx.Unlock()
+	for _, c := range checks {
+		if err := c.f(context.Background()); err != nil {
+			return fmt.Errorf("%s: %v", c.name, err)
+		}
+	}
+	return nil
+}
+
+// cachesWarm is a readiness check on the caches rates are converted and
+// priced from: the exchange rate table holds rates and the pricing engine a
+// rule set. A nil table or engine is not configured and passes.
+func cachesWarm(table *currency.Table, engine *pricing.Engine) func() error {
+	return func() error {
+		if table != nil && len(table.Rates) == 0 {
+			return errors.New("no exchange rates loaded")
+		}
+		if engine != nil && engine.RuleSet() == nil {
+			return errors.New("no pricing rule set loaded")
+		}
+		return nil
+	}
+}
+
+// ReadyHandler answers 200 while Ready and 503 with the reason otherwise, for
+// load balancer and orchestrator readiness probes.
+func (l *lifecycle) ReadyHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := l.Ready(); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ready")
+	})
+}
+
+// HealthHandler answers 200 for as long as the process is up, for liveness
+// probes. Unlike ReadyHandler it does not depend on suppliers.
+func (l *lifecycle) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+}
+
+// Run reports ready and blocks until a signal or a server error, then stops
+// the process: it reports not ready, waits delay, drains the servers for at
+// most timeout and closes the registered resources. It returns the reason it
+// stopped.
+func (l *lifecycle) Run(delay, timeout time.Duration) error {
+	sigc := make(chan os.Signal, 2)
+	signal.Notify(sigc, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigc)
+
+	atomic.StoreInt32(&l.ready, 1)
+	var reason error
+	select {
+	case s := <-sigc:
+		reason = fmt.Errorf("%s", s)
+	case reason = <-l.errc:
+	}
+	atomic.StoreInt32(&l.ready, 0)
+	l.logger.Log("msg", "shutting down", "reason", reason, "delay", delay, "timeout", timeout)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case s := <-sigc:
+			l.logger.Log("msg", "drain cut short", "signal", s)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	select {
+	case <-time.After(delay):
+	case <-ctx.Done():
+	}
+
+	l.mtx.Lock()
+	stops, closers := l.stops, l.closers
+	l.mtx.Unlock()
+
+	drain, cancelDrain := context.WithTimeout(ctx, timeout)
+	defer cancelDrain()
+	var wg sync.WaitGroup
+	for _, s := range stops {
+		wg.Add(1)
+		go func(s namedFunc) {
+			defer wg.Done()
+			if err := s.f(drain); err != nil {
+				l.logger.Log("stop", s.name, "err", err)
+			}
+		}(s)
+	}
+	wg.Wait()
+
+	for i := len(closers) - 1; i >= 0; i-- {
+		if err := closers[i].f(ctx); err != nil {
+			l.logger.Log("close", closers[i].name, "err", err)
+		}
+	}
+	return reason
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/jbowles/hotel_supply_platform/currency"
+	"github.com/jbowles/hotel_supply_platform/pricing"
+)
+
+// probe serves a request for path on h and returns the status and body.
+func probe(t *testing.T, h http.Handler, path string) (int, string) {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+	b, err := ioutil.ReadAll(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rec.Code, string(b)
+}
+
+func TestHealthAndReadiness(t *testing.T) {
+	warmTable := &currency.Table{Base: "USD", Rates: map[string]*big.Rat{"EUR": big.NewRat(9, 10)}}
+	rs, err := pricing.ReadRuleSet(strings.NewReader(`{"rules": []}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	failing := newSupplierHealth(nil)
+	down := failing.Instance("ean", "10.0.0.1:8080")
+	for j := 0; j < maxConsecutiveFailures; j++ {
+		down.Observe(time.Millisecond, errSupplier)
+	}
+
+	for _, tc := range []struct {
+		name    string
+		serving bool
+		checks  map[string]func() error
+		reason  string // empty when ready
+	}{
+		{"not serving yet", false, nil, "not serving"},
+		{"serving", true, nil, ""},
+		{"caches warm", true, map[string]func() error{"caches": cachesWarm(warmTable, pricing.NewEngine(rs))}, ""},
+		{"caches not configured", true, map[string]func() error{"caches": cachesWarm(nil, nil)}, ""},
+		{"no rates", true, map[string]func() error{"caches": cachesWarm(&currency.Table{Base: "USD", Rates: map[string]*big.Rat{}}, nil)}, "caches: no exchange rates loaded"},
+		{"no rule set", true, map[string]func() error{"caches": cachesWarm(warmTable, pricing.NewEngine(nil))}, "caches: no pricing rule set loaded"},
+		{"no supplier", true, map[string]func() error{"suppliers": failing.Ready}, "suppliers: " + ErrNoSupplierAvailable.Error()},
+	} {
+		lc := newLifecycle(log.NewNopLogger())
+		for name, check := range tc.checks {
+			lc.ReadyWhen(name, check)
+		}
+		if tc.serving {
+			atomic.StoreInt32(&lc.ready, 1)
+		}
+
+		// The process is alive whether or not it is ready.
+		if code, body := probe(t, lc.HealthHandler(), "/healthz"); code != http.StatusOK || body != "ok\n" {
+			t.Errorf("%s: /healthz %d %q, want 200 ok", tc.name, code, body)
+		}
+		code, body := probe(t, lc.ReadyHandler(), "/readyz")
+		if tc.reason == "" && (code != http.StatusOK || body != "ready\n") {
+			t.Errorf("%s: /readyz %d %q, want 200 ready", tc.name, code, body)
+		}
+		if tc.reason != "" && (code != http.StatusServiceUnavailable || strings.TrimSpace(body) != tc.reason) {
+			t.Errorf("%s: /readyz %d %q, want 503 %q", tc.name, code, body, tc.reason)
+		}
+	}
+}
+
+// freeAddr returns a local address nothing listens on.
+func freeAddr(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return ln.Addr().String()
+}
+
+// events is a log of what happened, in order, across goroutines.
+type events struct {
+	mtx  sync.Mutex
+	list []string
+}
+
+func (e *events) add(s string) {
+	e.mtx.Lock()
+	e.list = append(e.list, s)
+	e.mtx.Unlock()
+}
+
+func (e *events) get() []string {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	return append([]string(nil), e.list...)
+}
+
+// waitFor polls cond until it holds, failing the test after a while.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func getStatus(url string) int {
+	resp, err := http.Get(url)
+	if err != nil {
+		return 0
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestLifecycleShutdown(t *testing.T) {
+	var ev events
+	lc := newLifecycle(log.NewNopLogger())
+
+	// The api listener has a request in flight when the signal arrives; the
+	// debug listener serves the readiness probe.
+	started, release := make(chan struct{}), make(chan struct{})
+	api := http.NewServeMux()
+	api.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		fmt.Fprint(w, "done")
+		ev.add("slow request done")
+	})
+	debug := http.NewServeMux()
+	debug.Handle("/readyz", lc.ReadyHandler())
+	apiAddr, debugAddr := freeAddr(t), freeAddr(t)
+	lc.ServeHTTP("api", newHTTPServer(apiAddr, api, serverTimeouts{}))
+	lc.ServeHTTP("debug", newHTTPServer(debugAddr, debug, serverTimeouts{}))
+	lc.OnStop("connections", func() error { ev.add("close connections"); return nil })
+	lc.OnStop("booking", func() error { ev.add("close booking"); return errors.New("logged, not fatal") })
+
+	const delay = 150 * time.Millisecond
+	var stopped time.Time
+	reasonc := make(chan error, 1)
+	go func() {
+		reason := lc.Run(delay, 2*time.Second)
+		stopped = time.Now()
+		reasonc <- reason
+	}()
+	waitFor(t, "readiness", func() bool { return getStatus("http://"+debugAddr+"/readyz") == http.StatusOK })
+
+	slow := make(chan string, 1)
+	go func() {
+		resp, err := http.Get("http://" + apiAddr + "/slow")
+		if err != nil {
+			slow <- err.Error()
+			return
+		}
+		b, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		slow <- string(b)
+	}()
+	<-started
+
+	signaled := time.Now()
+	if err := syscall.Kill(os.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	// Not ready, but still serving through the delay so the load balancers
+	// can tell.
+	waitFor(t, "not ready", func() bool {
+		return getStatus("http://"+debugAddr+"/readyz") == http.StatusServiceUnavailable
+	})
+	if ev := ev.get(); len(ev) != 0 {
+		t.Errorf("events %v before the drain, want none", ev)
+	}
+	close(release)
+
+	select {
+	case reason := <-reasonc:
+		if reason == nil || reason.Error() != syscall.SIGTERM.String() {
+			t.Errorf("reason %v, want %v", reason, syscall.SIGTERM)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("Run did not return")
+	}
+	if took := stopped.Sub(signaled); took < delay {
+		t.Errorf("stopped %v after the signal, want at least the %v delay", took, delay)
+	}
+	if body := <-slow; body != "done" {
+		t.Errorf("in-flight request got %q, want it completed", body)
+	}
+	// Every listener drains before anything is closed, and the closers run
+	// last registered first.
+	want := []string{"slow request done", "close booking", "close connections"}
+	if got := ev.get(); strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("events %v, want %v", got, want)
+	}
+	for _, addr := range []string{apiAddr, debugAddr} {
+		if _, err := http.Get("http://" + addr + "/readyz"); err == nil {
+			t.Errorf("%s still serving after Run", addr)
+		}
+	}
+}
+
+func TestLifecycleDrainDeadline(t *testing.T) {
+	var (
+		mtx     sync.Mutex
+		stopErr []string
+	)
+	logger := log.LoggerFunc(func(keyvals ...interface{}) error {
+		mtx.Lock()
+		defer mtx.Unlock()
+		for i := 0; i+1 < len(keyvals); i += 2 {
+			if keyvals[i] == "stop" {
+				stopErr = append(stopErr, fmt.Sprint(keyvals[i+1], ": ", keyvals[i+3]))
+			}
+		}
+		return nil
+	})
+	lc := newLifecycle(logger)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	defer close(release)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/stuck", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})
+	addr := freeAddr(t)
+	lc.ServeHTTP("api", newHTTPServer(addr, mux, serverTimeouts{}))
+	closed := make(chan struct{})
+	lc.OnStop("booking", func() error { close(closed); return nil })
+
+	const timeout = 100 * time.Millisecond
+	reasonc := make(chan error, 1)
+	go func() { reasonc <- lc.Run(0, timeout) }()
+	waitFor(t, "the server", func() bool { return getStatus("http://"+addr+"/none") == http.StatusNotFound })
+	go http.Get("http://" + addr + "/stuck")
+	<-started
+
+	// A server error stops the process as a signal does.
+	begin := time.Now()
+	lc.fail("grpc", errors.New("listener closed"))
+	select {
+	case reason := <-reasonc:
+		if reason == nil || reason.Error() != "grpc: listener closed" {
+			t.Errorf("reason %v, want the server error", reason)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("Run waited past the drain deadline")
+	}
+	if took := time.Since(begin); took < timeout {
+		t.Errorf("stopped after %v, want the %v drain deadline first", took, timeout)
+	}
+	select {
+	case <-closed:
+	default:
+		t.Error("resources not closed after the drain deadline")
+	}
+	mtx.Lock()
+	defer mtx.Unlock()
+	if len(stopErr) != 1 || !strings.HasPrefix(stopErr[0], "api: context deadline exceeded") {
+		t.Errorf("logged stop errors %v, want the api's deadline", stopErr)
+	}
+}
@@ -7,37 +7,64 @@ import (
 	"math/rand"
 	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
 	"time"
 
+	"github.com/jbowles/hotel_supply_platform/booking"
+	"github.com/jbowles/hotel_supply_platform/currency"
 	"github.com/jbowles/hotel_supply_platform/hspservice"
+	"github.com/jbowles/hotel_supply_platform/outbound"
+	"github.com/jbowles/hotel_supply_platform/pb"
+	"github.com/jbowles/hotel_supply_platform/pricing"
+	"github.com/jbowles/hotel_supply_platform/replay"
+	"github.com/jbowles/hotel_supply_platform/trace"
 	"golang.org/x/net/context"
+	"google.golang.org/grpc"
 
 	"github.com/go-kit/kit/endpoint"
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/metrics"
 	"github.com/go-kit/kit/metrics/expvar"
 	"github.com/go-kit/kit/metrics/prometheus"
-	httptransport "github.com/go-kit/kit/transport/http"
 	stdprometheus "github.com/prometheus/client_golang/prometheus"
 )
 
-func interrupt() error {
-	c := make(chan os.Signal)
-	signal.Notify(c, syscall.SIGINT, syscall.SIGTERM)
-	return fmt.Errorf("%s", <-c)
-}
-
 func main() {
 	// Flag domain. Note that gRPC transitively registers flags via its import
 	// of glog. So, we define a new flag set, to keep those domains distinct.
 	fs := flag.NewFlagSet("", flag.ExitOnError)
 	var (
 		eanHttpAddr = fs.String("ean.addr", ":8001", "Address for Ean HTTP (JSON) server")
+		otaHttpAddr = fs.String("ota.addr", ":8002", "Address for OTA HTTP (JSON) server")
+		eanEnv      = fs.String("ean.env", "production", "EAN environment: production, sandbox or mock (a local mock-ean)")
+		eanConfig   = fs.String("ean.config", "", "EAN configuration file (JSON) overriding the environment's URLs, paths, TLS, proxy and HTTP client settings")
+		eanURL      = fs.String("ean.url", "", "Base URL of the EAN API, reservations included, overriding the configuration's")
+		otaURL      = fs.String("ota.url", "", "OTA supplier OTA_HotelAvailRQ endpoint")
+		eanProxy    = fs.String("ean.proxy", "", "Comma separated EAN stack instances (host:port or URL) the HTTP and gRPC servers forward calls to; empty is this instance's, at -ean.addr")
 		httpAddr    = fs.String("http.addr", ":8022", "Address for HTTP (JSON) server")
+		grpcAddr    = fs.String("grpc.addr", ":8023", "Address for gRPC server")
 		debugAddr   = fs.String("debug.addr", ":8000", "Address for HTTP debug/instrumentation server")
+		debugTrace  = fs.Bool("debug.trace", false, "Return redacted supplier calls with each rate breakdown; for debugging only")
+		recordFile  = fs.String("supplier.record", "", "Append supplier requests and responses, credentials scrubbed, to this JSONL file")
+		replayFile  = fs.String("supplier.replay", "", "Answer supplier requests from this JSONL recording instead of the network")
+		maxConns    = fs.Int("supplier.max-conns-per-host", 32, "Connections to each supplier host, idle or in use, for suppliers without their own configuration; EAN's is in its configuration")
+		maxResponse = fs.Int64("supplier.max-response-bytes", 32<<20, "Largest supplier response body, decompressed; 0 for no limit")
+		attemptTime = fs.Duration("supplier.attempt-timeout", 10*time.Second, "How long each supplier call attempt may take, response body included; 0 for no limit")
+		hedgeAfter  = fs.Duration("supplier.hedge-after", 0, "How long to wait for an idempotent supplier call before sending it again; 0 disables hedging")
+		maxHedges   = fs.Int("supplier.max-hedges", 1, "How many times an idempotent supplier call is hedged at most")
+		traceExport = fs.String("trace.exporter", "none", "Where to export trace spans: none or log")
+		ratesFile   = fs.String("currency.rates", "", "Exchange rates table (JSON) used to convert supplier prices; empty disables conversion")
+		rulesFile   = fs.String("pricing.rules", "", "Pricing rule set (JSON); empty sells at net")
+		rulesReload = fs.Duration("pricing.reload", 30*time.Second, "How often to check the pricing rule set for changes")
+		bookingDB   = fs.String("booking.db", "", "BoltDB file for itineraries and booking attempts; empty keeps them in memory")
+		middlewares = fs.String("middleware", "logging,instrumenting,pricing,currency", "Service middlewares, outermost first, of: logging, instrumenting, pricing, currency; pricing prices converted rates when it wraps currency")
+		logSample   = fs.Float64("log.sample", 0.1, "Share of successful requests logged, from 0 to 1; failed requests are always logged")
+		timeouts    serverTimeouts
+		drainDelay  = fs.Duration("shutdown.delay", 5*time.Second, "How long to report not ready before draining on shutdown")
+		drainTime   = fs.Duration("shutdown.timeout", 30*time.Second, "How long to wait for in-flight requests on shutdown")
 	)
+	fs.DurationVar(&timeouts.Read, "server.read-timeout", 10*time.Second, "HTTP server read timeout")
+	fs.DurationVar(&timeouts.Write, "server.write-timeout", 30*time.Second, "HTTP server write timeout, including supplier calls")
+	fs.DurationVar(&timeouts.Idle, "server.idle-timeout", 2*time.Minute, "HTTP server keep-alive idle timeout")
 	flag.Usage = fs.Usage // only show our flags
 	if err := fs.Parse(os.Args[1:]); err != nil {
 		fmt.Fprintf(os.Stderr, "%v", err)
@@ -67,55 +94,292 @@ func main() {
 		))
 	}
 
+	m := newStackMetrics(requestDuration)
+
+	// package trace
+	var tracer *trace.Tracer
+	{
+		switch *traceExport {
+		case "none":
+		case "log":
+			tracer = trace.NewTracer(trace.LogExporter{Logger: log.NewContext(logger).With("component", "trace")})
+		default:
+			logger.Log("fatal", fmt.Sprintf("unknown trace exporter %q", *traceExport))
+			os.Exit(1)
+		}
+	}
+
 	// Mechanical stuff
 	rand.Seed(time.Now().UnixNano())
 	root := context.Background()
-	errc := make(chan error)
+	lc := newLifecycle(logger)
+	health := newSupplierHealth(m.observeSupplierCall)
+	lc.ReadyWhen("suppliers", health.Ready)
+
+	// package outbound: supplier calls go through a tuned client layer, over
+	// EAN's own connection pool or the pool shared by the other suppliers.
+	supplierPool := outbound.NewPool(*maxConns, *maxConns)
+	lc.OnStop("supplier connections", func() error {
+		supplierPool.CloseIdleConnections()
+		return nil
+	})
+	outboundOptions := outbound.Options{
+		AttemptTimeout:   *attemptTime,
+		MaxResponseBytes: *maxResponse,
+		HedgeAfter:       *hedgeAfter,
+		MaxHedges:        *maxHedges,
+		Conns:            m.supplierConns,
+		Hedges:           m.supplierHedges,
+	}
+
+	// package replay: supplier transports are wrapped with supplierTransport,
+	// which records their calls or answers them from a recording.
+	supplierTransport := func(next http.RoundTripper) http.RoundTripper { return next }
+	{
+		if *recordFile != "" && *replayFile != "" {
+			logger.Log("fatal", "-supplier.record and -supplier.replay are exclusive")
+			os.Exit(1)
+		}
+		if *recordFile != "" {
+			f, err := os.OpenFile(*recordFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+			if err != nil {
+				logger.Log("fatal", err)
+				os.Exit(1)
+			}
+			rec := replay.NewRecorder(f)
+			supplierTransport = rec.Transport
+			lc.OnStop("supplier recording", func() error {
+				err := f.Close()
+				if rerr := rec.Err(); rerr != nil {
+					err = rerr
+				}
+				return err
+			})
+			logger.Log("supplier_record", *recordFile)
+		}
+		if *replayFile != "" {
+			p, err := replay.Open(*replayFile)
+			if err != nil {
+				logger.Log("fatal", err)
+				os.Exit(1)
+			}
+			supplierTransport = func(http.RoundTripper) http.RoundTripper { return p }
+			logger.Log("supplier_replay", *replayFile)
+		}
+	}
+
+	// EAN configuration: where EAN is and the client to call it with.
+	var (
+		eanEndpoints   EanEndpoints
+		eanCredentials EanCredentials
+		eanClient      *http.Client
+	)
+	{
+		cfg, err := loadEanConfig(*eanEnv, *eanConfig)
+		if err == nil && *eanURL != "" {
+			cfg.BaseURL, cfg.BookingURL = *eanURL, ""
+		}
+		if err == nil {
+			eanEndpoints, err = cfg.Endpoints()
+		}
+		eanCredentials = cfg.Credentials
+		var t *http.Transport
+		if err == nil {
+			t, err = cfg.Transport()
+		}
+		if err != nil {
+			logger.Log("fatal", err)
+			os.Exit(1)
+		}
+		lc.OnStop("ean connections", func() error {
+			t.CloseIdleConnections()
+			return nil
+		})
+		eanClient = cfg.Client(health.Transport(eanSupplierName, supplierTransport(outbound.NewTransport(t, outboundOptions))))
+		logger.Log("ean_env", *eanEnv, "ean_hotel_list", eanEndpoints.HotelList, "ean_reservation", eanEndpoints.Reservation)
+	}
 
-	// Business domain
+	// package currency
+	var (
+		convert ServiceMiddleware = func(next hspservice.Hsp) hspservice.Hsp { return next }
+		table   *currency.Table
+	)
+	if *ratesFile != "" {
+		var err error
+		table, err = currency.LoadFile(*ratesFile)
+		if err != nil {
+			logger.Log("fatal", err)
+			os.Exit(1)
+		}
+		logger.Log("currency_base", table.Base, "as_of", table.AsOf, "rates", len(table.Rates))
+		convert = currencyMiddleware(currency.Converter{Provider: table}, log.NewContext(logger).With("component", "currency"))
+	}
+
+	// package pricing
+	var (
+		price  ServiceMiddleware = func(next hspservice.Hsp) hspservice.Hsp { return next }
+		engine *pricing.Engine
+	)
+	if *rulesFile != "" {
+		rs, err := pricing.ReadRuleSetFile(*rulesFile)
+		if err != nil {
+			logger.Log("fatal", err)
+			os.Exit(1)
+		}
+		engine = pricing.NewEngine(rs)
+		pricingLogger := log.NewContext(logger).With("component", "pricing")
+		done := make(chan struct{})
+		go engine.Watch(*rulesFile, *rulesReload, func(err error) { pricingLogger.Log("reload_err", err) }, done)
+		lc.OnStop("pricing watcher", func() error { close(done); return nil })
+		price = pricingMiddleware(engine, pricingLogger)
+	}
+	lc.ReadyWhen("caches", cachesWarm(table, engine))
+
+	// package booking
+	var itineraries booking.Repository
+	{
+		if *bookingDB == "" {
+			itineraries = booking.NewMemory()
+		} else {
+			db, err := booking.OpenBolt(*bookingDB)
+			if err != nil {
+				logger.Log("fatal", err)
+				os.Exit(1)
+			}
+			logger.Log("booking_db", *bookingDB, "schema_version", booking.SchemaVersion)
+			itineraries = db
+		}
+		itineraries = instrumentedRepository{itineraries, m.cacheLookups}
+		lc.OnStop("booking", itineraries.Close)
+	}
+
+	// Business domain. Each stack gets the configured middleware chain, its
+	// logs and metrics named with the stack prefix. The aggregate stack, with
+	// no prefix, forwards to the supplier stacks, which price and convert the
+	// rates, so it does neither again.
+	middleware := func(prefix string) ServiceMiddleware {
+		available := map[string]ServiceMiddleware{
+			"logging":       loggingMiddleware(logger, prefix, *logSample),
+			"instrumenting": instrumentingMiddleware(m.requestDuration, m.hotelsReturned, prefix),
+			"pricing":       price,
+			"currency":      convert,
+		}
+		if prefix == "" {
+			available["pricing"], available["currency"] = chain(), chain()
+		}
+		mw, err := buildChain(split(*middlewares), available)
+		if err != nil {
+			logger.Log("fatal", err)
+			os.Exit(1)
+		}
+		return mw
+	}
+	// The HTTP and gRPC servers forward every call to the EAN stacks through
+	// the supplier client layer, under the aggregate stack's middlewares.
 	var svc hspservice.Hsp
 	{
+		proxyTo := *eanProxy
+		if proxyTo == "" {
+			proxyTo = *eanHttpAddr
+		}
 		svc = HspService{}
-		svc = instrumentingMiddleware{svc, requestDuration}
-		//svc = loggingMiddleware{svc, logger}
-		svc = eanLoggingMiddleware{svc, logger}
+		svc = eanProxyingMiddleware(proxyTo, root, newProxyClient(supplierPool, outboundOptions), outboundOptions.AttemptTimeout, health, m, logger)(svc)
+		svc = middleware("")(svc)
 	}
 
-	go func() {
-		errc <- interrupt()
-	}()
-
 	// Debug/instrumentation
-	go func() {
-		transportLogger := log.NewContext(logger).With("transport", "debug")
-		transportLogger.Log("addr", *debugAddr)
-		errc <- http.ListenAndServe(*debugAddr, nil) // DefaultServeMux
-	}()
+	{
+		http.Handle("/healthz", lc.HealthHandler())
+		http.Handle("/readyz", lc.ReadyHandler())
+		http.Handle("/suppliers/status", health.StatusHandler())
+		http.Handle("/metrics", stdprometheus.Handler())
+		lc.ServeHTTP("debug", newHTTPServer(*debugAddr, http.DefaultServeMux, timeouts))
+	}
 
 	// Transport: Ean HTTP/JSON client servers come first
-	go func() {
+	{
 		var (
 			transportLogger = log.NewContext(logger).With("transport", "EAN-HTTP/JSON")
 			mux             = http.NewServeMux()
 			eanrateb        endpoint.Endpoint
+			eansvc          hspservice.Hsp
 		)
 
-		eanrateb = makeEanRateBreakdownEndpoint(EanHspService{})
-		mux.Handle("/ean/rate_breakdown", httptransport.NewServer(
+		eansvc = EanHspService{Client: eanClient, Endpoints: eanEndpoints, Credentials: eanCredentials, Itineraries: itineraries, Trace: *debugTrace, Logger: log.NewContext(logger).With("component", "ean")}
+		eansvc = middleware("ean")(eansvc)
+		eanrateb = makeRateBreakdownEndpoint(eansvc)
+		mux.Handle("/ean/rate_breakdown", newHTTPHandler(
 			root,
+			"ean.rate_breakdown",
 			eanrateb,
 			hspservice.DecodeRateBreakdownRequest,
 			hspservice.EncodeRateBreakdownResponse,
-			//httptransport.ServerBefore(traceSum),
-			httptransport.ServerErrorLogger(transportLogger),
+			tracer,
+			transportLogger,
+		))
+		mux.Handle("/ean/rate_breakdown/stream", newRateStreamHandler(
+			root,
+			"ean.rate_breakdown.stream",
+			eansvc,
+			tracer,
+			transportLogger,
+		))
+		mux.Handle("/ean/book", newHTTPHandler(
+			root,
+			"ean.book",
+			makeBookEndpoint(eansvc),
+			hspservice.DecodeBookRequest,
+			hspservice.EncodeBookResponse,
+			tracer,
+			transportLogger,
+		))
+		mux.Handle("/ean/itinerary", newHTTPHandler(
+			root,
+			"ean.itinerary",
+			makeItineraryEndpoint(eansvc),
+			hspservice.DecodeItineraryRequest,
+			hspservice.EncodeItineraryResponse,
+			tracer,
+			transportLogger,
+		))
+		mux.Handle("/ean/cancel", newHTTPHandler(
+			root,
+			"ean.cancel",
+			makeCancelEndpoint(eansvc),
+			hspservice.DecodeCancelRequest,
+			hspservice.EncodeCancelResponse,
+			tracer,
+			transportLogger,
 		))
 
-		transportLogger.Log("addr", *eanHttpAddr)
-		errc <- http.ListenAndServe(*eanHttpAddr, mux)
-	}()
+		lc.ServeHTTP("EAN-HTTP/JSON", newHTTPServer(*eanHttpAddr, m.instrumentHandler(mux), timeouts))
+	}
+
+	// Transport: OTA HTTP/JSON
+	{
+		var (
+			transportLogger = log.NewContext(logger).With("transport", "OTA-HTTP/JSON")
+			mux             = http.NewServeMux()
+			otasvc          hspservice.Hsp
+		)
+
+		otasvc = OtaHspService{Endpoint: *otaURL, Client: &http.Client{Transport: health.Transport(otaSupplierName, supplierTransport(outbound.NewTransport(supplierPool, outboundOptions)))}, Trace: *debugTrace}
+		otasvc = middleware("ota")(otasvc)
+		mux.Handle("/ota/rate_breakdown", newHTTPHandler(
+			root,
+			"ota.rate_breakdown",
+			makeRateBreakdownEndpoint(otasvc),
+			hspservice.DecodeRateBreakdownRequest,
+			hspservice.EncodeRateBreakdownResponse,
+			tracer,
+			transportLogger,
+		))
+
+		lc.ServeHTTP("OTA-HTTP/JSON", newHTTPServer(*otaHttpAddr, m.instrumentHandler(mux), timeouts))
+	}
 
 	// Transport: HTTP/JSON
-	go func() {
+	{
 		var (
 			transportLogger = log.NewContext(logger).With("transport", "HTTP/JSON")
 			mux             = http.NewServeMux()
@@ -123,28 +387,55 @@ func main() {
 		)
 
 		rateb = makeRateBreakdownEndpoint(svc)
-		mux.Handle("/rate_breakdown", httptransport.NewServer(
+		mux.Handle("/rate_breakdown", newHTTPHandler(
 			root,
+			"rate_breakdown",
 			rateb,
-			//makeRateBreakdownEndpoint(svc),
 			hspservice.DecodeRateBreakdownRequest,
 			hspservice.EncodeRateBreakdownResponse,
-			//httptransport.ServerBefore(traceSum),
-			httptransport.ServerErrorLogger(transportLogger),
+			tracer,
+			transportLogger,
+		))
+		mux.Handle("/book", newHTTPHandler(
+			root,
+			"book",
+			makeBookEndpoint(svc),
+			hspservice.DecodeBookRequest,
+			hspservice.EncodeBookResponse,
+			tracer,
+			transportLogger,
+		))
+		mux.Handle("/itinerary", newHTTPHandler(
+			root,
+			"itinerary",
+			makeItineraryEndpoint(svc),
+			hspservice.DecodeItineraryRequest,
+			hspservice.EncodeItineraryResponse,
+			tracer,
+			transportLogger,
+		))
+		mux.Handle("/cancel", newHTTPHandler(
+			root,
+			"cancel",
+			makeCancelEndpoint(svc),
+			hspservice.DecodeCancelRequest,
+			hspservice.EncodeCancelResponse,
+			tracer,
+			transportLogger,
 		))
 
-		transportLogger.Log("addr", *httpAddr)
-		errc <- http.ListenAndServe(*httpAddr, mux)
-	}()
+		lc.ServeHTTP("HTTP/JSON", newHTTPServer(*httpAddr, m.instrumentHandler(mux), timeouts))
+	}
 
-	//Proxy to running servers
-	/*
-		go func() {
-			svc = eanProxyingMiddleware(*eanHttpAddr, root, logger)(svc)
-		}()
-	*/
+	// Transport: gRPC
+	{
+		transportLogger := log.NewContext(logger).With("transport", "gRPC")
+		s := grpc.NewServer()
+		pb.RegisterHspServer(s, newGRPCBinding(root, svc, tracer, transportLogger))
+		lc.ServeGRPC("gRPC", *grpcAddr, s)
+	}
 
-	logger.Log("fatal", <-errc)
+	logger.Log("exit", lc.Run(*drainDelay, *drainTime))
 }
 
 /// one way to setup the server... simplified with one service and one api endpoint
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/jbowles/hotel_supply_platform/hspservice"
+	"golang.org/x/net/context"
+)
+
+// OtaHotelAvailRS is the subset of OTA_HotelAvailRS we read.
+type OtaHotelAvailRS struct {
+	XMLName   xml.Name      `xml:"OTA_HotelAvailRS"`
+	Success   *struct{}     `xml:"Success"`
+	Errors    []OtaError    `xml:"Errors>Error"`
+	RoomStays []OtaRoomStay `xml:"RoomStays>RoomStay"`
+}
+
+// OtaError is an OTA Errors/Error element.
+type OtaError struct {
+	Type      string `xml:"Type,attr"`
+	Code      string `xml:"Code,attr"`
+	ShortText string `xml:"ShortText,attr"`
+}
+
+func (e OtaError) Error() string {
+	return fmt.Sprintf("ota: error %s (type %s): %s", e.Code, e.Type, e.ShortText)
+}
+
+type OtaRoomStay struct {
+	Property  OtaPropertyInfo `xml:"BasicPropertyInfo"`
+	RoomRates []OtaRoomRate   `xml:"RoomRates>RoomRate"`
+}
+
+type OtaPropertyInfo struct {
+	ChainCode string `xml:"ChainCode,attr"`
+	HotelCode string `xml:"HotelCode,attr"`
+	HotelName string `xml:"HotelName,attr"`
+	Address   struct {
+		CountryName struct {
+			Code string `xml:"Code,attr"`
+		} `xml:"CountryName"`
+	} `xml:"Address"`
+}
+
+type OtaRoomRate struct {
+	RoomTypeCode string   `xml:"RoomTypeCode,attr"`
+	RatePlanCode string   `xml:"RatePlanCode,attr"`
+	Total        OtaTotal `xml:"Total"`
+}
+
+type OtaTotal struct {
+	AmountAfterTax string `xml:"AmountAfterTax,attr"`
+	CurrencyCode   string `xml:"CurrencyCode,attr"`
+}
+
+// Rates normalizes the response into supplier neutral rates, one per room rate.
+func (rs OtaHotelAvailRS) Rates() []hspservice.HotelRate {
+	var rates []hspservice.HotelRate
+	for _, stay := range rs.RoomStays {
+		for _, rr := range stay.RoomRates {
+			rates = append(rates, hspservice.HotelRate{
+				Supplier:     otaSupplierName,
+				HotelId:      stay.Property.HotelCode,
+				HotelName:    stay.Property.HotelName,
+				CountryCode:  stay.Property.Address.CountryName.Code,
+				ChainCode:    stay.Property.ChainCode,
+				RoomTypeCode: rr.RoomTypeCode,
+				RateCode:     rr.RatePlanCode,
+				Total:        rr.Total.AmountAfterTax,
+				Currency:     rr.Total.CurrencyCode,
+			})
+		}
+	}
+	return rates
+}
+
+// fetchOtaHotelAvail POSTs the availability request in ctx and decodes the
+// response.
+// OTA reports failures in the body, usually with a 200 status, so an Errors
+// element without Success is surfaced as the first error.
+func fetchOtaHotelAvail(ctx context.Context, client *http.Client, o *OtaHotelAvail) (rs OtaHotelAvailRS, err error) {
+	req, err := hspservice.NewRequest(o)
+	if err != nil {
+		return rs, err
+	}
+	req = req.WithContext(ctx)
+	resp, err := client.Do(req)
+	if err != nil {
+		return rs, hspservice.RedactError(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return rs, fmt.Errorf("ota: unexpected status %s", resp.Status)
+	}
+	if ct := resp.Header.Get("Content-Type"); !strings.Contains(ct, "xml") {
+		return rs, fmt.Errorf("ota: unexpected content type %q", ct)
+	}
+	if err = xml.NewDecoder(resp.Body).Decode(&rs); err != nil {
+		return rs, err
+	}
+	if rs.Success == nil && len(rs.Errors) > 0 {
+		return rs, rs.Errors[0]
+	}
+	return rs, nil
+}
@@ -0,0 +1,190 @@
+package main
+
+// OtaApi is a second supplier adapter speaking the OpenTravel Alliance
+// OTA_HotelAvailRQ/RS messages. Unlike EAN, OTA suppliers take the request as
+// an XML document POSTed to a single endpoint.
+// NOTE: like HotelAvail, the structs carry only the minimal availability fields.
+
+import (
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/jbowles/hotel_supply_platform/format"
+	"github.com/jbowles/hotel_supply_platform/hspservice"
+	"github.com/jbowles/hotel_supply_platform/trace"
+	"golang.org/x/net/context"
+)
+
+const (
+	otaSupplierName = "ota"
+	otaNamespace    = "http://www.opentravel.org/OTA/2003/05"
+	otaVersion      = "1.003"
+
+	// OTA AgeQualifyingCode values.
+	otaAdult = 10
+	otaChild = 8
+)
+
+// ErrNoOtaEndpoint is returned when an OTA request is built without an endpoint.
+var ErrNoOtaEndpoint = errors.New("ota: no supplier endpoint configured")
+
+// OtaHspService implements hspservice.Hsp against an OTA supplier endpoint.
+// When Client is nil the service only builds the supplier request URL. Trace
+// returns the redacted supplier calls with each rate breakdown; it is for
+// debugging only.
+type OtaHspService struct {
+	Endpoint string
+	Client   *http.Client
+	Trace    bool
+	ctx      context.Context
+}
+
+// WithContext binds the service to the context of a request; see contextual.
+func (s OtaHspService) WithContext(ctx context.Context) hspservice.Hsp {
+	s.ctx = ctx
+	return s
+}
+
+// satisfy interface
+func (s OtaHspService) RateBreakdown(rbreq hspservice.RateBreakdownRequest) (rbres hspservice.RateBreakdownResponse) {
+	o := OtaHotelAvail{Endpoint: s.Endpoint}
+	o.HotelCodes = []string{"225697", "116908"}
+	o.Rooms = []Room{{NumberOfAdults: 2}}
+
+	var u *url.URL
+	u, rbres.Error = hspservice.Build(&o, 14)
+	rbres.Request = rbreq
+	if rbres.Error != nil || s.Client == nil {
+		return
+	}
+
+	ctx := s.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	countAttempt(ctx)
+	span, _ := trace.StartSpan(ctx, "supplier.ota")
+	span.SetAttribute("http.url", hspservice.RedactURL(u))
+	begin := time.Now()
+	rs, err := fetchOtaHotelAvail(ctx, s.Client, &o)
+	span.SetError(err)
+	span.Finish()
+	if s.Trace {
+		rbres.Trace = append(rbres.Trace, hspservice.NewSupplierCall(otaSupplierName, "POST", u, begin, err))
+	}
+	if err != nil {
+		rbres.Error = err
+		return
+	}
+	rbres.Rates = rs.Rates()
+	return
+}
+
+// Book implements hspservice.Hsp. OTA reservations (OTA_HotelResRQ) are not
+// supported yet.
+func (OtaHspService) Book(hspservice.BookRequest) hspservice.BookResponse {
+	return hspservice.BookResponse{Error: hspservice.ErrNotSupported}
+}
+
+// GetItinerary implements hspservice.Hsp; see Book.
+func (OtaHspService) GetItinerary(hspservice.ItineraryRequest) hspservice.ItineraryResponse {
+	return hspservice.ItineraryResponse{Error: hspservice.ErrNotSupported}
+}
+
+// Cancel implements hspservice.Hsp; see Book.
+func (OtaHspService) Cancel(hspservice.CancelRequest) hspservice.CancelResponse {
+	return hspservice.CancelResponse{Error: hspservice.ErrNotSupported}
+}
+
+// OtaHotelAvail builds OTA_HotelAvailRQ documents. It implements
+// hspservice.Supplier and hspservice.Poster.
+type OtaHotelAvail struct {
+	Endpoint   string
+	HotelCodes []string
+	Rooms      []Room
+	Start      string // YYYY-MM-DD
+	End        string // YYYY-MM-DD
+}
+
+// OtaHotelAvailRQ is the wire format of the availability request.
+type OtaHotelAvailRQ struct {
+	XMLName   xml.Name          `xml:"OTA_HotelAvailRQ"`
+	Xmlns     string            `xml:"xmlns,attr"`
+	Version   string            `xml:"Version,attr"`
+	TimeStamp string            `xml:"TimeStamp,attr,omitempty"`
+	Segment   OtaRequestSegment `xml:"AvailRequestSegments>AvailRequestSegment"`
+}
+
+type OtaRequestSegment struct {
+	StayDateRange OtaDateRange   `xml:"StayDateRange"`
+	Candidates    []OtaCandidate `xml:"RoomStayCandidates>RoomStayCandidate"`
+	HotelRefs     []OtaHotelRef  `xml:"HotelSearchCriteria>Criterion>HotelRef"`
+}
+
+type OtaDateRange struct {
+	Start string `xml:"Start,attr"`
+	End   string `xml:"End,attr"`
+}
+
+type OtaCandidate struct {
+	Quantity    int             `xml:"Quantity,attr"`
+	GuestCounts []OtaGuestCount `xml:"GuestCounts>GuestCount"`
+}
+
+type OtaGuestCount struct {
+	AgeQualifyingCode int `xml:"AgeQualifyingCode,attr"`
+	Age               int `xml:"Age,attr,omitempty"`
+	Count             int `xml:"Count,attr"`
+}
+
+type OtaHotelRef struct {
+	HotelCode string `xml:"HotelCode,attr"`
+}
+
+// Name implements Supplier interface.
+func (o *OtaHotelAvail) Name() string { return otaSupplierName }
+
+// DateRange implements Supplier interface. OTA dates are ISO 8601.
+func (o *OtaHotelAvail) DateRange(days int) {
+	a := time.Now()
+	d := a.AddDate(0, 0, days)
+	o.Start, o.End = format.TimeInStringsOut(format.StandardDateLayout, a, d)
+}
+
+// Params implements Supplier interface. All search criteria go in the Body, so
+// this is just the supplier endpoint.
+func (o *OtaHotelAvail) Params() (*url.URL, error) {
+	if o.Endpoint == "" {
+		return nil, ErrNoOtaEndpoint
+	}
+	return url.Parse(o.Endpoint)
+}
+
+// Body implements hspservice.Poster.
+func (o *OtaHotelAvail) Body() (string, []byte, error) {
+	rq := OtaHotelAvailRQ{
+		Xmlns:     otaNamespace,
+		Version:   otaVersion,
+		TimeStamp: time.Now().UTC().Format(time.RFC3339),
+	}
+	rq.Segment.StayDateRange = OtaDateRange{Start: o.Start, End: o.End}
+	for _, code := range o.HotelCodes {
+		rq.Segment.HotelRefs = append(rq.Segment.HotelRefs, OtaHotelRef{HotelCode: code})
+	}
+	for _, rm := range o.Rooms {
+		c := OtaCandidate{Quantity: 1}
+		c.GuestCounts = append(c.GuestCounts, OtaGuestCount{AgeQualifyingCode: otaAdult, Count: rm.NumberOfAdults})
+		for _, age := range rm.ChildAges {
+			c.GuestCounts = append(c.GuestCounts, OtaGuestCount{AgeQualifyingCode: otaChild, Age: age, Count: 1})
+		}
+		rq.Segment.Candidates = append(rq.Segment.Candidates, c)
+	}
+	b, err := xml.Marshal(rq)
+	if err != nil {
+		return "", nil, err
+	}
+	return "application/xml", append([]byte(xml.Header), b...), nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jbowles/hotel_supply_platform/hspservice"
+)
+
+const otaAvailRS = `<?xml version="1.0" encoding="UTF-8"?>
+<OTA_HotelAvailRS xmlns="http://www.opentravel.org/OTA/2003/05" Version="1.003">
+  <Success/>
+  <RoomStays>
+    <RoomStay>
+      <RoomRates>
+        <RoomRate RoomTypeCode="KNG" RatePlanCode="BAR">
+          <Total AmountAfterTax="289.50" CurrencyCode="USD"/>
+        </RoomRate>
+        <RoomRate RoomTypeCode="QQ" RatePlanCode="NRF">
+          <Total AmountAfterTax="259.00" CurrencyCode="USD"/>
+        </RoomRate>
+      </RoomRates>
+      <BasicPropertyInfo ChainCode="MC" HotelCode="225697" HotelName="Mock Harbour Hotel">
+        <Address><CountryName Code="US"/></Address>
+      </BasicPropertyInfo>
+    </RoomStay>
+  </RoomStays>
+</OTA_HotelAvailRS>`
+
+const otaErrorRS = `<?xml version="1.0" encoding="UTF-8"?>
+<OTA_HotelAvailRS xmlns="http://www.opentravel.org/OTA/2003/05" Version="1.003">
+  <Errors><Error Type="3" Code="392" ShortText="Invalid hotel code"/></Errors>
+</OTA_HotelAvailRS>`
+
+// otaMock is a mock OTA supplier answering every request with status, a
+// Content-Type and body. It keeps the last request document it was POSTed.
+type otaMock struct {
+	status      int
+	contentType string
+	body        string
+	rq          OtaHotelAvailRQ
+	method      string
+}
+
+func (m *otaMock) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.method = r.Method
+	m.rq = OtaHotelAvailRQ{}
+	if err := xml.NewDecoder(r.Body).Decode(&m.rq); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", m.contentType)
+	w.WriteHeader(m.status)
+	io.WriteString(w, m.body)
+}
+
+func TestOtaRateBreakdown(t *testing.T) {
+	m := &otaMock{status: http.StatusOK, contentType: "text/xml; charset=utf-8", body: otaAvailRS}
+	srv := httptest.NewServer(m)
+	defer srv.Close()
+
+	svc := OtaHspService{Endpoint: srv.URL, Client: &http.Client{}}
+	res := svc.RateBreakdown(hspservice.RateBreakdownRequest{})
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	want := []hspservice.HotelRate{
+		{Supplier: otaSupplierName, HotelId: "225697", HotelName: "Mock Harbour Hotel", CountryCode: "US", ChainCode: "MC", RoomTypeCode: "KNG", RateCode: "BAR", Total: "289.50", Currency: "USD"},
+		{Supplier: otaSupplierName, HotelId: "225697", HotelName: "Mock Harbour Hotel", CountryCode: "US", ChainCode: "MC", RoomTypeCode: "QQ", RateCode: "NRF", Total: "259.00", Currency: "USD"},
+	}
+	if !reflect.DeepEqual(res.Rates, want) {
+		t.Errorf("rates\ngot:  %+v\nwant: %+v", res.Rates, want)
+	}
+
+	if m.method != "POST" {
+		t.Errorf("method %s, want POST", m.method)
+	}
+	if m.rq.Xmlns != otaNamespace || m.rq.Version != otaVersion {
+		t.Errorf("request namespace %q version %q", m.rq.Xmlns, m.rq.Version)
+	}
+	var hotels []string
+	for _, ref := range m.rq.Segment.HotelRefs {
+		hotels = append(hotels, ref.HotelCode)
+	}
+	if !reflect.DeepEqual(hotels, []string{"225697", "116908"}) {
+		t.Errorf("request hotel codes %v", hotels)
+	}
+	if r := m.rq.Segment.StayDateRange; r.Start == "" || r.End == "" {
+		t.Errorf("request stay date range %+v", r)
+	}
+}
+
+func TestOtaRateBreakdownErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		status      int
+		contentType string
+		body        string
+		want        string
+	}{
+		{"ota error", http.StatusOK, "application/xml", otaErrorRS, "ota: error 392 (type 3): Invalid hotel code"},
+		{"server error with xml body", http.StatusInternalServerError, "application/xml", otaAvailRS, "ota: unexpected status 500 Internal Server Error"},
+		{"bad gateway", http.StatusBadGateway, "text/html", "<h1>Bad Gateway</h1>", "ota: unexpected status 502 Bad Gateway"},
+		{"not xml", http.StatusOK, "text/html", "<h1>Maintenance</h1>", `ota: unexpected content type "text/html"`},
+	} {
+		srv := httptest.NewServer(&otaMock{status: tc.status, contentType: tc.contentType, body: tc.body})
+		svc := OtaHspService{Endpoint: srv.URL, Client: &http.Client{}}
+		res := svc.RateBreakdown(hspservice.RateBreakdownRequest{})
+		srv.Close()
+		if res.Error == nil || !strings.Contains(res.Error.Error(), tc.want) {
+			t.Errorf("%s: got error %v, want %q", tc.name, res.Error, tc.want)
+		}
+		if len(res.Rates) != 0 {
+			t.Errorf("%s: got rates %+v", tc.name, res.Rates)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package outbound
+
+import (
+	"compress/gzip"
+	"io"
+
+	"golang.org/x/net/context"
+)
+
+// body is a response body, decompressed if gzip is set and capped at max
+// bytes if max is positive. Closing it ends its attempt.
+type body struct {
+	rc     io.ReadCloser
+	gzip   bool
+	zr     *gzip.Reader
+	max    int64
+	n      int64
+	err    error
+	cancel context.CancelFunc
+}
+
+func (b *body) Read(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+	var r io.Reader = b.rc
+	if b.gzip {
+		if b.zr == nil {
+			if b.zr, b.err = gzip.NewReader(b.rc); b.err != nil {
+				return 0, b.err
+			}
+		}
+		r = b.zr
+	}
+	if b.max > 0 && int64(len(p)) > b.max-b.n+1 {
+		p = p[:b.max-b.n+1] // one byte more tells a body of exactly max
+	}
+	n, err := r.Read(p)
+	b.n += int64(n)
+	if b.max > 0 && b.n > b.max {
+		b.err = ErrResponseTooLarge
+		return n - 1, b.err
+	}
+	return n, err
+}
+
+func (b *body) Close() error {
+	err := b.rc.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,37 @@
+// Package outbound is the HTTP client layer of supplier calls. Supplier calls
+// dominate our tail latency, so they go through a Transport that bounds each
+// attempt, caps response sizes, asks for gzip, hedges slow idempotent
+// requests and counts how often connections are reused, over a connection
+// pool with per-host limits.
+package outbound
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+// ErrResponseTooLarge is returned reading a response body longer, once
+// decompressed, than the cap of its Transport.
+var ErrResponseTooLarge = errors.New("outbound: response too large")
+
+// NewPool returns a connection pool for suppliers: at most maxConnsPerHost
+// connections to a host, idle or in use, of which maxIdleConnsPerHost are
+// kept idle. Zero means no limit and the default, respectively.
+func NewPool(maxConnsPerHost, maxIdleConnsPerHost int) *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   5 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	return &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		Dial:                  dialer.Dial,
+		TLSHandshakeTimeout:   5 * time.Second,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   maxIdleConnsPerHost,
+		MaxConnsPerHost:       maxConnsPerHost,
+		IdleConnTimeout:       90 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+}
@@ -0,0 +1,220 @@
+package outbound
+
+import (
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+	"golang.org/x/net/context"
+)
+
+// Options tune a Transport. Zero values disable what they tune.
+type Options struct {
+	// AttemptTimeout bounds each attempt, from sending the request to closing
+	// the response body. The deadline of the request's context still applies.
+	AttemptTimeout time.Duration
+
+	// MaxResponseBytes caps response bodies, decompressed; reading past it
+	// fails with ErrResponseTooLarge.
+	MaxResponseBytes int64
+
+	// HedgeAfter is how long to wait for the response to an idempotent
+	// request before sending it again, up to MaxHedges more times. The first
+	// response wins and the other attempts are canceled.
+	HedgeAfter time.Duration
+	MaxHedges  int
+
+	// Conns counts the connections attempts are sent on, by host and by
+	// whether they were reused from the pool: reused "true" or "false".
+	Conns metrics.Counter
+
+	// Hedges counts hedged attempts by host and result: "sent", and "won"
+	// when the response used was a hedge's.
+	Hedges metrics.Counter
+}
+
+// Transport is an http.RoundTripper making supplier calls with next. Unless
+// the request says otherwise, it asks for gzip and decompresses responses
+// itself, so size caps apply to the decompressed body.
+type Transport struct {
+	next http.RoundTripper
+	Options
+}
+
+// NewTransport returns a Transport making requests with next, or with
+// http.DefaultTransport if next is nil.
+func NewTransport(next http.RoundTripper, o Options) *Transport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Transport{next: next, Options: o}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := new(http.Request)
+	*r = *req
+	r.Header = make(http.Header, len(req.Header)+1)
+	for k, v := range req.Header {
+		r.Header[k] = v
+	}
+	gzip := false
+	if r.Header.Get("Accept-Encoding") == "" && r.Method != "HEAD" {
+		r.Header.Set("Accept-Encoding", "gzip")
+		gzip = true
+	}
+
+	var (
+		resp *http.Response
+		err  error
+	)
+	if t.hedged(r) {
+		resp, err = t.hedge(r, gzip)
+	} else {
+		ctx, cancel := t.attemptContext(r.Context())
+		resp, err = t.attempt(r.WithContext(ctx), cancel, gzip)
+	}
+	if err != nil {
+		return nil, err
+	}
+	resp.Request = req
+	return resp, nil
+}
+
+// CloseIdleConnections closes the idle connections of the underlying
+// transport, if it has any.
+func (t *Transport) CloseIdleConnections() {
+	if c, ok := t.next.(interface {
+		CloseIdleConnections()
+	}); ok {
+		c.CloseIdleConnections()
+	}
+}
+
+// hedged reports whether req can be hedged: it is idempotent and has no body
+// to send twice.
+func (t *Transport) hedged(req *http.Request) bool {
+	if t.HedgeAfter <= 0 || t.MaxHedges <= 0 {
+		return false
+	}
+	if req.Method != "GET" && req.Method != "HEAD" {
+		return false
+	}
+	return req.Body == nil || req.Body == http.NoBody
+}
+
+func (t *Transport) attemptContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if t.AttemptTimeout > 0 {
+		return context.WithTimeout(ctx, t.AttemptTimeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// attempt sends req, whose context is canceled by cancel when the response
+// body is closed or the attempt fails.
+func (t *Transport) attempt(req *http.Request, cancel context.CancelFunc, gzip bool) (*http.Response, error) {
+	if t.Conns != nil {
+		host := req.URL.Host
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+			GotConn: func(info httptrace.GotConnInfo) {
+				t.Conns.With(metrics.Field{Key: "host", Value: host}).
+					With(metrics.Field{Key: "reused", Value: strconv.FormatBool(info.Reused)}).Add(1)
+			},
+		}))
+	}
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	b := &body{rc: resp.Body, max: t.MaxResponseBytes, cancel: cancel}
+	if gzip && resp.Header.Get("Content-Encoding") == "gzip" {
+		b.gzip = true
+		resp.Header.Del("Content-Encoding")
+		resp.Header.Del("Content-Length")
+		resp.ContentLength = -1
+		resp.Uncompressed = true
+	} else if b.max > 0 && resp.ContentLength > b.max {
+		resp.Body.Close()
+		cancel()
+		return nil, ErrResponseTooLarge
+	}
+	resp.Body = b
+	return resp, nil
+}
+
+type result struct {
+	resp *http.Response
+	err  error
+	n    int // the attempt's: 0 the first, then the hedges
+}
+
+// hedge sends req, then again each HedgeAfter without a response, up to
+// MaxHedges more times, and returns the first response. It fails when every
+// attempt sent has failed, with the error of the last.
+func (t *Transport) hedge(req *http.Request, gzip bool) (*http.Response, error) {
+	var (
+		results = make(chan result, t.MaxHedges+1)
+		cancels []context.CancelFunc
+		pending int
+	)
+	send := func() {
+		n := len(cancels)
+		ctx, cancel := t.attemptContext(req.Context())
+		cancels = append(cancels, cancel)
+		pending++
+		go func() {
+			resp, err := t.attempt(req.WithContext(ctx), cancel, gzip)
+			results <- result{resp, err, n}
+		}()
+	}
+	count := func(r string) {
+		if t.Hedges != nil {
+			t.Hedges.With(metrics.Field{Key: "host", Value: req.URL.Host}).
+				With(metrics.Field{Key: "result", Value: r}).Add(1)
+		}
+	}
+
+	send()
+	timer := time.NewTimer(t.HedgeAfter)
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+			if len(cancels) <= t.MaxHedges {
+				send()
+				count("sent")
+				timer.Reset(t.HedgeAfter)
+			}
+		case r := <-results:
+			pending--
+			if r.err != nil {
+				if pending == 0 {
+					return nil, r.err
+				}
+				continue
+			}
+			for n, cancel := range cancels {
+				if n != r.n {
+					cancel()
+				}
+			}
+			go discard(results, pending)
+			if r.n > 0 {
+				count("won")
+			}
+			return r.resp, nil
+		}
+	}
+}
+
+// discard closes the responses of the n attempts that lost.
+func discard(results <-chan result, n int) {
+	for ; n > 0; n-- {
+		if r := <-results; r.resp != nil {
+			r.resp.Body.Close()
+		}
+	}
+}
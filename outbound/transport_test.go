@@ -0,0 +1,272 @@
+package outbound
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+)
+
+// counter is a metrics.Counter keeping its counts by fields, as
+// "key=value key=value".
+type counter struct {
+	mtx    *sync.Mutex
+	counts map[string]uint64
+	fields []string
+}
+
+func newCounter() counter {
+	return counter{mtx: new(sync.Mutex), counts: map[string]uint64{}}
+}
+
+func (c counter) Name() string { return "test" }
+
+func (c counter) With(f metrics.Field) metrics.Counter {
+	c.fields = append(c.fields[:len(c.fields):len(c.fields)], f.Key+"="+f.Value)
+	return c
+}
+
+func (c counter) Add(delta uint64) {
+	c.mtx.Lock()
+	c.counts[strings.Join(c.fields, " ")] += delta
+	c.mtx.Unlock()
+}
+
+func (c counter) get(fields string) uint64 {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.counts[fields]
+}
+
+func host(t *testing.T, srv *httptest.Server) string {
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.Host
+}
+
+func get(t *testing.T, rt http.RoundTripper, u string) (string, error) {
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	return string(b), err
+}
+
+func TestHedgeCap(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		time.Sleep(150 * time.Millisecond)
+		io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+
+	hedges := newCounter()
+	tr := NewTransport(NewPool(0, 0), Options{HedgeAfter: 10 * time.Millisecond, MaxHedges: 2, Hedges: hedges})
+	if body, err := get(t, tr, srv.URL); err != nil || body != "ok" {
+		t.Fatalf("got %q, %v", body, err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 3 {
+		t.Errorf("%d requests sent, want the first and 2 hedges", n)
+	}
+	h := host(t, srv)
+	if n := hedges.get("host=" + h + " result=sent"); n != 2 {
+		t.Errorf("%d hedges counted sent, want 2", n)
+	}
+	if n := hedges.get("host=" + h + " result=won"); n != 0 {
+		t.Errorf("%d hedges counted won, want the first request to win", n)
+	}
+
+	// A request with a body is never hedged.
+	atomic.StoreInt32(&requests, 0)
+	req, _ := http.NewRequest("POST", srv.URL, strings.NewReader("book"))
+	resp, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("%d POST requests sent, want 1", n)
+	}
+}
+
+func TestHedgeCancelsLoser(t *testing.T) {
+	var requests int32
+	canceled := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			select {
+			case <-r.Context().Done():
+				close(canceled)
+			case <-time.After(5 * time.Second):
+			}
+			return
+		}
+		io.WriteString(w, "hedge")
+	}))
+	defer srv.Close()
+
+	hedges := newCounter()
+	tr := NewTransport(NewPool(0, 0), Options{HedgeAfter: 20 * time.Millisecond, MaxHedges: 1, Hedges: hedges})
+	if body, err := get(t, tr, srv.URL); err != nil || body != "hedge" {
+		t.Fatalf("got %q, %v; want the hedge's response", body, err)
+	}
+	select {
+	case <-canceled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the first request was not canceled once the hedge won")
+	}
+	if n := hedges.get("host=" + host(t, srv) + " result=won"); n != 1 {
+		t.Errorf("%d hedges counted won, want 1", n)
+	}
+}
+
+// trackedBody is a response body recording when it is closed.
+type trackedBody struct {
+	io.Reader
+	closed *int32
+}
+
+func (b trackedBody) Close() error {
+	atomic.AddInt32(b.closed, 1)
+	return nil
+}
+
+// lateTransport answers the nth request after delays[n], ignoring
+// cancellation, as a transport may once the response has arrived.
+type lateTransport struct {
+	delays   []time.Duration
+	requests int32
+	closed   int32
+}
+
+func (t *lateTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	n := atomic.AddInt32(&t.requests, 1) - 1
+	time.Sleep(t.delays[n])
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+		Body:       trackedBody{strings.NewReader("attempt"), &t.closed},
+		Request:    req,
+	}, nil
+}
+
+func TestHedgeDiscardsLosers(t *testing.T) {
+	late := &lateTransport{delays: []time.Duration{100 * time.Millisecond, 10 * time.Millisecond, 200 * time.Millisecond}}
+	tr := NewTransport(late, Options{HedgeAfter: 5 * time.Millisecond, MaxHedges: 2})
+	if body, err := get(t, tr, "http://supplier.example.com/list"); err != nil || body != "attempt" {
+		t.Fatalf("got %q, %v", body, err)
+	}
+	// The winner's body is closed by get; the two losers' by discard once
+	// their responses arrive.
+	deadline := time.Now().Add(2 * time.Second)
+	for atomic.LoadInt32(&late.closed) != 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d bodies closed, want the 3 attempts'", atomic.LoadInt32(&late.closed))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := atomic.LoadInt32(&late.requests); n != 3 {
+		t.Errorf("%d requests sent, want 3", n)
+	}
+}
+
+func TestHedgeAllFail(t *testing.T) {
+	var requests int32
+	fail := roundTripFunc(func(*http.Request) (*http.Response, error) {
+		atomic.AddInt32(&requests, 1)
+		time.Sleep(20 * time.Millisecond)
+		return nil, errors.New("refused")
+	})
+	tr := NewTransport(fail, Options{HedgeAfter: 5 * time.Millisecond, MaxHedges: 1})
+	if _, err := get(t, tr, "http://supplier.example.com/list"); err == nil {
+		t.Fatal("no error when every attempt failed")
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Errorf("%d requests sent, want 2", n)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestAttemptTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/body" {
+			// The headers arrive in time, the rest of the body never does.
+			io.WriteString(w, "first")
+			w.(http.Flusher).Flush()
+		}
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+
+	tr := NewTransport(NewPool(0, 0), Options{AttemptTimeout: 50 * time.Millisecond})
+	for _, path := range []string{"/headers", "/body"} {
+		begin := time.Now()
+		_, err := get(t, tr, srv.URL+path)
+		if err == nil {
+			t.Errorf("%s: no error past the attempt timeout", path)
+		}
+		if took := time.Since(begin); took > time.Second {
+			t.Errorf("%s: failed after %v, want the attempt timeout", path, took)
+		}
+	}
+
+	// Each hedge has an attempt timeout of its own.
+	hedged := NewTransport(NewPool(0, 0), Options{AttemptTimeout: 50 * time.Millisecond, HedgeAfter: 30 * time.Millisecond, MaxHedges: 1})
+	begin := time.Now()
+	if _, err := get(t, hedged, srv.URL+"/headers"); err == nil {
+		t.Error("hedged: no error past the attempt timeouts")
+	}
+	if took := time.Since(begin); took < 80*time.Millisecond || took > time.Second {
+		t.Errorf("hedged: failed after %v, want the hedge's timeout", took)
+	}
+}
+
+func TestConnsReused(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+
+	conns := newCounter()
+	pool := NewPool(0, 0)
+	defer pool.CloseIdleConnections()
+	tr := NewTransport(pool, Options{Conns: conns})
+	for i := 0; i < 3; i++ {
+		if _, err := get(t, tr, srv.URL); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h := host(t, srv)
+	if n := conns.get("host=" + h + " reused=false"); n != 1 {
+		t.Errorf("%d new connections counted, want 1", n)
+	}
+	if n := conns.get("host=" + h + " reused=true"); n != 2 {
+		t.Errorf("%d reused connections counted, want 2", n)
+	}
+}
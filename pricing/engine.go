@@ -0,0 +1,123 @@
+package pricing
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"github.com/jbowles/hotel_supply_platform/currency"
+)
+
+// Rules that are not configured but may still appear in Decision.Applied.
+const (
+	MinMarginRule  = "min_margin"
+	RateParityRule = "rate_parity"
+)
+
+// Input is everything a rule can match on, plus the net rate being priced.
+type Input struct {
+	Supplier string
+	Country  string
+	Chain    string
+	Channel  string
+	Nights   int
+	LeadDays int
+	Net      currency.Money
+}
+
+// Decision is the outcome of pricing one rate. Applied lists the IDs of the
+// rules that changed the price, in order, for audit.
+type Decision struct {
+	Version string
+	Net     currency.Money
+	Sell    currency.Money
+	Applied []string
+}
+
+// Engine prices rates against the current RuleSet. It is safe for concurrent
+// use, and the rule set can be swapped at any time with Set or Watch.
+type Engine struct {
+	mu    sync.RWMutex
+	rules *RuleSet
+}
+
+// NewEngine returns an Engine pricing with rs.
+func NewEngine(rs *RuleSet) *Engine {
+	return &Engine{rules: rs}
+}
+
+// Set replaces the rule set used by subsequent Price calls.
+func (e *Engine) Set(rs *RuleSet) {
+	e.mu.Lock()
+	e.rules = rs
+	e.mu.Unlock()
+}
+
+// RuleSet returns the rule set currently in use.
+func (e *Engine) RuleSet() *RuleSet {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.rules
+}
+
+// Price applies the matching rules to in.Net and enforces the margin floors.
+func (e *Engine) Price(in Input) Decision {
+	rs := e.RuleSet()
+	d := Decision{Version: rs.Version, Net: in.Net, Sell: in.Net}
+	for _, r := range rs.Rules {
+		if !r.matches(in) {
+			continue
+		}
+		d.Sell = d.Sell.Mul(r.factor)
+		d.Applied = append(d.Applied, r.ID)
+		if r.Stop {
+			break
+		}
+	}
+
+	if rs.parity(in.Supplier) && d.Sell.Cmp(in.Net) < 0 {
+		d.Sell = in.Net
+		d.Applied = append(d.Applied, RateParityRule)
+	}
+	if rs.minMargin != nil {
+		if floor := in.Net.Mul(rs.minMargin); d.Sell.Cmp(floor) < 0 {
+			d.Sell = floor
+			d.Applied = append(d.Applied, MinMarginRule)
+		}
+	}
+	return d
+}
+
+// Watch polls path every interval and loads the rule set whenever the file's
+// modification time changes. Load errors go to errf and leave the current
+// rule set in place. Watch returns when done is closed.
+func (e *Engine) Watch(path string, interval time.Duration, errf func(error), done <-chan struct{}) {
+	var last time.Time
+	if fi, err := os.Stat(path); err == nil {
+		last = fi.ModTime()
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-t.C:
+		}
+		fi, err := os.Stat(path)
+		if err != nil {
+			errf(err)
+			continue
+		}
+		if !fi.ModTime().After(last) {
+			continue
+		}
+		rs, err := ReadRuleSetFile(path)
+		if err != nil {
+			errf(err)
+			continue
+		}
+		last = fi.ModTime()
+		e.Set(rs)
+	}
+}
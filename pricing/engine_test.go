@@ -0,0 +1,198 @@
+package pricing
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jbowles/hotel_supply_platform/currency"
+)
+
+const testRules = `{
+	"version": "test-1",
+	"rules": [
+		{"id": "mobile-discount", "channel": "mobile", "percent": "-10"},
+		{"id": "fr-luxury", "country": "FR", "chain": "LX", "percent": "25", "stop": true},
+		{"id": "long-stay", "min_nights": 7, "percent": "-5"},
+		{"id": "last-minute", "max_lead_days": 2, "percent": "15", "stop": true},
+		{"id": "base", "percent": "12"}
+	]
+}`
+
+func mustRuleSet(t *testing.T, s string) *RuleSet {
+	rs, err := ReadRuleSet(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rs
+}
+
+func usd(t *testing.T, amount string) currency.Money {
+	m, err := currency.Parse(amount, "USD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestPrice(t *testing.T) {
+	e := NewEngine(mustRuleSet(t, testRules))
+	for _, tc := range []struct {
+		name    string
+		in      Input
+		sell    string
+		applied []string
+	}{
+		{"base only", Input{Supplier: "other", Nights: 2, LeadDays: 30}, "112.00", []string{"base"}},
+		{"rules in file order", Input{Supplier: "other", Channel: "mobile", Nights: 7, LeadDays: 30}, "95.76", []string{"mobile-discount", "long-stay", "base"}},
+		{"match is case insensitive", Input{Supplier: "other", Country: "fr", Chain: "lx", Nights: 1, LeadDays: 30}, "125.00", []string{"fr-luxury"}},
+		{"stop after earlier rules", Input{Supplier: "other", Channel: "mobile", Nights: 8, LeadDays: 1}, "98.325", []string{"mobile-discount", "long-stay", "last-minute"}},
+		{"range bounds inclusive", Input{Supplier: "other", Nights: 6, LeadDays: 2}, "115.00", []string{"last-minute"}},
+	} {
+		tc.in.Net = usd(t, "100")
+		d := e.Price(tc.in)
+		if d.Sell.Rat().Cmp(usd(t, tc.sell).Rat()) != 0 {
+			t.Errorf("%s: sell %s, want %s", tc.name, d.Sell.Rat().FloatString(4), tc.sell)
+		}
+		if !reflect.DeepEqual(d.Applied, tc.applied) {
+			t.Errorf("%s: applied %v, want %v", tc.name, d.Applied, tc.applied)
+		}
+		if d.Version != "test-1" || d.Net.Rat().Cmp(tc.in.Net.Rat()) != 0 {
+			t.Errorf("%s: version %s net %s, want test-1 and the input's", tc.name, d.Version, d.Net)
+		}
+	}
+}
+
+func TestPriceFloors(t *testing.T) {
+	rs := mustRuleSet(t, `{
+		"version": "floors",
+		"min_margin_percent": "5",
+		"parity_suppliers": ["EAN"],
+		"rules": [{"id": "deep-discount", "percent": "-20"}]
+	}`)
+	for _, tc := range []struct {
+		name     string
+		rs       *RuleSet
+		supplier string
+		sell     string
+		applied  []string
+	}{
+		// Parity first takes the rate back to net, then the margin floor
+		// raises it above.
+		{"parity then margin", rs, "ean", "105", []string{"deep-discount", RateParityRule, MinMarginRule}},
+		{"margin only", rs, "other", "105", []string{"deep-discount", MinMarginRule}},
+		{"parity only", mustRuleSet(t, `{"parity_suppliers": ["ean"], "rules": [{"id": "deep-discount", "percent": "-20"}]}`), "ean", "100", []string{"deep-discount", RateParityRule}},
+		{"no floors", mustRuleSet(t, `{"rules": [{"id": "deep-discount", "percent": "-20"}]}`), "ean", "80", []string{"deep-discount"}},
+		{"above the floors", mustRuleSet(t, `{"min_margin_percent": "5", "parity_suppliers": ["ean"], "rules": [{"id": "markup", "percent": "10"}]}`), "ean", "110", []string{"markup"}},
+	} {
+		d := NewEngine(tc.rs).Price(Input{Supplier: tc.supplier, Net: usd(t, "100")})
+		if d.Sell.Rat().Cmp(usd(t, tc.sell).Rat()) != 0 {
+			t.Errorf("%s: sell %s, want %s", tc.name, d.Sell.Rat().FloatString(4), tc.sell)
+		}
+		if !reflect.DeepEqual(d.Applied, tc.applied) {
+			t.Errorf("%s: applied %v, want %v", tc.name, d.Applied, tc.applied)
+		}
+	}
+}
+
+func TestReadRuleSetInvalid(t *testing.T) {
+	for _, s := range []string{
+		`{"rules": [{"id": "bad", "percent": "ten"}]}`,
+		`{"rules": [{"id": "below", "percent": "-101"}]}`,
+		`{"min_margin_percent": "x", "rules": []}`,
+		`{"rules": [`,
+	} {
+		if _, err := ReadRuleSet(strings.NewReader(s)); err == nil {
+			t.Errorf("%s: no error", s)
+		}
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pricing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "rules.json")
+	// write replaces the rule set file with one modified at mtime. The times
+	// are set explicitly, as a file system with a coarse clock may not tell
+	// two writes apart, and the file renamed into place so Watch never sees
+	// it before.
+	write := func(s string, mtime time.Time) {
+		tmp := path + ".tmp"
+		if err := ioutil.WriteFile(tmp, []byte(s), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(tmp, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	start := time.Now().Add(-time.Hour)
+
+	e := NewEngine(mustRuleSet(t, `{"version": "v1", "rules": []}`))
+	errs := make(chan error, 1)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		e.Watch(path, 5*time.Millisecond, func(err error) {
+			select {
+			case errs <- err:
+			default:
+			}
+		}, done)
+		close(stopped)
+	}()
+	waitErr := func(what string) {
+		select {
+		case <-errs:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("no error for %s", what)
+		}
+	}
+	waitVersion := func(want string) {
+		deadline := time.Now().Add(2 * time.Second)
+		for e.RuleSet().Version != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("version %s, want %s", e.RuleSet().Version, want)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	// A missing file is reported, which also shows Watch is polling.
+	waitErr("a missing rule set")
+	write(`{"version": "v2", "rules": [{"id": "base", "percent": "10"}]}`, start.Add(time.Minute))
+	waitVersion("v2")
+	if d := e.Price(Input{Net: usd(t, "100")}); d.Sell.Rat().Cmp(usd(t, "110").Rat()) != 0 {
+		t.Errorf("sell %s after reload, want 110", d.Sell)
+	}
+
+	// A broken file is reported and the loaded rule set kept.
+	select {
+	case <-errs: // left from the missing file
+	default:
+	}
+	write(`{"version": "v3", "rules": [`, start.Add(2*time.Minute))
+	waitErr("a broken rule set")
+	if v := e.RuleSet().Version; v != "v2" {
+		t.Errorf("version %s after a broken reload, want v2 kept", v)
+	}
+
+	write(`{"version": "v4", "rules": []}`, start.Add(3*time.Minute))
+	waitVersion("v4")
+
+	close(done)
+	select {
+	case <-stopped:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Watch did not return once done was closed")
+	}
+}
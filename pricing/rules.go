@@ -0,0 +1,117 @@
+// Package pricing turns supplier (net) rates into sell rates by applying
+// configurable markup and discount rules, then enforcing margin floors.
+package pricing
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+)
+
+// Rule is a markup (positive Percent) or discount (negative Percent) applied
+// to every rate it matches. Empty strings and zero bounds match anything, so
+// MaxNights 0 means no upper bound on length of stay. Matching rules are
+// applied in file order, each to the result of the previous one, until a
+// rule with Stop set has been applied.
+type Rule struct {
+	ID          string `json:"id"`
+	Supplier    string `json:"supplier,omitempty"`
+	Country     string `json:"country,omitempty"`
+	Chain       string `json:"chain,omitempty"`
+	Channel     string `json:"channel,omitempty"`
+	MinNights   int    `json:"min_nights,omitempty"`
+	MaxNights   int    `json:"max_nights,omitempty"`
+	MinLeadDays int    `json:"min_lead_days,omitempty"` // days between booking and arrival
+	MaxLeadDays int    `json:"max_lead_days,omitempty"`
+	Percent     string `json:"percent"` // decimal, e.g. "12.5" or "-5"
+	Stop        bool   `json:"stop,omitempty"`
+
+	factor *big.Rat // 1 + Percent/100
+}
+
+// RuleSet is the unit that is loaded and hot reloaded.
+// MinMarginPercent is the lowest markup over net any sell rate may have.
+// ParitySuppliers lists suppliers whose rates we may not undercut, so
+// discounts never take their sell rate below net.
+type RuleSet struct {
+	Version          string   `json:"version"`
+	MinMarginPercent string   `json:"min_margin_percent,omitempty"`
+	ParitySuppliers  []string `json:"parity_suppliers,omitempty"`
+	Rules            []Rule   `json:"rules"`
+
+	minMargin *big.Rat // 1 + MinMarginPercent/100, nil when unset
+}
+
+// ReadRuleSet decodes and validates a JSON rule set.
+func ReadRuleSet(r io.Reader) (*RuleSet, error) {
+	var rs RuleSet
+	if err := json.NewDecoder(r).Decode(&rs); err != nil {
+		return nil, err
+	}
+	if rs.MinMarginPercent != "" {
+		m, err := factor(rs.MinMarginPercent)
+		if err != nil {
+			return nil, fmt.Errorf("pricing: min_margin_percent: %v", err)
+		}
+		rs.minMargin = m
+	}
+	for i := range rs.Rules {
+		f, err := factor(rs.Rules[i].Percent)
+		if err != nil {
+			return nil, fmt.Errorf("pricing: rule %q: %v", rs.Rules[i].ID, err)
+		}
+		rs.Rules[i].factor = f
+	}
+	return &rs, nil
+}
+
+// ReadRuleSetFile reads a rule set from a file; see ReadRuleSet.
+func ReadRuleSetFile(path string) (*RuleSet, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadRuleSet(f)
+}
+
+func factor(percent string) (*big.Rat, error) {
+	p, ok := new(big.Rat).SetString(strings.TrimSpace(percent))
+	if !ok {
+		return nil, fmt.Errorf("invalid percent %q", percent)
+	}
+	f := new(big.Rat).Add(big.NewRat(1, 1), p.Quo(p, big.NewRat(100, 1)))
+	if f.Sign() < 0 {
+		return nil, fmt.Errorf("percent %q below -100", percent)
+	}
+	return f, nil
+}
+
+func (r Rule) matches(in Input) bool {
+	return matchString(r.Supplier, in.Supplier) &&
+		matchString(r.Country, in.Country) &&
+		matchString(r.Chain, in.Chain) &&
+		matchString(r.Channel, in.Channel) &&
+		matchRange(r.MinNights, r.MaxNights, in.Nights) &&
+		matchRange(r.MinLeadDays, r.MaxLeadDays, in.LeadDays)
+}
+
+func matchString(want, got string) bool {
+	return want == "" || strings.EqualFold(want, got)
+}
+
+func matchRange(min, max, got int) bool {
+	return got >= min && (max == 0 || got <= max)
+}
+
+func (rs *RuleSet) parity(supplier string) bool {
+	for _, s := range rs.ParitySuppliers {
+		if strings.EqualFold(s, supplier) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,165 @@
+package replay
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ErrNoRecording is returned by a Replayer for a request it has no recording
+// for.
+var ErrNoRecording = errors.New("replay: no recording for request")
+
+// Replayer is an http.RoundTripper answering requests with recorded
+// responses. A request is answered by the recordings of the same method and
+// scrubbed URL, ignoring the parameters that differ between identical
+// requests. When there are none, it is answered by those of the same method
+// and path, as the documents in supplier requests often carry dates relative
+// to today. Several recordings for a request are served in turn, the last one
+// repeatedly.
+type Replayer struct {
+	// Strict answers only requests recorded with the same URL.
+	Strict bool
+
+	mtx    sync.Mutex
+	byURL  map[string]*recordings
+	byPath map[string]*recordings
+}
+
+type recordings struct {
+	entries []Entry
+	next    int
+}
+
+func (r *recordings) take() Entry {
+	e := r.entries[r.next]
+	if r.next < len(r.entries)-1 {
+		r.next++
+	}
+	return e
+}
+
+// Load reads a JSONL recording from rd.
+func Load(rd io.Reader) (*Replayer, error) {
+	p := &Replayer{
+		byURL:  map[string]*recordings{},
+		byPath: map[string]*recordings{},
+	}
+	s := bufio.NewScanner(rd)
+	s.Buffer(nil, 64<<20) // supplier responses can be large
+	for line := 1; s.Scan(); line++ {
+		if strings.TrimSpace(s.Text()) == "" {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(s.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("replay: line %d: %v", line, err)
+		}
+		u, err := url.Parse(e.Request.URL)
+		if err != nil {
+			return nil, fmt.Errorf("replay: line %d: %v", line, err)
+		}
+		add(p.byURL, urlKey(e.Request.Method, u), e)
+		add(p.byPath, pathKey(e.Request.Method, u), e)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Open reads the JSONL recording in the file path.
+func Open(path string) (*Replayer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Load(f)
+}
+
+func add(m map[string]*recordings, key string, e Entry) {
+	r, ok := m[key]
+	if !ok {
+		r = &recordings{}
+		m[key] = r
+	}
+	r.entries = append(r.entries, e)
+}
+
+// volatileParams are the query parameters that differ between identical
+// requests: EAN's customerSessionId is the request id.
+var volatileParams = []string{"customerSessionId"}
+
+// urlKey identifies the recordings of a request: its method and scrubbed URL
+// without the volatile parameters. Recorded URLs are scrubbed already;
+// scrubbing them again changes nothing.
+func urlKey(method string, u *url.URL) string {
+	r := *u
+	q := r.Query()
+	for _, k := range volatileParams {
+		q.Del(k)
+	}
+	r.RawQuery = q.Encode()
+	return method + " " + scrubURL(&r)
+}
+
+func pathKey(method string, u *url.URL) string {
+	return method + " " + u.Host + u.Path
+}
+
+// RoundTrip implements http.RoundTripper.
+func (p *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	p.mtx.Lock()
+	r, ok := p.byURL[urlKey(req.Method, req.URL)]
+	if !ok && !p.Strict {
+		r, ok = p.byPath[pathKey(req.Method, req.URL)]
+	}
+	var e Entry
+	if ok {
+		e = r.take()
+	}
+	p.mtx.Unlock()
+	if !ok {
+		return nil, ErrNoRecording
+	}
+
+	if e.Response == nil {
+		return nil, errors.New(e.Error)
+	}
+	var body io.Reader = strings.NewReader(e.Response.Body)
+	if e.Error != "" {
+		body = io.MultiReader(body, errReader{errors.New(e.Error)})
+	}
+	header := http.Header{}
+	for k, v := range e.Response.Header {
+		header[k] = append([]string(nil), v...)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.Response.Status, http.StatusText(e.Response.Status)),
+		StatusCode:    e.Response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(body),
+		ContentLength: int64(len(e.Response.Body)),
+		Request:       req,
+	}, nil
+}
+
+// errReader fails every read with err.
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) { return 0, r.err }
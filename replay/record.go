@@ -0,0 +1,118 @@
+package replay
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Recorder writes the requests made through its transports, and their
+// responses, to a JSONL stream. It is safe for concurrent use.
+//
+// Response bodies are passed to the caller as they arrive, so streaming
+// decoders work in record mode too, and the entry is written when the body
+// is closed. Until then the recorder keeps a copy of the body: a recorded
+// call holds its response whole. A body closed before its end is recorded as
+// far as it was read.
+type Recorder struct {
+	mtx sync.Mutex
+	enc *json.Encoder
+	err error
+}
+
+// NewRecorder returns a Recorder writing to w.
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{enc: json.NewEncoder(w)}
+}
+
+// Transport returns an http.RoundTripper making requests with next and
+// recording them. A failure to record does not fail the request; see Err.
+func (r *Recorder) Transport(next http.RoundTripper) http.RoundTripper {
+	return recordingTransport{r, next}
+}
+
+// Err returns the first error writing a recording, if any.
+func (r *Recorder) Err() error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return r.err
+}
+
+func (r *Recorder) record(e Entry) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.err != nil {
+		return
+	}
+	r.err = r.enc.Encode(e)
+}
+
+type recordingTransport struct {
+	recorder *Recorder
+	next     http.RoundTripper
+}
+
+func (t recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// The request is the caller's, so its body is replaced on a copy.
+	out := *req
+	reqBody, err := readBody(&out.Body)
+	if err != nil {
+		return nil, err
+	}
+	e := Entry{
+		Time: time.Now().UTC(),
+		Request: Request{
+			Method: req.Method,
+			URL:    scrubURL(req.URL),
+			Header: scrubHeader(req.Header),
+			Body:   scrubBody(req.Header.Get("Content-Type"), string(reqBody)),
+		},
+	}
+
+	resp, err := t.next.RoundTrip(&out)
+	if err != nil {
+		e.Took = time.Since(e.Time).String()
+		e.Error = err.Error()
+		t.recorder.record(e)
+		return nil, err
+	}
+	e.Response = &Response{Status: resp.StatusCode, Header: scrubHeader(resp.Header)}
+	resp.Body = &recordingBody{ReadCloser: resp.Body, done: func(body []byte, err error) {
+		e.Took = time.Since(e.Time).String()
+		e.Response.Body = string(body)
+		if err != nil {
+			e.Error = err.Error()
+		}
+		t.recorder.record(e)
+	}}
+	return resp, nil
+}
+
+// recordingBody passes a response body through as it is read, keeping a
+// copy, and calls done with the copy and the first read error when it is
+// closed.
+type recordingBody struct {
+	io.ReadCloser
+	buf  bytes.Buffer
+	err  error
+	done func(body []byte, err error)
+	once sync.Once
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+	if err != nil && err != io.EOF && b.err == nil {
+		b.err = err
+	}
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() { b.done(b.buf.Bytes(), b.err) })
+	return err
+}
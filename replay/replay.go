@@ -0,0 +1,141 @@
+// Package replay records the HTTP traffic with suppliers to JSONL files, and
+// serves it back, so supplier code can be developed and regression tested
+// offline against real payloads.
+//
+// A Recorder wraps the transport of a supplier http.Client and writes every
+// request and response, one Entry per line. Credentials are scrubbed before
+// anything is written: the credential query and form parameters, card
+// details in request documents and authentication headers. A Replayer is an
+// http.RoundTripper answering requests from such a file, without a network.
+package replay
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jbowles/hotel_supply_platform/hspservice"
+)
+
+// Entry is a recorded request and its response, or the error it failed with.
+// An entry with both a response and an error is a response whose body failed
+// to read after Body.
+type Entry struct {
+	Time     time.Time `json:"time"`
+	Took     string    `json:"took"`
+	Request  Request   `json:"request"`
+	Response *Response `json:"response,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// Request is a recorded request, scrubbed.
+type Request struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// Response is a recorded response.
+type Response struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+const redacted = "REDACTED"
+
+// secretHeaders are the headers recorded as "REDACTED".
+var secretHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// cardFields are the request document fields carrying card details, in the
+// XML and JSON documents suppliers take.
+var cardFields = []string{
+	"creditCardNumber",
+	"creditCardIdentifier",
+	"creditCardExpirationMonth",
+	"creditCardExpirationYear",
+}
+
+var cardPatterns []*regexp.Regexp
+
+func init() {
+	for _, f := range cardFields {
+		cardPatterns = append(cardPatterns,
+			regexp.MustCompile(`(<`+f+`>)[^<]*(</`+f+`>)`),
+			regexp.MustCompile(`("`+f+`"\s*:\s*")[^"]*(")`),
+		)
+	}
+}
+
+// scrubDocument replaces the card details of an XML or JSON document.
+func scrubDocument(s string) string {
+	for _, p := range cardPatterns {
+		s = p.ReplaceAllString(s, `${1}`+redacted+`${2}`)
+	}
+	return s
+}
+
+// scrubValues scrubs a query or a form body: its credential parameters and
+// the card details of the documents in it, such as EAN's xml parameter.
+func scrubValues(v url.Values) {
+	hspservice.RedactValues(v)
+	for _, values := range v {
+		for i := range values {
+			values[i] = scrubDocument(values[i])
+		}
+	}
+}
+
+// scrubURL returns u as a string, scrubbed.
+func scrubURL(u *url.URL) string {
+	r := *u
+	q := r.Query()
+	scrubValues(q)
+	r.RawQuery = q.Encode()
+	return hspservice.RedactURL(&r)
+}
+
+// scrubBody scrubs a request body of the given Content-Type.
+func scrubBody(contentType, body string) string {
+	if strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+		if v, err := url.ParseQuery(body); err == nil {
+			scrubValues(v)
+			return v.Encode()
+		}
+	}
+	return scrubDocument(body)
+}
+
+// scrubHeader returns a copy of h with the secret headers redacted.
+func scrubHeader(h http.Header) http.Header {
+	if len(h) == 0 {
+		return nil
+	}
+	c := http.Header{}
+	for k, v := range h {
+		c[k] = append([]string(nil), v...)
+	}
+	for _, k := range secretHeaders {
+		if _, ok := c[k]; ok {
+			c.Set(k, redacted)
+		}
+	}
+	return c
+}
+
+// readBody reads all of *body and replaces it with a reader of the same bytes.
+func readBody(body *io.ReadCloser) ([]byte, error) {
+	if *body == nil {
+		return nil, nil
+	}
+	b, err := ioutil.ReadAll(*body)
+	(*body).Close()
+	*body = ioutil.NopCloser(bytes.NewReader(b))
+	return b, err
+}
@@ -0,0 +1,206 @@
+package replay
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// supplier answers every request with its method, path and body.
+var supplier = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	w.Header().Set("Content-Type", "text/plain")
+	w.Header().Set("Set-Cookie", "session=secret-session")
+	if r.URL.Path == "/missing" {
+		w.WriteHeader(http.StatusNotFound)
+	}
+	w.Write([]byte(r.Method + " " + r.URL.Path + " " + string(body)))
+})
+
+// record makes the requests to srv through a Recorder and returns the
+// recording.
+func record(t *testing.T, srv *httptest.Server, reqs ...*http.Request) []byte {
+	var buf bytes.Buffer
+	rec := NewRecorder(&buf)
+	client := &http.Client{Transport: rec.Transport(http.DefaultTransport)}
+	for _, req := range reqs {
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+	if err := rec.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func get(t *testing.T, u string) *http.Request {
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+func post(t *testing.T, u, contentType, body string) *http.Request {
+	req, err := http.NewRequest("POST", u, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	return req
+}
+
+// replayBody makes req with p and returns the response status and body.
+func replayBody(t *testing.T, p *Replayer, req *http.Request) (int, string) {
+	resp, err := (&http.Client{Transport: p}).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(body)
+}
+
+func TestRoundTrip(t *testing.T) {
+	srv := httptest.NewServer(supplier)
+	defer srv.Close()
+	recording := record(t, srv,
+		get(t, srv.URL+"/list?cid=55505&apiKey=key&customerSessionId=req-1&arrivalDate=11/02/2026"),
+		post(t, srv.URL+"/res", "application/x-www-form-urlencoded", "xml=<HotelRoomReservationRequest/>"),
+		get(t, srv.URL+"/missing"),
+	)
+
+	p, err := Load(bytes.NewReader(recording))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Another request id and the real credentials are the same request.
+	status, body := replayBody(t, p, get(t, srv.URL+"/list?cid=55505&apiKey=key&customerSessionId=req-2&arrivalDate=11/02/2026"))
+	if status != http.StatusOK || body != "GET /list " {
+		t.Errorf("list: %d %q", status, body)
+	}
+	status, body = replayBody(t, p, post(t, srv.URL+"/res", "application/x-www-form-urlencoded", "xml=<HotelRoomReservationRequest/>"))
+	if status != http.StatusOK || body != "POST /res xml=<HotelRoomReservationRequest/>" {
+		t.Errorf("res: %d %q", status, body)
+	}
+	if status, _ = replayBody(t, p, get(t, srv.URL+"/missing")); status != http.StatusNotFound {
+		t.Errorf("missing: %d, want the recorded 404", status)
+	}
+}
+
+func TestScrub(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Set-Cookie", "session=secret-session")
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+	card := `<HotelRoomReservationRequest><creditCardNumber>4111111111111111</creditCardNumber>` +
+		`<creditCardIdentifier>123</creditCardIdentifier><creditCardExpirationMonth>11</creditCardExpirationMonth>` +
+		`<creditCardExpirationYear>2030</creditCardExpirationYear></HotelRoomReservationRequest>`
+	form := url.Values{"cid": {"55505"}, "apiKey": {"secret-key"}, "xml": {card}}
+	res := post(t, srv.URL+"/res?cid=55505&apiKey=secret-key", "application/x-www-form-urlencoded", form.Encode())
+	res.Header.Set("Authorization", "Bearer secret-token")
+	json := post(t, srv.URL+"/book", "application/json", `{"creditCardNumber": "4111111111111111", "creditCardIdentifier":"123"}`)
+	recording := string(record(t, srv, res, json))
+
+	for _, secret := range []string{"55505", "secret-key", "4111111111111111", ">123<", `"123"`, ">2030<", "secret-token", "secret-session"} {
+		if strings.Contains(recording, secret) {
+			t.Errorf("recording has %s:\n%s", secret, recording)
+		}
+	}
+	if !strings.Contains(recording, `{\"creditCardNumber\": \"REDACTED\", \"creditCardIdentifier\":\"REDACTED\"}`) {
+		t.Errorf("JSON card fields not redacted as strings:\n%s", recording)
+	}
+	if n := strings.Count(recording, redacted); n < 10 {
+		t.Errorf("%d values redacted, want the credentials, card fields and headers:\n%s", n, recording)
+	}
+}
+
+func TestMatching(t *testing.T) {
+	srv := httptest.NewServer(supplier)
+	defer srv.Close()
+	recording := record(t, srv,
+		post(t, srv.URL+"/res?arrivalDate=11/02/2026", "text/xml", "first"),
+		post(t, srv.URL+"/res?arrivalDate=11/02/2026", "text/xml", "second"),
+		post(t, srv.URL+"/res?arrivalDate=12/24/2026", "text/xml", "other"),
+	)
+	p, err := Load(bytes.NewReader(recording))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The recordings of a URL are served in turn, the last repeatedly.
+	for _, want := range []string{"first", "second", "second"} {
+		if _, body := replayBody(t, p, post(t, srv.URL+"/res?arrivalDate=11/02/2026", "text/xml", "")); body != "POST /res "+want {
+			t.Errorf("by URL: %q, want %s", body, want)
+		}
+	}
+	if _, body := replayBody(t, p, post(t, srv.URL+"/res?arrivalDate=12/24/2026", "text/xml", "")); body != "POST /res other" {
+		t.Errorf("by URL: %q, want other", body)
+	}
+	// A URL never recorded is answered by the recordings of its path.
+	if _, body := replayBody(t, p, post(t, srv.URL+"/res?arrivalDate=01/01/2027", "text/xml", "")); !strings.HasPrefix(body, "POST /res ") {
+		t.Errorf("by path: %q", body)
+	}
+	if _, err := (&http.Client{Transport: p}).Do(get(t, srv.URL+"/res")); err == nil || !strings.Contains(err.Error(), ErrNoRecording.Error()) {
+		t.Errorf("other method: %v, want %v", err, ErrNoRecording)
+	}
+
+	p.Strict = true
+	if _, err := (&http.Client{Transport: p}).Do(post(t, srv.URL+"/res?arrivalDate=01/01/2027", "text/xml", "")); err == nil {
+		t.Error("strict replayer answered a URL never recorded")
+	}
+}
+
+func TestRecordStreams(t *testing.T) {
+	sent, read := make(chan struct{}), make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("first,"))
+		w.(http.Flusher).Flush()
+		close(sent)
+		select {
+		case <-read:
+		case <-time.After(5 * time.Second):
+		}
+		w.Write([]byte("rest"))
+	}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	rec := NewRecorder(&buf)
+	resp, err := (&http.Client{Transport: rec.Transport(http.DefaultTransport)}).Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-sent
+	first := make([]byte, len("first,"))
+	if _, err := resp.Body.Read(first); err != nil || string(first) != "first," {
+		t.Fatalf("read %q, %v before the body ended", first, err)
+	}
+	if buf.Len() != 0 {
+		t.Error("recorded before the body was closed")
+	}
+	close(read)
+	ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	p, err := Load(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, body := replayBody(t, p, get(t, srv.URL)); body != "first,rest" {
+		t.Errorf("recorded body %q, want it whole", body)
+	}
+}
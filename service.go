@@ -2,24 +2,152 @@ package main
 
 import (
 	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"net/url"
+	"sort"
+	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/go-kit/kit/loadbalancer"
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/metrics"
+	kitratelimit "github.com/go-kit/kit/ratelimit"
+	"github.com/jbowles/hotel_supply_platform/booking"
+	"github.com/jbowles/hotel_supply_platform/currency"
+	"github.com/jbowles/hotel_supply_platform/format"
 	"github.com/jbowles/hotel_supply_platform/hspservice"
+	"github.com/jbowles/hotel_supply_platform/outbound"
+	"github.com/jbowles/hotel_supply_platform/pricing"
+	"github.com/jbowles/hotel_supply_platform/trace"
+	"github.com/sony/gobreaker"
+	"golang.org/x/net/context"
 )
 
+// ServiceMiddleware decorates an Hsp. Middlewares embed the next Hsp, so the
+// methods they do not decorate pass through to it; the ones they do must call
+// it.
 type ServiceMiddleware func(hspservice.Hsp) hspservice.Hsp
 
-type loggingMiddleware struct {
-	hspservice.Hsp
-	logger log.Logger
+// contextual is implemented by the services and middlewares that use the
+// request context, which the Hsp methods do not take. WithContext returns a
+// copy bound to ctx, binding the Hsp it wraps too; the endpoints bind the
+// service to each request's context.
+type contextual interface {
+	WithContext(ctx context.Context) hspservice.Hsp
+}
+
+// withContext binds svc to ctx when it uses a context.
+func withContext(ctx context.Context, svc hspservice.Hsp) hspservice.Hsp {
+	if c, ok := svc.(contextual); ok {
+		return c.WithContext(ctx)
+	}
+	return svc
+}
+
+// withRates binds svc to ctx like withContext, wrapping the rate sink of ctx,
+// if it has one, to call f on each rate before handing it on. Middlewares that
+// work on the rates of a response use it, so the rates svc streams get the
+// same work as the ones it returns.
+func withRates(ctx context.Context, svc hspservice.Hsp, f func(*hspservice.HotelRate)) hspservice.Hsp {
+	if sink := hspservice.RateSinkFromContext(ctx); sink != nil {
+		ctx = hspservice.NewRateSinkContext(ctx, func(r hspservice.HotelRate) error {
+			f(&r)
+			return sink(r)
+		})
+	}
+	return withContext(ctx, svc)
+}
+
+// chain composes mws into one ServiceMiddleware, the first outermost:
+// chain(a, b)(svc) is a(b(svc)).
+func chain(mws ...ServiceMiddleware) ServiceMiddleware {
+	return func(next hspservice.Hsp) hspservice.Hsp {
+		for i := len(mws) - 1; i >= 0; i-- {
+			next = mws[i](next)
+		}
+		return next
+	}
 }
-type instrumentingMiddleware struct {
+
+// buildChain chains the middlewares of available named by names, the first
+// outermost, each running in a span named after it. Empty names are skipped;
+// unknown ones are an error.
+func buildChain(names []string, available map[string]ServiceMiddleware) (ServiceMiddleware, error) {
+	var mws []ServiceMiddleware
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		mw, ok := available[name]
+		if !ok {
+			known := make([]string, 0, len(available))
+			for k := range available {
+				known = append(known, k)
+			}
+			sort.Strings(known)
+			return nil, fmt.Errorf("unknown middleware %q; known: %s", name, strings.Join(known, ", "))
+		}
+		mws = append(mws, traced(name, mw))
+	}
+	return chain(mws...), nil
+}
+
+// traced runs the calls through mw in a span named "middleware." + name.
+func traced(name string, mw ServiceMiddleware) ServiceMiddleware {
+	return func(next hspservice.Hsp) hspservice.Hsp {
+		return tracemw{"middleware." + name, context.Background(), mw(next)}
+	}
+}
+
+type tracemw struct {
+	name string
+	ctx  context.Context
 	hspservice.Hsp
-	//requestCount        metrics.Counter
-	requestDuration metrics.TimeHistogram
-	//rateBreakdownResult metrics.Histogram
+}
+
+func (mw tracemw) WithContext(ctx context.Context) hspservice.Hsp {
+	mw.ctx = ctx
+	return mw
+}
+
+// start starts the span of a call, returning the wrapped Hsp bound to it.
+func (mw tracemw) start() (*trace.Span, hspservice.Hsp) {
+	span, ctx := trace.StartSpan(mw.ctx, mw.name)
+	return span, withContext(ctx, mw.Hsp)
+}
+
+func (mw tracemw) RateBreakdown(rbreq hspservice.RateBreakdownRequest) (rbres hspservice.RateBreakdownResponse) {
+	span, next := mw.start()
+	defer span.Finish()
+	rbres = next.RateBreakdown(rbreq)
+	span.SetError(rbres.Error)
+	return
+}
+
+func (mw tracemw) Book(r hspservice.BookRequest) (res hspservice.BookResponse) {
+	span, next := mw.start()
+	defer span.Finish()
+	res = next.Book(r)
+	span.SetError(res.Error)
+	return
+}
+
+func (mw tracemw) GetItinerary(r hspservice.ItineraryRequest) (res hspservice.ItineraryResponse) {
+	span, next := mw.start()
+	defer span.Finish()
+	res = next.GetItinerary(r)
+	span.SetError(res.Error)
+	return
+}
+
+func (mw tracemw) Cancel(r hspservice.CancelRequest) (res hspservice.CancelResponse) {
+	span, next := mw.start()
+	defer span.Finish()
+	res = next.Cancel(r)
+	span.SetError(res.Error)
+	return
 }
 
 // interface to satisfy interface methods
@@ -30,26 +158,433 @@ func (HspService) RateBreakdown(hspservice.RateBreakdownRequest) (rbres hspservi
 	return rbres
 }
 
-func (m loggingMiddleware) RateBreakdown(rbreq hspservice.RateBreakdownRequest) (rbres hspservice.RateBreakdownResponse) {
+// Book implements hspservice.Hsp. HspService has no supplier to book with.
+func (HspService) Book(hspservice.BookRequest) hspservice.BookResponse {
+	return hspservice.BookResponse{Error: hspservice.ErrNotSupported}
+}
+
+// GetItinerary implements hspservice.Hsp; see Book.
+func (HspService) GetItinerary(hspservice.ItineraryRequest) hspservice.ItineraryResponse {
+	return hspservice.ItineraryResponse{Error: hspservice.ErrNotSupported}
+}
+
+// Cancel implements hspservice.Hsp; see Book.
+func (HspService) Cancel(hspservice.CancelRequest) hspservice.CancelResponse {
+	return hspservice.CancelResponse{Error: hspservice.ErrNotSupported}
+}
+
+// methodName is the name of method logged and measured for the stack prefix
+// names: "ean_rate_breakdown" for the EAN stack, "rate_breakdown" for the
+// aggregate one.
+func methodName(prefix, method string) string {
+	if prefix == "" {
+		return method
+	}
+	return prefix + "_" + method
+}
+
+// loggingMiddleware logs every call that fails, and the share sample of the
+// calls that succeed, with its request id, outcome and the supplier attempts
+// it took. Success sampling is by request id, so a sampled request is logged
+// by every stack it goes through. Book requests are not logged, as they carry
+// card details.
+func loggingMiddleware(logger log.Logger, prefix string, sample float64) ServiceMiddleware {
+	return func(next hspservice.Hsp) hspservice.Hsp {
+		return logmw{logger, prefix, sample, context.Background(), next}
+	}
+}
+
+type logmw struct {
+	logger log.Logger
+	prefix string
+	sample float64
+	ctx    context.Context
+	hspservice.Hsp
+}
+
+func (mw logmw) WithContext(ctx context.Context) hspservice.Hsp {
+	mw.ctx = ctx
+	return mw
+}
+
+// start returns the context of a call, counting its supplier attempts, and
+// the wrapped Hsp bound to it.
+func (mw logmw) start() (context.Context, hspservice.Hsp) {
+	ctx := withAttempts(hspservice.EnsureRequestID(mw.ctx))
+	return ctx, withContext(ctx, mw.Hsp)
+}
+
+// log logs a call to method made in ctx, unless it succeeded and is not
+// sampled.
+func (mw logmw) log(ctx context.Context, method string, begin time.Time, err error, keyvals ...interface{}) {
+	id := hspservice.RequestIDFromContext(ctx)
+	if err == nil && !sampled(id, mw.sample) {
+		return
+	}
+	outcome := "ok"
+	if err != nil {
+		outcome = "error"
+	}
+	keyvals = append([]interface{}{
+		"request_id", id,
+		"method", methodName(mw.prefix, method),
+	}, keyvals...)
+	_ = mw.logger.Log(append(keyvals,
+		"outcome", outcome,
+		"error_category", errorCategory(err),
+		"err", err,
+		"attempts", attempts(ctx),
+		"took", time.Since(begin),
+	)...)
+}
+
+func (mw logmw) RateBreakdown(rbreq hspservice.RateBreakdownRequest) (rbres hspservice.RateBreakdownResponse) {
+	ctx, _ := mw.start()
+	var count rateCount
+	next := withRates(ctx, mw.Hsp, count.add)
 	defer func(begin time.Time) {
-		_ = m.logger.Log(
-			"method", "rate_breakdown",
-			"took", time.Since(begin),
+		supplier := mw.prefix
+		if supplier == "" {
+			supplier = rateSuppliers(rbres)
+		}
+		count.addAll(rbres.Rates)
+		mw.log(ctx, "rate_breakdown", begin, rbres.Error,
+			"supplier", supplier,
+			"arrival", rbreq.Arrival,
+			"departure", rbreq.Departure,
+			"hotels", len(count.hotels),
+			"rates", count.rates,
 		)
 	}(time.Now())
 
-	//rbres = hspservice.RateBreakdownResponse{rbreq, nil}
+	rbres = next.RateBreakdown(rbreq)
 	return
 }
 
-func (m instrumentingMiddleware) RateBreakdown(rbreq hspservice.RateBreakdownRequest) (rbres hspservice.RateBreakdownResponse) {
+func (mw logmw) Book(r hspservice.BookRequest) (res hspservice.BookResponse) {
+	ctx, next := mw.start()
 	defer func(begin time.Time) {
-		methodField := metrics.Field{Key: "method", Value: "rate_breakdown"}
-		errorField := metrics.Field{Key: "error", Value: fmt.Sprintf("%v", rbres.Error)}
-		//m.requestCount.With(methodField).With(errorField).Add(1)
-		m.requestDuration.With(methodField).With(errorField).Observe(time.Since(begin))
+		mw.log(ctx, "book", begin, res.Error,
+			"supplier", mw.prefix,
+			"itinerary_id", res.Itinerary.ItineraryId,
+		)
 	}(time.Now())
 
-	rbres = hspservice.RateBreakdownResponse{rbreq, nil}
+	res = next.Book(r)
+	return
+}
+
+func (mw logmw) GetItinerary(r hspservice.ItineraryRequest) (res hspservice.ItineraryResponse) {
+	ctx, next := mw.start()
+	defer func(begin time.Time) {
+		mw.log(ctx, "get_itinerary", begin, res.Error,
+			"supplier", mw.prefix,
+			"itinerary_id", r.ItineraryId,
+		)
+	}(time.Now())
+
+	res = next.GetItinerary(r)
+	return
+}
+
+func (mw logmw) Cancel(r hspservice.CancelRequest) (res hspservice.CancelResponse) {
+	ctx, next := mw.start()
+	defer func(begin time.Time) {
+		mw.log(ctx, "cancel", begin, res.Error,
+			"supplier", mw.prefix,
+			"itinerary_id", r.ItineraryId,
+		)
+	}(time.Now())
+
+	res = next.Cancel(r)
+	return
+}
+
+// sampled reports whether the successful calls of request id are logged, for
+// a share sample of the requests. Calls without an id are sampled at random.
+func sampled(id string, sample float64) bool {
+	if sample >= 1 {
+		return true
+	}
+	if sample <= 0 {
+		return false
+	}
+	if id == "" {
+		return rand.Float64() < sample
+	}
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	return float64(h.Sum32()%10000) < sample*10000
+}
+
+// rateSuppliers returns the distinct suppliers of the rates of rbres, sorted
+// and comma separated.
+func rateSuppliers(rbres hspservice.RateBreakdownResponse) string {
+	seen := map[string]bool{}
+	var suppliers []string
+	for _, r := range rbres.Rates {
+		if !seen[r.Supplier] {
+			seen[r.Supplier] = true
+			suppliers = append(suppliers, r.Supplier)
+		}
+	}
+	sort.Strings(suppliers)
+	return strings.Join(suppliers, ",")
+}
+
+// errorCategory classifies err for logs, so errors can be counted by kind
+// rather than by message: "" for no error.
+func errorCategory(err error) string {
+	switch e := err.(type) {
+	case nil:
+		return ""
+	case loadbalancer.RetryError:
+		return errorCategory(e.Final)
+	case *EanWsError:
+		return "supplier"
+	case *hspservice.Error:
+		return "remote"
+	case hspservice.HTTPError:
+		return "invalid_request"
+	}
+	switch err {
+	case gobreaker.ErrOpenState, gobreaker.ErrTooManyRequests:
+		return "breaker_open"
+	case kitratelimit.ErrLimited:
+		return "rate_limited"
+	case ErrNoSupplierAvailable, ErrNoEanClient, ErrNoOtaEndpoint:
+		return "unavailable"
+	case hspservice.ErrInvalidPageToken, hspservice.ErrNoIdempotencyKey, hspservice.ErrNoConfirmationNumber:
+		return "invalid_request"
+	case hspservice.ErrItineraryNotFound, booking.ErrNotFound:
+		return "not_found"
+	case hspservice.ErrNotSupported:
+		return "not_supported"
+	case outbound.ErrResponseTooLarge:
+		return "response_too_large"
+	}
+	if callResult(err) == "timeout" {
+		return "timeout"
+	}
+	if e, ok := err.(*url.Error); ok {
+		if e.Err == outbound.ErrResponseTooLarge {
+			return "response_too_large"
+		}
+		return "network"
+	}
+	return "internal"
+}
+
+type attemptsKey struct{}
+
+// withAttempts returns ctx counting the supplier attempts made in it, keeping
+// the count of ctx if it has one, so outer stacks count the attempts of inner
+// ones.
+func withAttempts(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(attemptsKey{}).(*int32); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, attemptsKey{}, new(int32))
+}
+
+// countAttempt counts a supplier attempt made in ctx: a request sent to a
+// supplier, or one refused by its breaker or rate limiter.
+func countAttempt(ctx context.Context) {
+	if n, ok := ctx.Value(attemptsKey{}).(*int32); ok {
+		atomic.AddInt32(n, 1)
+	}
+}
+
+// attempts returns the supplier attempts made in ctx.
+func attempts(ctx context.Context) int32 {
+	if n, ok := ctx.Value(attemptsKey{}).(*int32); ok {
+		return atomic.LoadInt32(n)
+	}
+	return 0
+}
+
+// instrumentingMiddleware records the duration of every call, and the hotels
+// returned by rate breakdowns.
+func instrumentingMiddleware(requestDuration metrics.TimeHistogram, hotelsReturned metrics.Histogram, prefix string) ServiceMiddleware {
+	return func(next hspservice.Hsp) hspservice.Hsp {
+		return instrmw{requestDuration, hotelsReturned, prefix, context.Background(), next}
+	}
+}
+
+type instrmw struct {
+	requestDuration metrics.TimeHistogram
+	hotelsReturned  metrics.Histogram
+	prefix          string
+	ctx             context.Context
+	hspservice.Hsp
+}
+
+func (mw instrmw) WithContext(ctx context.Context) hspservice.Hsp {
+	mw.ctx = ctx
+	mw.Hsp = withContext(ctx, mw.Hsp)
+	return mw
+}
+
+func (mw instrmw) observe(method string, begin time.Time, err error) {
+	methodField := metrics.Field{Key: "method", Value: methodName(mw.prefix, method)}
+	errorField := metrics.Field{Key: "error", Value: fmt.Sprintf("%v", err)}
+	mw.requestDuration.With(methodField).With(errorField).Observe(time.Since(begin))
+}
+
+func (mw instrmw) RateBreakdown(rbreq hspservice.RateBreakdownRequest) (rbres hspservice.RateBreakdownResponse) {
+	var count rateCount
+	next := withRates(mw.ctx, mw.Hsp, count.add)
+	defer func(begin time.Time) {
+		mw.observe("rate_breakdown", begin, rbres.Error)
+		count.addAll(rbres.Rates)
+		observeHotels(mw.hotelsReturned, methodName(mw.prefix, "rate_breakdown"), len(count.hotels))
+	}(time.Now())
+
+	rbres = next.RateBreakdown(rbreq)
+	return
+}
+
+func (mw instrmw) Book(r hspservice.BookRequest) (res hspservice.BookResponse) {
+	defer func(begin time.Time) { mw.observe("book", begin, res.Error) }(time.Now())
+	res = mw.Hsp.Book(r)
+	return
+}
+
+func (mw instrmw) GetItinerary(r hspservice.ItineraryRequest) (res hspservice.ItineraryResponse) {
+	defer func(begin time.Time) { mw.observe("get_itinerary", begin, res.Error) }(time.Now())
+	res = mw.Hsp.GetItinerary(r)
+	return
+}
+
+func (mw instrmw) Cancel(r hspservice.CancelRequest) (res hspservice.CancelResponse) {
+	defer func(begin time.Time) { mw.observe("cancel", begin, res.Error) }(time.Now())
+	res = mw.Hsp.Cancel(r)
+	return
+}
+
+// currencyMiddleware converts every supplier rate into the currency asked for by
+// the request, keeping the supplier's original amount alongside. A rate that
+// cannot be converted, as when the table has no rate for its currency, is
+// logged and returned as the supplier sent it, with no Converted amount; the
+// other rates are still compared.
+func currencyMiddleware(conv currency.Converter, logger log.Logger) ServiceMiddleware {
+	return func(next hspservice.Hsp) hspservice.Hsp {
+		return currencymw{conv, logger, context.Background(), next}
+	}
+}
+
+type currencymw struct {
+	conv   currency.Converter
+	logger log.Logger
+	ctx    context.Context
+	hspservice.Hsp
+}
+
+func (mw currencymw) WithContext(ctx context.Context) hspservice.Hsp {
+	mw.ctx = ctx
+	mw.Hsp = withContext(ctx, mw.Hsp)
+	return mw
+}
+
+func (mw currencymw) RateBreakdown(rbreq hspservice.RateBreakdownRequest) (rbres hspservice.RateBreakdownResponse) {
+	if rbreq.Currency == "" {
+		return mw.Hsp.RateBreakdown(rbreq)
+	}
+	convert := func(rate *hspservice.HotelRate) {
+		m, err := currency.Parse(rate.Total, rate.Currency)
+		if err == nil {
+			m, err = mw.conv.Convert(m, rbreq.Currency)
+		}
+		if err != nil {
+			_ = mw.logger.Log(
+				"method", "currency",
+				"request_id", hspservice.RequestIDFromContext(mw.ctx),
+				"supplier", rate.Supplier,
+				"hotel_id", rate.HotelId,
+				"currency", rate.Currency,
+				"to", rbreq.Currency,
+				"err", err,
+			)
+			return
+		}
+		rate.Converted = &m
+	}
+	rbres = withRates(mw.ctx, mw.Hsp, convert).RateBreakdown(rbreq)
+	for i := range rbres.Rates {
+		convert(&rbres.Rates[i])
+	}
+	return
+}
+
+// pricingMiddleware prices every rate with the pricing engine, reporting net and
+// sell on the rate and logging each applied rule for audit. It prices in the
+// converted currency when there is one, so it wraps currencyMiddleware.
+func pricingMiddleware(engine *pricing.Engine, logger log.Logger) ServiceMiddleware {
+	return func(next hspservice.Hsp) hspservice.Hsp {
+		return pricingmw{engine, logger, context.Background(), next}
+	}
+}
+
+type pricingmw struct {
+	engine *pricing.Engine
+	logger log.Logger
+	ctx    context.Context
+	hspservice.Hsp
+}
+
+func (mw pricingmw) WithContext(ctx context.Context) hspservice.Hsp {
+	mw.ctx = ctx
+	mw.Hsp = withContext(ctx, mw.Hsp)
+	return mw
+}
+
+func (mw pricingmw) RateBreakdown(rbreq hspservice.RateBreakdownRequest) (rbres hspservice.RateBreakdownResponse) {
+	var nights, leadDays int
+	if arrival, err := time.Parse(format.StandardDateLayout, rbreq.Arrival); err == nil {
+		leadDays = int(arrival.Sub(time.Now()).Hours() / 24)
+		if departure, err := time.Parse(format.StandardDateLayout, rbreq.Departure); err == nil {
+			nights = int(departure.Sub(arrival).Hours() / 24)
+		}
+	}
+	var priceErr error
+	price := func(rate *hspservice.HotelRate) {
+		net, err := currency.Parse(rate.Total, rate.Currency)
+		if rate.Converted != nil {
+			net, err = *rate.Converted, nil
+		}
+		if err != nil {
+			priceErr = err
+			return
+		}
+		d := mw.engine.Price(pricing.Input{
+			Supplier: rate.Supplier,
+			Country:  rate.CountryCode,
+			Chain:    rate.ChainCode,
+			Channel:  rbreq.Channel,
+			Nights:   nights,
+			LeadDays: leadDays,
+			Net:      net,
+		})
+		rate.Net, rate.Sell, rate.PricingRules = &d.Net, &d.Sell, d.Applied
+		if len(d.Applied) > 0 {
+			_ = mw.logger.Log(
+				"method", "pricing",
+				"ruleset", d.Version,
+				"supplier", rate.Supplier,
+				"hotel_id", rate.HotelId,
+				"rules", strings.Join(d.Applied, ","),
+				"net", d.Net,
+				"sell", d.Sell,
+				"currency", d.Sell.Currency,
+			)
+		}
+	}
+	rbres = withRates(mw.ctx, mw.Hsp, price).RateBreakdown(rbreq)
+	for i := range rbres.Rates {
+		price(&rbres.Rates[i])
+	}
+	if priceErr != nil {
+		rbres.Error = priceErr
+	}
 	return
 }
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/log"
+	"github.com/jbowles/hotel_supply_platform/currency"
+	"github.com/jbowles/hotel_supply_platform/hspservice"
+	"github.com/jbowles/hotel_supply_platform/pricing"
+	"github.com/jbowles/hotel_supply_platform/trace"
+)
+
+// callLog records the order in which middlewares and the service are called.
+type callLog struct{ calls []string }
+
+// logged returns a middleware adding name to l on every call.
+func (l *callLog) logged(name string) ServiceMiddleware {
+	return func(next hspservice.Hsp) hspservice.Hsp { return loggedmw{name, l, next} }
+}
+
+type loggedmw struct {
+	name string
+	log  *callLog
+	next hspservice.Hsp
+}
+
+func (mw loggedmw) WithContext(ctx context.Context) hspservice.Hsp {
+	mw.next = withContext(ctx, mw.next)
+	return mw
+}
+
+func (mw loggedmw) RateBreakdown(r hspservice.RateBreakdownRequest) hspservice.RateBreakdownResponse {
+	mw.log.calls = append(mw.log.calls, mw.name)
+	return mw.next.RateBreakdown(r)
+}
+
+func (mw loggedmw) Book(r hspservice.BookRequest) hspservice.BookResponse {
+	mw.log.calls = append(mw.log.calls, mw.name)
+	return mw.next.Book(r)
+}
+
+func (mw loggedmw) GetItinerary(r hspservice.ItineraryRequest) hspservice.ItineraryResponse {
+	mw.log.calls = append(mw.log.calls, mw.name)
+	return mw.next.GetItinerary(r)
+}
+
+func (mw loggedmw) Cancel(r hspservice.CancelRequest) hspservice.CancelResponse {
+	mw.log.calls = append(mw.log.calls, mw.name)
+	return mw.next.Cancel(r)
+}
+
+// echoHsp answers every call with a response carrying its request. It keeps
+// the context it was last bound to.
+type echoHsp struct {
+	log *callLog
+	ctx *context.Context
+}
+
+func (s echoHsp) WithContext(ctx context.Context) hspservice.Hsp {
+	*s.ctx = ctx
+	return s
+}
+
+func (s echoHsp) RateBreakdown(r hspservice.RateBreakdownRequest) hspservice.RateBreakdownResponse {
+	s.log.calls = append(s.log.calls, "service")
+	return hspservice.RateBreakdownResponse{Request: r, NextPageToken: "next"}
+}
+
+func (s echoHsp) Book(r hspservice.BookRequest) hspservice.BookResponse {
+	s.log.calls = append(s.log.calls, "service")
+	return hspservice.BookResponse{Itinerary: hspservice.Itinerary{IdempotencyKey: r.IdempotencyKey}}
+}
+
+func (s echoHsp) GetItinerary(r hspservice.ItineraryRequest) hspservice.ItineraryResponse {
+	s.log.calls = append(s.log.calls, "service")
+	return hspservice.ItineraryResponse{Itinerary: hspservice.Itinerary{ItineraryId: r.ItineraryId}}
+}
+
+func (s echoHsp) Cancel(r hspservice.CancelRequest) hspservice.CancelResponse {
+	s.log.calls = append(s.log.calls, "service")
+	return hspservice.CancelResponse{Itinerary: hspservice.Itinerary{ItineraryId: r.ItineraryId}}
+}
+
+func TestChainOrder(t *testing.T) {
+	l := &callLog{}
+	svc := chain(l.logged("a"), l.logged("b"), l.logged("c"))(echoHsp{l, new(context.Context)})
+	svc.RateBreakdown(hspservice.RateBreakdownRequest{})
+	if want := []string{"a", "b", "c", "service"}; !reflect.DeepEqual(l.calls, want) {
+		t.Errorf("calls %v, want %v", l.calls, want)
+	}
+
+	l.calls = nil
+	chain()(echoHsp{l, new(context.Context)}).RateBreakdown(hspservice.RateBreakdownRequest{})
+	if want := []string{"service"}; !reflect.DeepEqual(l.calls, want) {
+		t.Errorf("empty chain calls %v, want %v", l.calls, want)
+	}
+}
+
+func TestBuildChain(t *testing.T) {
+	l := &callLog{}
+	available := map[string]ServiceMiddleware{
+		"logging": l.logged("logging"),
+		"cache":   l.logged("cache"),
+		"limit":   l.logged("limit"),
+	}
+	mw, err := buildChain([]string{"limit", "", "logging", "cache"}, available)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exporter := trace.NewInMemoryExporter()
+	bound := new(context.Context)
+	ctx := trace.NewContext(context.Background(), trace.NewTracer(exporter))
+	svc := withContext(ctx, mw(echoHsp{l, bound}))
+
+	rb := svc.RateBreakdown(hspservice.RateBreakdownRequest{Currency: "EUR", PageToken: "tok"})
+	if rb.Request.Currency != "EUR" || rb.Request.PageToken != "tok" || rb.NextPageToken != "next" {
+		t.Errorf("rate breakdown %+v, want the service's response", rb)
+	}
+	if b := svc.Book(hspservice.BookRequest{IdempotencyKey: "key"}); b.Itinerary.IdempotencyKey != "key" {
+		t.Errorf("book %+v, want the service's response", b)
+	}
+	if it := svc.GetItinerary(hspservice.ItineraryRequest{ItineraryId: "1001"}); it.Itinerary.ItineraryId != "1001" {
+		t.Errorf("itinerary %+v, want the service's response", it)
+	}
+	if c := svc.Cancel(hspservice.CancelRequest{ItineraryId: "1002"}); c.Itinerary.ItineraryId != "1002" {
+		t.Errorf("cancel %+v, want the service's response", c)
+	}
+
+	order := []string{"limit", "logging", "cache", "service"}
+	var want []string
+	for i := 0; i < 4; i++ {
+		want = append(want, order...)
+	}
+	if !reflect.DeepEqual(l.calls, want) {
+		t.Errorf("calls %v, want %v", l.calls, want)
+	}
+
+	// Each middleware runs in a span, the innermost finishing first, and the
+	// service is bound to the innermost span's context.
+	spans := exporter.Spans()
+	if len(spans) != 12 {
+		t.Fatalf("%d spans, want 12", len(spans))
+	}
+	var names []string
+	for _, s := range spans[:3] {
+		names = append(names, s.Name)
+	}
+	if want := []string{"middleware.cache", "middleware.logging", "middleware.limit"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("spans %v, want %v", names, want)
+	}
+	if spans[0].Parent != spans[1].Context.SpanID || spans[1].Parent != spans[2].Context.SpanID {
+		t.Error("middleware spans are not nested in chain order")
+	}
+	if s := trace.FromContext(*bound); s == nil || s.Context().SpanID != spans[len(spans)-3].Context.SpanID {
+		t.Error("service not bound to the innermost middleware span")
+	}
+}
+
+func TestBuildChainUnknown(t *testing.T) {
+	available := map[string]ServiceMiddleware{"logging": nil, "cache": nil}
+	_, err := buildChain([]string{"logging", "retry"}, available)
+	if err == nil || !strings.Contains(err.Error(), `"retry"`) || !strings.Contains(err.Error(), "cache, logging") {
+		t.Errorf("error %v, want the unknown name and the known ones", err)
+	}
+}
+
+// sinkHsp streams rates to the rate sink of its context, and returns them
+// when there is none.
+type sinkHsp struct {
+	HspService
+	ctx   context.Context
+	rates []hspservice.HotelRate
+}
+
+func (s sinkHsp) WithContext(ctx context.Context) hspservice.Hsp {
+	s.ctx = ctx
+	return s
+}
+
+func (s sinkHsp) RateBreakdown(r hspservice.RateBreakdownRequest) (res hspservice.RateBreakdownResponse) {
+	sink := hspservice.RateSinkFromContext(s.ctx)
+	if sink == nil {
+		res.Rates = append(res.Rates, s.rates...)
+		return
+	}
+	for _, rate := range s.rates {
+		if res.Error = sink(rate); res.Error != nil {
+			return
+		}
+	}
+	return
+}
+
+func TestMiddlewaresStreamedRates(t *testing.T) {
+	var logged []interface{}
+	logger := log.LoggerFunc(func(keyvals ...interface{}) error {
+		logged = keyvals
+		return nil
+	})
+	conv := currency.Converter{Provider: &currency.Table{Base: "USD", Rates: map[string]*big.Rat{"EUR": big.NewRat(9, 10)}}}
+	mw := chain(
+		loggingMiddleware(logger, "ean", 1),
+		pricingMiddleware(pricing.NewEngine(&pricing.RuleSet{Version: "test"}), log.NewNopLogger()),
+		currencyMiddleware(conv, log.NewNopLogger()),
+	)
+	svc := mw(sinkHsp{rates: []hspservice.HotelRate{
+		{Supplier: "ean", HotelId: "1", Total: "100.00", Currency: "USD"},
+		{Supplier: "ean", HotelId: "1", Total: "120.00", Currency: "USD"},
+		{Supplier: "ean", HotelId: "2", Total: "80.00", Currency: "USD"},
+	}})
+
+	var streamed []hspservice.HotelRate
+	ctx := hspservice.NewRateSinkContext(context.Background(), func(r hspservice.HotelRate) error {
+		streamed = append(streamed, r)
+		return nil
+	})
+	res := withContext(ctx, svc).RateBreakdown(hspservice.RateBreakdownRequest{Currency: "EUR"})
+	if res.Error != nil || len(res.Rates) != 0 {
+		t.Fatalf("response %+v, want no error and the rates streamed", res)
+	}
+	returned := withContext(context.Background(), svc).RateBreakdown(hspservice.RateBreakdownRequest{Currency: "EUR"})
+	if !reflect.DeepEqual(streamed, returned.Rates) {
+		t.Errorf("streamed rates %+v, want the returned ones %+v", streamed, returned.Rates)
+	}
+	for _, r := range streamed {
+		if r.Converted == nil || r.Converted.Currency != "EUR" || r.Sell == nil {
+			t.Errorf("streamed rate %+v not converted and priced", r)
+		}
+	}
+
+	if logged == nil {
+		t.Fatal("rate breakdown not logged")
+	}
+	want := map[string]int{"hotels": 2, "rates": 3}
+	for i := 0; i+1 < len(logged); i += 2 {
+		if n, ok := want[logged[i].(string)]; ok && logged[i+1] != n {
+			t.Errorf("logged %s %v, want %d", logged[i], logged[i+1], n)
+		}
+	}
+}
+
+func TestCurrencyUnconvertibleRate(t *testing.T) {
+	var logged [][]interface{}
+	logger := log.LoggerFunc(func(keyvals ...interface{}) error {
+		logged = append(logged, keyvals)
+		return nil
+	})
+	conv := currency.Converter{Provider: &currency.Table{Base: "USD", Rates: map[string]*big.Rat{"EUR": big.NewRat(9, 10)}}}
+	svc := currencyMiddleware(conv, logger)(sinkHsp{rates: []hspservice.HotelRate{
+		{Supplier: "ean", HotelId: "1", Total: "100.00", Currency: "USD"},
+		{Supplier: "ean", HotelId: "2", Total: "80.00", Currency: "GBP"},
+		{Supplier: "ean", HotelId: "3", Total: "90.00", Currency: "PLN"},
+	}})
+
+	res := withContext(context.Background(), svc).RateBreakdown(hspservice.RateBreakdownRequest{Currency: "EUR"})
+	if res.Error != nil || len(res.Rates) != 3 {
+		t.Fatalf("response %+v, want every rate and no error", res)
+	}
+	if c := res.Rates[0].Converted; c == nil || c.String() != "90.00" || c.Currency != "EUR" {
+		t.Errorf("USD rate converted to %v, want 90.00 EUR", c)
+	}
+	for _, r := range res.Rates[1:] {
+		if r.Converted != nil {
+			t.Errorf("rate %+v converted without a table rate", r)
+		}
+	}
+	if len(logged) != 2 {
+		t.Fatalf("logged %v, want the two rates not converted", logged)
+	}
+	for i, hotel := range []string{"2", "3"} {
+		kv := map[interface{}]interface{}{}
+		for j := 0; j+1 < len(logged[i]); j += 2 {
+			kv[logged[i][j]] = logged[i][j+1]
+		}
+		if kv["hotel_id"] != hotel || kv["to"] != "EUR" || kv["err"] == nil {
+			t.Errorf("logged %v, want hotel %s and the error", logged[i], hotel)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	jujuratelimit "github.com/juju/ratelimit"
+	"github.com/sony/gobreaker"
+	"golang.org/x/net/context"
+)
+
+const (
+	// statsWindow is how many recent calls an instance's error rate and
+	// latency percentiles are computed over.
+	statsWindow = 256
+	// maxConsecutiveFailures is how many calls in a row may fail before an
+	// instance without a circuit breaker is considered unavailable.
+	maxConsecutiveFailures = 5
+)
+
+// ErrNoSupplierAvailable is reported by the readiness check when every
+// supplier instance is failing.
+var ErrNoSupplierAvailable = errors.New("no supplier instance available")
+
+// supplierHealth tracks the supplier instances the process calls, for the
+// readiness check and the /suppliers/status page. onCall, when set, is told
+// of every call too, for metrics.
+type supplierHealth struct {
+	mtx       sync.RWMutex
+	instances []*supplierInstance
+	onCall    func(i *supplierInstance, took time.Duration, err error)
+}
+
+func newSupplierHealth(onCall func(i *supplierInstance, took time.Duration, err error)) *supplierHealth {
+	return &supplierHealth{onCall: onCall}
+}
+
+// supplierInstance is one address a supplier is called at. Breaker and Bucket
+// are set for instances called through a load balancer factory.
+type supplierInstance struct {
+	Supplier string
+	Instance string
+	Breaker  *gobreaker.CircuitBreaker
+	Bucket   *jujuratelimit.Bucket
+
+	onCall      func(i *supplierInstance, took time.Duration, err error)
+	mtx         sync.Mutex
+	calls       uint64
+	consecutive int // consecutive failures
+	samples     [statsWindow]sample
+}
+
+type sample struct {
+	took   time.Duration
+	failed bool
+}
+
+// Add tracks i, an instance built with its breaker and bucket.
+func (h *supplierHealth) Add(i *supplierInstance) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	i.onCall = h.onCall
+	h.instances = append(h.instances, i)
+}
+
+// Instance returns the instance of supplier at addr, adding it if it is new.
+func (h *supplierHealth) Instance(supplier, addr string) *supplierInstance {
+	h.mtx.RLock()
+	for _, i := range h.instances {
+		if i.Supplier == supplier && i.Instance == addr {
+			h.mtx.RUnlock()
+			return i
+		}
+	}
+	h.mtx.RUnlock()
+
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	for _, i := range h.instances {
+		if i.Supplier == supplier && i.Instance == addr {
+			return i
+		}
+	}
+	i := &supplierInstance{Supplier: supplier, Instance: addr, onCall: h.onCall}
+	h.instances = append(h.instances, i)
+	return i
+}
+
+// Observe records a call that took took and failed when err is not nil.
+func (i *supplierInstance) Observe(took time.Duration, err error) {
+	i.mtx.Lock()
+	i.samples[i.calls%statsWindow] = sample{took, err != nil}
+	i.calls++
+	if err != nil {
+		i.consecutive++
+	} else {
+		i.consecutive = 0
+	}
+	i.mtx.Unlock()
+	if i.onCall != nil {
+		i.onCall(i, took, err)
+	}
+}
+
+// Available reports whether the instance is worth calling: its breaker is not
+// open and its recent calls have not all failed.
+func (i *supplierInstance) Available() bool {
+	if i.Breaker != nil && i.Breaker.State() == gobreaker.StateOpen {
+		return false
+	}
+	i.mtx.Lock()
+	defer i.mtx.Unlock()
+	return i.consecutive < maxConsecutiveFailures
+}
+
+// Endpoint returns an endpoint.Middleware observing the calls made through
+// it.
+func (i *supplierInstance) Endpoint() endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			begin := time.Now()
+			response, err := next(ctx, request)
+			i.Observe(time.Since(begin), err)
+			return response, err
+		}
+	}
+}
+
+// Transport returns an http.RoundTripper that observes the requests made
+// through next under the instance of supplier named by the request host.
+// Status codes of 500 and above count as failed.
+func (h *supplierHealth) Transport(supplier string, next http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		begin := time.Now()
+		resp, err := next.RoundTrip(r)
+		failed := err
+		if err == nil && resp.StatusCode >= http.StatusInternalServerError {
+			failed = fmt.Errorf("status %s", resp.Status)
+		}
+		h.Instance(supplier, r.URL.Host).Observe(time.Since(begin), failed)
+		return resp, err
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+// Ready is a readiness check: at least one supplier instance is available.
+// A process that has not called any supplier yet is ready.
+func (h *supplierHealth) Ready() error {
+	h.mtx.RLock()
+	defer h.mtx.RUnlock()
+	if len(h.instances) == 0 {
+		return nil
+	}
+	for _, i := range h.instances {
+		if i.Available() {
+			return nil
+		}
+	}
+	return ErrNoSupplierAvailable
+}
+
+// instanceStatus is an instance's entry on the /suppliers/status page.
+type instanceStatus struct {
+	Supplier  string             `json:"supplier"`
+	Instance  string             `json:"instance"`
+	Available bool               `json:"available"`
+	Breaker   string             `json:"breaker,omitempty"`
+	RateLimit *rateLimitStatus   `json:"rate_limit,omitempty"`
+	Calls     uint64             `json:"calls"`
+	ErrorRate float64            `json:"error_rate"`
+	LatencyMs map[string]float64 `json:"latency_ms,omitempty"`
+}
+
+// rateLimitStatus is the headroom of an instance's token bucket.
+type rateLimitStatus struct {
+	Available int64   `json:"available"`
+	Capacity  int64   `json:"capacity"`
+	Rate      float64 `json:"rate"`
+}
+
+var breakerStates = map[gobreaker.State]string{
+	gobreaker.StateClosed:   "closed",
+	gobreaker.StateHalfOpen: "half_open",
+	gobreaker.StateOpen:     "open",
+}
+
+// Status returns the state of the instance, with the error rate and latency
+// percentiles of its last statsWindow calls.
+func (i *supplierInstance) Status() instanceStatus {
+	s := instanceStatus{Supplier: i.Supplier, Instance: i.Instance, Available: i.Available()}
+	if i.Breaker != nil {
+		s.Breaker = breakerStates[i.Breaker.State()]
+	}
+	if i.Bucket != nil {
+		s.RateLimit = &rateLimitStatus{i.Bucket.Available(), i.Bucket.Capacity(), i.Bucket.Rate()}
+	}
+
+	i.mtx.Lock()
+	s.Calls = i.calls
+	n := i.calls
+	if n > statsWindow {
+		n = statsWindow
+	}
+	took := make([]time.Duration, n)
+	failed := 0
+	for j := range took {
+		took[j] = i.samples[j].took
+		if i.samples[j].failed {
+			failed++
+		}
+	}
+	i.mtx.Unlock()
+
+	if n == 0 {
+		return s
+	}
+	s.ErrorRate = float64(failed) / float64(n)
+	sort.Slice(took, func(a, b int) bool { return took[a] < took[b] })
+	s.LatencyMs = map[string]float64{}
+	for _, p := range []int{50, 95, 99} {
+		rank := (len(took)*p+99)/100 - 1 // nearest rank
+		s.LatencyMs[fmt.Sprintf("p%d", p)] = took[rank].Seconds() * 1000
+	}
+	return s
+}
+
+// StatusHandler serves the status of every supplier instance as JSON.
+func (h *supplierHealth) StatusHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.mtx.RLock()
+		status := make([]instanceStatus, len(h.instances))
+		for j, i := range h.instances {
+			status[j] = i.Status()
+		}
+		h.mtx.RUnlock()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"suppliers": status})
+	})
+}
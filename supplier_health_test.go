@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	jujuratelimit "github.com/juju/ratelimit"
+	"github.com/sony/gobreaker"
+)
+
+var errSupplier = errors.New("supplier failed")
+
+// tripped returns a breaker opened by a single failure.
+func tripped() *gobreaker.CircuitBreaker {
+	cb := gobreaker.NewCircuitBreaker(gobreaker.Settings{
+		ReadyToTrip: func(c gobreaker.Counts) bool { return c.ConsecutiveFailures > 0 },
+	})
+	cb.Execute(func() (interface{}, error) { return nil, errSupplier })
+	return cb
+}
+
+// suppliersStatus serves the status page of h and decodes it.
+func suppliersStatus(t *testing.T, h *supplierHealth) map[string]instanceStatus {
+	rec := httptest.NewRecorder()
+	h.StatusHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/suppliers/status", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("status %d Content-Type %q, want 200 application/json", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	var page struct {
+		Suppliers []instanceStatus `json:"suppliers"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&page); err != nil {
+		t.Fatal(err)
+	}
+	byInstance := map[string]instanceStatus{}
+	for _, s := range page.Suppliers {
+		byInstance[s.Instance] = s
+	}
+	return byInstance
+}
+
+func TestSupplierStatus(t *testing.T) {
+	h := newSupplierHealth(nil)
+
+	// Ten calls of 1ms to 10ms, the second and fifth failing.
+	balanced := &supplierInstance{Supplier: "ean", Instance: "10.0.0.1:8080", Breaker: gobreaker.NewCircuitBreaker(gobreaker.Settings{}), Bucket: jujuratelimit.NewBucketWithRate(0.5, 10)}
+	h.Add(balanced)
+	balanced.Bucket.TakeAvailable(4)
+	for j := 1; j <= 10; j++ {
+		var err error
+		if j == 2 || j == 5 {
+			err = errSupplier
+		}
+		balanced.Observe(time.Duration(j)*time.Millisecond, err)
+	}
+
+	h.Add(&supplierInstance{Supplier: "ean", Instance: "10.0.0.2:8080", Breaker: tripped()})
+
+	// Only the last statsWindow calls count: the early failures are gone.
+	windowed := h.Instance("ota", "ota.example.com")
+	for j := 0; j < statsWindow+44; j++ {
+		var err error
+		if j < 44 {
+			err = errSupplier
+		}
+		windowed.Observe(3*time.Millisecond, err)
+	}
+
+	failing := h.Instance("ota", "ota-2.example.com")
+	for j := 0; j < maxConsecutiveFailures; j++ {
+		failing.Observe(time.Millisecond, errSupplier)
+	}
+
+	h.Instance("ota", "ota-3.example.com")
+
+	want := map[string]instanceStatus{
+		"10.0.0.1:8080": {
+			Supplier: "ean", Instance: "10.0.0.1:8080", Available: true, Breaker: "closed",
+			RateLimit: &rateLimitStatus{Available: 6, Capacity: 10, Rate: 0.5},
+			Calls:     10, ErrorRate: 0.2,
+			LatencyMs: map[string]float64{"p50": 5, "p95": 10, "p99": 10},
+		},
+		"10.0.0.2:8080": {Supplier: "ean", Instance: "10.0.0.2:8080", Breaker: "open"},
+		"ota.example.com": {
+			Supplier: "ota", Instance: "ota.example.com", Available: true,
+			Calls: statsWindow + 44, LatencyMs: map[string]float64{"p50": 3, "p95": 3, "p99": 3},
+		},
+		"ota-2.example.com": {
+			Supplier: "ota", Instance: "ota-2.example.com",
+			Calls: maxConsecutiveFailures, ErrorRate: 1, LatencyMs: map[string]float64{"p50": 1, "p95": 1, "p99": 1},
+		},
+		"ota-3.example.com": {Supplier: "ota", Instance: "ota-3.example.com", Available: true},
+	}
+	got := suppliersStatus(t, h)
+	if len(got) != len(want) {
+		t.Errorf("%d instances, want %d", len(got), len(want))
+	}
+	for instance, w := range want {
+		if g := got[instance]; !reflect.DeepEqual(g, w) {
+			t.Errorf("%s: %+v (rate limit %+v), want %+v (rate limit %+v)", instance, g, g.RateLimit, w, w.RateLimit)
+		}
+	}
+}
+
+func TestSupplierReady(t *testing.T) {
+	h := newSupplierHealth(nil)
+	if err := h.Ready(); err != nil {
+		t.Errorf("no instance yet: %v, want ready", err)
+	}
+
+	h.Add(&supplierInstance{Supplier: "ean", Instance: "10.0.0.1:8080", Breaker: tripped()})
+	failing := h.Instance("ota", "ota.example.com")
+	for j := 0; j < maxConsecutiveFailures; j++ {
+		failing.Observe(time.Millisecond, errSupplier)
+	}
+	if err := h.Ready(); err != ErrNoSupplierAvailable {
+		t.Errorf("every instance failing: %v, want %v", err, ErrNoSupplierAvailable)
+	}
+
+	// One success is enough for an instance without a breaker to recover.
+	failing.Observe(time.Millisecond, nil)
+	if err := h.Ready(); err != nil {
+		t.Errorf("an instance recovered: %v, want ready", err)
+	}
+}
+
+func TestSupplierTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			http.Error(w, "down", http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+
+	var calls int
+	h := newSupplierHealth(func(i *supplierInstance, took time.Duration, err error) { calls++ })
+	c := &http.Client{Transport: h.Transport("ota", http.DefaultTransport)}
+	for _, path := range []string{"/ok", "/fail", "/fail"} {
+		resp, err := c.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	s := suppliersStatus(t, h)[srv.Listener.Addr().String()]
+	if s.Supplier != "ota" || s.Calls != 3 || s.ErrorRate != 2.0/3 {
+		t.Errorf("status %+v, want 3 ota calls, 2 failed", s)
+	}
+	if calls != 3 {
+		t.Errorf("onCall told of %d calls, want 3", calls)
+	}
+}
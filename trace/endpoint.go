@@ -0,0 +1,20 @@
+package trace
+
+import (
+	"github.com/go-kit/kit/endpoint"
+	"golang.org/x/net/context"
+)
+
+// Endpoint returns an endpoint.Middleware running each call in a span named
+// name, failed when the endpoint returns an error.
+func Endpoint(name string) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			span, ctx := StartSpan(ctx, name)
+			defer span.Finish()
+			response, err := next(ctx, request)
+			span.SetError(err)
+			return response, err
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package trace
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-kit/kit/log"
+)
+
+// InMemoryExporter keeps the spans it is given, for tests and debugging.
+type InMemoryExporter struct {
+	mtx   sync.Mutex
+	spans []SpanData
+}
+
+// NewInMemoryExporter returns an empty InMemoryExporter.
+func NewInMemoryExporter() *InMemoryExporter { return &InMemoryExporter{} }
+
+// Export implements Exporter.
+func (e *InMemoryExporter) Export(s SpanData) {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	e.spans = append(e.spans, s)
+}
+
+// Spans returns the spans exported so far, in the order they finished.
+func (e *InMemoryExporter) Spans() []SpanData {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	return append([]SpanData(nil), e.spans...)
+}
+
+// Reset forgets the spans exported so far.
+func (e *InMemoryExporter) Reset() {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	e.spans = nil
+}
+
+// LogExporter logs every span as one line.
+type LogExporter struct {
+	Logger log.Logger
+}
+
+// Export implements Exporter.
+func (e LogExporter) Export(s SpanData) {
+	keyvals := []interface{}{
+		"span", s.Name,
+		"trace_id", s.Context.TraceID,
+		"span_id", s.Context.SpanID,
+		"parent_id", s.Parent,
+		"took", s.End.Sub(s.Start),
+	}
+	keys := make([]string, 0, len(s.Attributes))
+	for k := range s.Attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		keyvals = append(keyvals, strings.Replace(k, ".", "_", -1), s.Attributes[k])
+	}
+	if s.Error != "" {
+		keyvals = append(keyvals, "err", s.Error)
+	}
+	e.Logger.Log(keyvals...)
+}
@@ -0,0 +1,183 @@
+// Package trace records the spans of a request as it moves through hsp and on
+// to the suppliers, and propagates its trace to other services in W3C Trace
+// Context headers (https://www.w3.org/TR/trace-context/).
+//
+// A Tracer starts spans and hands the finished ones to its Exporter. The span
+// of a request travels in its context.Context; StartSpan starts a child of it,
+// so code below the transport needs only the context, not the Tracer. With no
+// span in the context StartSpan returns a nil *Span, whose methods do nothing,
+// so untraced code paths need no checks.
+package trace
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// TraceID identifies a trace, shared by all of its spans.
+type TraceID [16]byte
+
+func (id TraceID) String() string { return hex.EncodeToString(id[:]) }
+
+// SpanID identifies a span within its trace.
+type SpanID [8]byte
+
+func (id SpanID) String() string { return hex.EncodeToString(id[:]) }
+
+// SpanContext is what a span passes to its children, in process or across a
+// traceparent header.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+	State   string // tracestate header, passed on as received
+}
+
+// SpanData is a finished span, as exporters receive it.
+type SpanData struct {
+	Name       string
+	Context    SpanContext
+	Parent     SpanID // zero for a root span
+	Start      time.Time
+	End        time.Time
+	Attributes map[string]string
+	Error      string
+}
+
+// Exporter receives finished spans. Export is called from the goroutine that
+// finished the span, so it must be safe for concurrent use and fast.
+type Exporter interface {
+	Export(s SpanData)
+}
+
+// Tracer starts spans and exports them when they finish. Unsampled spans are
+// propagated but not exported.
+type Tracer struct {
+	exporter Exporter
+}
+
+// NewTracer returns a Tracer exporting to e.
+func NewTracer(e Exporter) *Tracer {
+	return &Tracer{exporter: e}
+}
+
+// Span is a timed operation within a trace. A nil *Span is valid and records
+// nothing.
+type Span struct {
+	tracer *Tracer
+
+	mtx  sync.Mutex
+	data SpanData
+	done bool
+}
+
+type contextKey int
+
+const (
+	spanKey contextKey = iota
+	remoteKey
+	tracerKey
+)
+
+// NewContext returns ctx carrying t, so StartSpan can start root spans in it.
+func NewContext(ctx context.Context, t *Tracer) context.Context {
+	return context.WithValue(ctx, tracerKey, t)
+}
+
+// WithRemoteParent returns ctx carrying a parent span received from another
+// service, for the next StartSpan to continue.
+func WithRemoteParent(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteKey, sc)
+}
+
+// FromContext returns the span in ctx, or nil.
+func FromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey).(*Span)
+	return s
+}
+
+// StartSpan starts a span named name: a child of the span in ctx, else of the
+// remote parent in ctx, else a new trace when ctx carries a Tracer. It returns
+// a nil span and ctx unchanged when there is nothing to trace to.
+func StartSpan(ctx context.Context, name string) (*Span, context.Context) {
+	var (
+		t      *Tracer
+		parent SpanContext
+		child  bool
+	)
+	if p := FromContext(ctx); p != nil {
+		t, parent, child = p.tracer, p.data.Context, true
+	} else if t, _ = ctx.Value(tracerKey).(*Tracer); t == nil {
+		return nil, ctx
+	} else if sc, ok := ctx.Value(remoteKey).(SpanContext); ok {
+		parent, child = sc, true
+	}
+
+	s := &Span{tracer: t}
+	s.data.Name = name
+	s.data.Start = time.Now()
+	if child {
+		s.data.Context = parent
+		s.data.Parent = parent.SpanID
+	} else {
+		rand.Read(s.data.Context.TraceID[:])
+		s.data.Context.Sampled = true
+	}
+	rand.Read(s.data.Context.SpanID[:])
+	return s, context.WithValue(ctx, spanKey, s)
+}
+
+// Context returns the span's SpanContext, to propagate.
+func (s *Span) Context() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.data.Context
+}
+
+// SetAttribute records a key/value pair on the span.
+func (s *Span) SetAttribute(key, value string) {
+	if s == nil {
+		return
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.data.Attributes == nil {
+		s.data.Attributes = map[string]string{}
+	}
+	s.data.Attributes[key] = value
+}
+
+// SetError records err as the span's failure, if it is not nil.
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.data.Error = err.Error()
+}
+
+// Finish ends the span and exports it if it is sampled. Later calls do
+// nothing.
+func (s *Span) Finish() {
+	if s == nil {
+		return
+	}
+	s.mtx.Lock()
+	if s.done {
+		s.mtx.Unlock()
+		return
+	}
+	s.done = true
+	s.data.End = time.Now()
+	data := s.data
+	s.mtx.Unlock()
+	if data.Context.Sampled && s.tracer.exporter != nil {
+		s.tracer.exporter.Export(data)
+	}
+}
@@ -0,0 +1,101 @@
+package trace
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestInMemoryExporter(t *testing.T) {
+	e := NewInMemoryExporter()
+	ctx := NewContext(context.Background(), NewTracer(e))
+
+	root, ctx := StartSpan(ctx, "root")
+	child, _ := StartSpan(ctx, "child")
+	child.SetAttribute("instance", "ean-1")
+	child.SetError(errors.New("timeout"))
+	child.Finish()
+	child.Finish()
+	root.Finish()
+
+	spans := e.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("%d spans, want 2", len(spans))
+	}
+	c, r := spans[0], spans[1]
+	if c.Name != "child" || r.Name != "root" {
+		t.Errorf("spans %s, %s; want child then root, in the order they finished", c.Name, r.Name)
+	}
+	if r.Parent != (SpanID{}) || c.Parent != r.Context.SpanID || c.Context.TraceID != r.Context.TraceID {
+		t.Errorf("child %+v not a child of root %+v", c.Context, r.Context)
+	}
+	if c.Attributes["instance"] != "ean-1" || c.Error != "timeout" {
+		t.Errorf("child attributes %v, error %q", c.Attributes, c.Error)
+	}
+	if c.End.Before(c.Start) {
+		t.Errorf("child ends at %v before it starts at %v", c.End, c.Start)
+	}
+
+	e.Reset()
+	if spans := e.Spans(); len(spans) != 0 {
+		t.Errorf("%d spans after Reset", len(spans))
+	}
+}
+
+func TestNoTracer(t *testing.T) {
+	span, ctx := StartSpan(context.Background(), "untraced")
+	if span != nil || FromContext(ctx) != nil {
+		t.Errorf("span %v without a tracer", span)
+	}
+	// A nil span records nothing, without panicking.
+	span.SetAttribute("k", "v")
+	span.SetError(errors.New("err"))
+	span.Finish()
+}
+
+func TestUnsampledRemoteParent(t *testing.T) {
+	e := NewInMemoryExporter()
+	sc, err := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := WithRemoteParent(NewContext(context.Background(), NewTracer(e)), sc)
+	span, _ := StartSpan(ctx, "server")
+	span.Finish()
+	if spans := e.Spans(); len(spans) != 0 {
+		t.Errorf("exported %d unsampled spans", len(spans))
+	}
+	if span.Context().TraceID != sc.TraceID {
+		t.Errorf("trace %s, want the remote parent's %s", span.Context().TraceID, sc.TraceID)
+	}
+}
+
+func TestHTTPPropagation(t *testing.T) {
+	client := NewInMemoryExporter()
+	span, ctx := StartSpan(NewContext(context.Background(), NewTracer(client)), "client")
+	r, _ := http.NewRequest("GET", "http://hsp/rate_breakdown", nil)
+	ContextToHTTP(ctx, r)
+	span.Finish()
+
+	server := NewInMemoryExporter()
+	ctx = HTTPToContext(NewTracer(server))(context.Background(), r)
+	s, _ := StartSpan(ctx, "server")
+	s.Finish()
+
+	spans := server.Spans()
+	if len(spans) != 1 {
+		t.Fatalf("server exported %d spans, want 1", len(spans))
+	}
+	if got, want := spans[0], span.Context(); got.Context.TraceID != want.TraceID || got.Parent != want.SpanID {
+		t.Errorf("server span %+v, want a child of %+v", got.Context, want)
+	}
+
+	r.Header.Set(TraceparentHeader, "not a traceparent")
+	ctx = HTTPToContext(NewTracer(server))(context.Background(), r)
+	s, _ = StartSpan(ctx, "server")
+	if s.Context().TraceID == span.Context().TraceID {
+		t.Error("an invalid traceparent continued the trace")
+	}
+}
@@ -0,0 +1,135 @@
+package trace
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/metadata"
+)
+
+// The W3C Trace Context headers.
+const (
+	TraceparentHeader = "traceparent"
+	TracestateHeader  = "tracestate"
+)
+
+// ErrInvalidTraceparent is returned by ParseTraceparent for a header it
+// cannot use; the request then starts a new trace.
+var ErrInvalidTraceparent = errors.New("trace: invalid traceparent")
+
+const sampledFlag = 0x01
+
+// ParseTraceparent parses a traceparent header value,
+// "version-traceid-parentid-flags". Versions above 00 are read as 00, as the
+// specification asks; all-zero ids are invalid.
+func ParseTraceparent(v string) (SpanContext, error) {
+	var sc SpanContext
+	parts := strings.Split(strings.TrimSpace(v), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return sc, ErrInvalidTraceparent
+	}
+	if !decodeHex(sc.TraceID[:], parts[1]) || sc.TraceID == (TraceID{}) {
+		return sc, ErrInvalidTraceparent
+	}
+	if !decodeHex(sc.SpanID[:], parts[2]) || sc.SpanID == (SpanID{}) {
+		return sc, ErrInvalidTraceparent
+	}
+	var flags [1]byte
+	if !decodeHex(flags[:], parts[3]) {
+		return sc, ErrInvalidTraceparent
+	}
+	sc.Sampled = flags[0]&sampledFlag != 0
+	return sc, nil
+}
+
+// decodeHex decodes lower case hex s into exactly len(dst) bytes.
+func decodeHex(dst []byte, s string) bool {
+	if len(s) != hex.EncodedLen(len(dst)) || strings.ToLower(s) != s {
+		return false
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err == nil
+}
+
+// Traceparent returns the traceparent header value of sc.
+func (sc SpanContext) Traceparent() string {
+	var flags byte
+	if sc.Sampled {
+		flags |= sampledFlag
+	}
+	return fmt.Sprintf("00-%s-%s-%02x", sc.TraceID, sc.SpanID, flags)
+}
+
+// HTTPToContext returns a transport/http.Server before func that puts t in
+// the context, and the caller's span from the request's traceparent header,
+// if it has a valid one, as the remote parent of the spans of the request.
+func HTTPToContext(t *Tracer) func(ctx context.Context, r *http.Request) context.Context {
+	return func(ctx context.Context, r *http.Request) context.Context {
+		ctx = NewContext(ctx, t)
+		sc, err := ParseTraceparent(r.Header.Get(TraceparentHeader))
+		if err != nil {
+			return ctx
+		}
+		sc.State = r.Header.Get(TracestateHeader)
+		return WithRemoteParent(ctx, sc)
+	}
+}
+
+// ContextToHTTP sets the traceparent and tracestate headers of r from the
+// span in ctx, so the service called continues the trace. It's designed to be
+// used as a transport/http.Client before func.
+func ContextToHTTP(ctx context.Context, r *http.Request) context.Context {
+	s := FromContext(ctx)
+	if s == nil {
+		return ctx
+	}
+	sc := s.Context()
+	r.Header.Set(TraceparentHeader, sc.Traceparent())
+	if sc.State != "" {
+		r.Header.Set(TracestateHeader, sc.State)
+	}
+	return ctx
+}
+
+// GRPCToContext is HTTPToContext for gRPC: the caller's span comes from the
+// traceparent metadata of the call. It's designed to be used as a
+// transport/grpc.Server before func.
+func GRPCToContext(t *Tracer) func(ctx context.Context, md *metadata.MD) context.Context {
+	return func(ctx context.Context, md *metadata.MD) context.Context {
+		ctx = NewContext(ctx, t)
+		sc, err := ParseTraceparent(first(*md, TraceparentHeader))
+		if err != nil {
+			return ctx
+		}
+		sc.State = first(*md, TracestateHeader)
+		return WithRemoteParent(ctx, sc)
+	}
+}
+
+// ContextToGRPC sets the traceparent and tracestate metadata of the call from
+// the span in ctx. It's designed to be used as a transport/grpc.Client before
+// func.
+func ContextToGRPC(ctx context.Context, md *metadata.MD) context.Context {
+	s := FromContext(ctx)
+	if s == nil {
+		return ctx
+	}
+	sc := s.Context()
+	(*md)[TraceparentHeader] = []string{sc.Traceparent()}
+	if sc.State != "" {
+		(*md)[TracestateHeader] = []string{sc.State}
+	}
+	return ctx
+}
+
+// first returns the first value of key in md, or "".
+func first(md metadata.MD, key string) string {
+	if v := md[key]; len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
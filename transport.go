@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/jbowles/hotel_supply_platform/hspservice"
+	"github.com/jbowles/hotel_supply_platform/trace"
+	"golang.org/x/net/context"
+)
+
+// newHTTPHandler serves e over HTTP with the options every hsp server uses:
+// content negotiation, JSON errors, request ids and tracing. The endpoint runs
+// in a span named name, continuing the caller's trace when it sent a
+// traceparent, and under the caller's X-Request-Id or a new one.
+func newHTTPHandler(ctx context.Context, name string, e endpoint.Endpoint, dec httptransport.DecodeRequestFunc, enc httptransport.EncodeResponseFunc, tracer *trace.Tracer, logger log.Logger) *httptransport.Server {
+	return httptransport.NewServer(
+		ctx,
+		trace.Endpoint(name)(e),
+		dec,
+		enc,
+		httptransport.ServerErrorLogger(logger),
+		httptransport.ServerErrorEncoder(encodeHTTPError),
+		httptransport.ServerBefore(hspservice.NegotiateContentType, hspservice.RequestIDToContext, trace.HTTPToContext(tracer)),
+		httptransport.ServerAfter(hspservice.SetContentType, hspservice.SetRequestIDHeader),
+	)
+}
+
+// newRateStreamHandler serves svc's rate breakdowns as rate streams, see
+// hspservice.RateStreamWriter: each rate is written as soon as svc hands it
+// to the rate sink, so callers can start on the first hotels while the
+// supplier is still sending the rest. Request ids and tracing are those of
+// newHTTPHandler. A request that cannot be decoded is answered as by
+// encodeHTTPError; once the stream has started, errors go in its last line.
+func newRateStreamHandler(ctx context.Context, name string, svc hspservice.Hsp, tracer *trace.Tracer, logger log.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		ctx = trace.HTTPToContext(tracer)(hspservice.RequestIDToContext(ctx, r), r)
+		hspservice.SetRequestIDHeader(ctx, w)
+
+		request, err := hspservice.DecodeRateStreamRequest(r)
+		if err != nil {
+			logger.Log("err", err)
+			encodeHTTPError(w, httptransport.BadRequestError{Err: err})
+			return
+		}
+		span, ctx := trace.StartSpan(ctx, name)
+		defer span.Finish()
+		stream := hspservice.NewRateStreamWriter(w)
+		response := withContext(hspservice.NewRateSinkContext(ctx, stream.Rate), svc).RateBreakdown(request)
+		span.SetError(response.Error)
+		if err := stream.End(response); err != nil {
+			logger.Log("err", err)
+		}
+	})
+}
+
+// encodeHTTPError writes a transport error as a JSON hspservice.Error. The
+// status is the one of an hspservice.HTTPError, 400 for other errors decoding
+// the request and 500 for anything else. It's designed to be used as the
+// transport/http.Server error encoder.
+func encodeHTTPError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	if e, ok := err.(httptransport.BadRequestError); ok {
+		status, err = http.StatusBadRequest, e.Err
+	}
+	if e, ok := err.(hspservice.HTTPError); ok {
+		status = e.Status
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(struct {
+		Error hspservice.Error `json:"error"`
+	}{hspservice.Error{Message: err.Error()}})
+}
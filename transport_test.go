@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/jbowles/hotel_supply_platform/hspservice"
+	"golang.org/x/net/context"
+)
+
+// newTransportServer serves the endpoints of svc as the hsp HTTP API does.
+func newTransportServer(svc hspservice.Hsp) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/rate_breakdown", newHTTPHandler(context.Background(), "rate_breakdown", makeRateBreakdownEndpoint(svc),
+		hspservice.DecodeRateBreakdownRequest, hspservice.EncodeRateBreakdownResponse, nil, log.NewNopLogger()))
+	mux.Handle("/book", newHTTPHandler(context.Background(), "book", makeBookEndpoint(svc),
+		hspservice.DecodeBookRequest, hspservice.EncodeBookResponse, nil, log.NewNopLogger()))
+	mux.Handle("/itinerary", newHTTPHandler(context.Background(), "itinerary", makeItineraryEndpoint(svc),
+		hspservice.DecodeItineraryRequest, hspservice.EncodeItineraryResponse, nil, log.NewNopLogger()))
+	return httptest.NewServer(mux)
+}
+
+func newGrpcFake() grpcFake {
+	return grpcFake{ids: new([]string), mtx: new(sync.Mutex)}
+}
+
+// httpDo makes a request with the given Accept header, and Content-Type and
+// body when body is not empty.
+func httpDo(t *testing.T, method, url, accept, contentType, body string) *http.Response {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+// transportError decodes the JSON error body of a refused request.
+func transportError(t *testing.T, resp *http.Response) hspservice.Error {
+	var body struct {
+		Error hspservice.Error `json:"error"`
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("error Content-Type %q, want application/json", ct)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	return body.Error
+}
+
+func TestHTTPAccept(t *testing.T) {
+	srv := newTransportServer(newGrpcFake())
+	defer srv.Close()
+
+	for _, tc := range []struct {
+		accept, want string // want empty for a 406
+	}{
+		{"", "application/json"},
+		{"*/*", "application/json"},
+		{"application/xml", "application/xml"},
+		{"text/xml", "text/xml"},
+		{"text/*", "text/xml"},
+		{"application/xml, application/json", "application/xml"},
+		{"application/xml;q=0.5, application/json", "application/json"},
+		{"application/json;q=0, */*", "application/xml"},
+		{"application/*;q=0, */*", "text/xml"},
+		{"application/*;q=0, application/json", "application/json"},
+		{"image/png, text/html;q=0.9", ""},
+		{"application/json;q=0", ""},
+		{"*/*;q=0", ""},
+	} {
+		for _, method := range []string{"GET", "POST"} {
+			body, contentType := "", ""
+			if method == "POST" {
+				body, contentType = `{"currency": "EUR"}`, "application/json"
+			}
+			resp := httpDo(t, method, srv.URL+"/rate_breakdown?currency=EUR", tc.accept, contentType, body)
+			if tc.want == "" {
+				if resp.StatusCode != http.StatusNotAcceptable {
+					t.Errorf("%s %q: status %d, want 406", method, tc.accept, resp.StatusCode)
+				} else if e := transportError(t, resp); !strings.Contains(e.Message, "supported: application/json") {
+					t.Errorf("%s %q: error %q, want the supported types", method, tc.accept, e.Message)
+				}
+				resp.Body.Close()
+				continue
+			}
+			if ct := resp.Header.Get("Content-Type"); resp.StatusCode != http.StatusOK || ct != tc.want {
+				t.Errorf("%s %q: status %d Content-Type %q, want 200 %s", method, tc.accept, resp.StatusCode, ct, tc.want)
+				resp.Body.Close()
+				continue
+			}
+			// The body is in the negotiated type and decodes back.
+			v, err := hspservice.DecodeRateBreakdownResponse(resp)
+			resp.Body.Close()
+			if err != nil {
+				t.Errorf("%s %q: %v", method, tc.accept, err)
+				continue
+			}
+			res := v.(hspservice.RateBreakdownResponse)
+			if res.Error != nil || res.Request.Currency != "EUR" || res.NextPageToken != "next" || len(res.Rates) != 1 || res.Rates[0].RateKey != grpcFakeRate.RateKey {
+				t.Errorf("%s %q: response %+v, want the fake's", method, tc.accept, res)
+			}
+		}
+	}
+}
+
+func TestHTTPUnsupportedMediaType(t *testing.T) {
+	srv := newTransportServer(newGrpcFake())
+	defer srv.Close()
+
+	for _, path := range []string{"/rate_breakdown", "/book", "/itinerary"} {
+		resp := httpDo(t, "POST", srv.URL+path, "", "text/csv", "currency\nEUR\n")
+		if resp.StatusCode != http.StatusUnsupportedMediaType {
+			t.Errorf("%s: status %d, want 415", path, resp.StatusCode)
+		} else if e := transportError(t, resp); !strings.Contains(e.Message, `"text/csv"`) {
+			t.Errorf("%s: error %q, want the refused type", path, e.Message)
+		}
+		resp.Body.Close()
+	}
+
+	// An XML body is as good as a JSON one.
+	resp := httpDo(t, "POST", srv.URL+"/rate_breakdown", "", "application/xml", `<RateBreakdownRequest><currency>EUR</currency></RateBreakdownRequest>`)
+	defer resp.Body.Close()
+	v, err := hspservice.DecodeRateBreakdownResponse(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res := v.(hspservice.RateBreakdownResponse); resp.StatusCode != http.StatusOK || res.Request.Currency != "EUR" {
+		t.Errorf("status %d request %+v, want the XML one", resp.StatusCode, res.Request)
+	}
+}
+
+func TestHTTPErrorRoundTrip(t *testing.T) {
+	// HspService refuses to book with a coded error; the fake fails
+	// GetItinerary with an error of its own.
+	for _, accept := range []string{"application/json", "application/xml", "text/xml"} {
+		srv := newTransportServer(HspService{})
+		resp := httpDo(t, "POST", srv.URL+"/book", accept, "application/json", `{"idempotency_key": "key-1"}`)
+		v, err := hspservice.DecodeBookResponse(resp)
+		resp.Body.Close()
+		srv.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", accept, err)
+		}
+		if res := v.(hspservice.BookResponse); res.Error != hspservice.ErrNotSupported {
+			t.Errorf("%s: book error %v, want %v itself", accept, res.Error, hspservice.ErrNotSupported)
+		}
+
+		srv = newTransportServer(newGrpcFake())
+		resp = httpDo(t, "POST", srv.URL+"/itinerary", accept, "application/json", `{"itinerary_id": "1001"}`)
+		v, err = hspservice.DecodeItineraryResponse(resp)
+		resp.Body.Close()
+		srv.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", accept, err)
+		}
+		res := v.(hspservice.ItineraryResponse)
+		if e, ok := res.Error.(*hspservice.Error); !ok || e.Code != "" || e.Message != "no itinerary 1001" {
+			t.Errorf("%s: itinerary error %#v, want the fake's message without a code", accept, res.Error)
+		}
+	}
+}